@@ -1,7 +1,427 @@
 package main
 
-import "fmt"
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tracer/internal/collector"
+	"tracer/internal/detect"
+	"tracer/internal/lure"
+	ms_office "tracer/internal/ms-office"
+	"tracer/internal/token"
+)
 
 func main() {
-	fmt.Println("success")
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = generate(os.Args[2:])
+	case "scan":
+		err = scan(os.Args[2:])
+	case "selftest":
+		err = selftest(os.Args[2:])
+	case "keys":
+		err = keys(os.Args[2:])
+	case "collect":
+		err = collect(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: tracer generate -lure <name> [-url <traceUrl>] [-profile default|stealth] [-tag] [-keys <file>] [-registry <file>] [-o <file>]")
+	fmt.Fprintln(os.Stderr, "       tracer scan <file>...")
+	fmt.Fprintln(os.Stderr, "       tracer selftest -url <traceUrl>[,<traceUrl>...] [-profile default,stealth] [-technique <name>,...] [-tag]")
+	fmt.Fprintln(os.Stderr, "       tracer keys -f <file> [-rotate] [-remove <kid>]")
+	fmt.Fprintln(os.Stderr, "       tracer collect [-http :80] [-dns :53 -domain <domain>] [-smb :445] [-kube :6443] [-s3 :9000] [-mysql :3306] [-postgres :5432] [-keys <file> [-strict]] [-registry <file>] [-log <file>] [-siem cef|leef|ecs [-siem-out <file>|udp://host:514]]")
+	fmt.Fprintln(os.Stderr, "               [-webhook <url>] [-chat <type>:<url>] [-syslog udp://host:514] [-smtp host:587 -smtp-from <addr> -smtp-to <addr>,...]")
+}
+
+// generate 根据模板生成诱饵文档，指定追踪地址时添加追踪信息
+func generate(args []string) error {
+	var (
+		fs        = flag.NewFlagSet("generate", flag.ExitOnError)
+		name      = fs.String("lure", "", "模板名称")
+		list      = fs.Bool("list", false, "列出全部模板")
+		output    = fs.String("o", "", "输出文件，默认为模板建议的文件名")
+		traceUrl  = fs.String("url", "", "追踪地址，为空时不添加追踪信息")
+		technique = fs.String("technique", "", "追踪技术，多个使用逗号分隔，默认使用模板格式的默认追踪技术")
+		locale    = fs.String("locale", string(lure.LocaleZH), "内容语言：zh、en")
+		seed      = fs.Int64("seed", 0, "随机种子，相同的种子生成相同的内容")
+		company   = fs.String("company", "", "公司名称")
+		domain    = fs.String("domain", "", "内网域名")
+		date      = fs.String("date", "", "文档日期，例如 2026-10-19")
+		profile   = fs.String("profile", string(ms_office.ProfileDefault), "追踪信息的生成方式：default、stealth")
+		tag       = fs.Bool("tag", false, "http、https 追踪地址添加查询参数 v 为追踪技术名称，容易被检测，不能与 -profile stealth 一起使用")
+		keysFile  = fs.String("keys", "", "签名密钥文件，指定时追踪地址中的 token 使用 HMAC 签名，collector 使用同一个文件校验")
+		registry  = fs.String("registry", "", "token 注册表文件，指定时登记生成的 token、文件名和追踪技术")
+	)
+	_ = fs.Parse(args)
+
+	if *list {
+		for _, l := range lure.Lures() {
+			fmt.Printf("%-16s %-6s %s\n", l.Name, l.Format, l.Description)
+		}
+		return nil
+	}
+
+	l, ok := lure.LookupLure(*name)
+	if !ok {
+		return fmt.Errorf("%w: %q", lure.ErrLure, *name)
+	}
+	opts := lure.Options{
+		Locale:       lure.Locale(*locale),
+		Seed:         *seed,
+		Company:      *company,
+		Domain:       *domain,
+		Profile:      ms_office.Profile(*profile),
+		TagTechnique: *tag,
+	}
+	if *date != "" {
+		t, err := time.ParseInLocation("2006-01-02", *date, time.Local)
+		if err != nil {
+			return err
+		}
+		opts.Date = t
+	}
+	if *output == "" {
+		*output = l.DefaultFileName(opts)
+	}
+
+	if *traceUrl == "" {
+		return lure.Generate(l.Name, *output, opts)
+	}
+	if *keysFile != "" {
+		keyring, err := token.OpenKeyring(*keysFile)
+		if err != nil {
+			return err
+		}
+		opts.Signer = keyring
+	}
+	techniques := []string{l.DefaultTechnique()}
+	if *technique != "" {
+		techniques = strings.Split(*technique, ",")
+	}
+	tok, err := lure.GenerateTracer(l.Name, *output, *traceUrl, opts, techniques...)
+	if err != nil {
+		return err
+	}
+	if *registry != "" {
+		r, err := token.OpenRegistry(*registry)
+		if err != nil {
+			return err
+		}
+		err = r.Add(&token.Record{Token: tok, Kind: l.Format, Memo: filepath.Base(*output), Technique: strings.Join(techniques, ",")})
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s %s\n", *output, tok)
+	return nil
+}
+
+// scan 使用内置检测规则检查文件
+func scan(args []string) error {
+	for _, filename := range args {
+		findings, err := detect.Scan(filename)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		for _, finding := range findings {
+			fmt.Printf("%s\t%s(%s)\t%s\t%s\n", filename, finding.Rule, finding.Severity, finding.Part, finding.Detail)
+		}
+	}
+	return nil
+}
+
+// selftest 使用不同的追踪技术、生成方式、追踪地址生成文件，输出会被检测规则发现的组合
+func selftest(args []string) error {
+	var (
+		fs        = flag.NewFlagSet("selftest", flag.ExitOnError)
+		traceUrl  = fs.String("url", "", "追踪地址，多个使用逗号分隔，用于对比不同的地址格式")
+		profile   = fs.String("profile", "", "生成方式，多个使用逗号分隔，默认为 default,stealth")
+		technique = fs.String("technique", "", "追踪技术，多个使用逗号分隔，默认为全部 office 追踪技术")
+		locale    = fs.String("locale", string(lure.LocaleZH), "诱饵文档语言：zh、en")
+		tag       = fs.Bool("tag", false, "同时对比追踪地址添加追踪技术名称的结果，stealth 不添加")
+	)
+	_ = fs.Parse(args)
+
+	if *traceUrl == "" {
+		return fmt.Errorf("missing -url")
+	}
+	opts := detect.SelfTestOptions{
+		TraceUrls:    strings.Split(*traceUrl, ","),
+		Locale:       lure.Locale(*locale),
+		TagTechnique: *tag,
+	}
+	if *profile != "" {
+		for _, p := range strings.Split(*profile, ",") {
+			opts.Profiles = append(opts.Profiles, ms_office.Profile(p))
+		}
+	}
+	if *technique != "" {
+		opts.Techniques = strings.Split(*technique, ",")
+	}
+
+	results, err := detect.SelfTest(opts)
+	if err != nil {
+		return err
+	}
+	return detect.WriteReport(os.Stdout, results)
+}
+
+// keys 管理追踪地址的签名密钥，轮换后旧的密钥依然用于校验，删除后才失效
+func keys(args []string) error {
+	var (
+		fs     = flag.NewFlagSet("keys", flag.ExitOnError)
+		file   = fs.String("f", "", "签名密钥文件，不存在时创建")
+		rotate = fs.Bool("rotate", false, "生成新的签名密钥")
+		remove = fs.String("remove", "", "删除指定 kid 的密钥")
+	)
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("missing -f")
+	}
+	keyring, err := token.OpenKeyring(*file)
+	if err != nil {
+		return err
+	}
+	if *rotate {
+		if _, err = keyring.Rotate(); err != nil {
+			return err
+		}
+	}
+	if *remove != "" {
+		if err = keyring.Remove(*remove); err != nil {
+			return fmt.Errorf("%s: %w", *remove, err)
+		}
+	}
+
+	all := keyring.Keys()
+	for i, key := range all {
+		current := ""
+		if i == len(all)-1 {
+			current = "current"
+		}
+		fmt.Printf("%s\t%s\t%s\n", key.ID, key.Created.Format(time.RFC3339), current)
+	}
+	return nil
+}
+
+// collect 启动 collector 的追踪服务和模拟服务，地址为空的服务不启动
+// 追踪记录以 JSON Lines 格式输出，可以同时输出 SIEM 事件、发送告警
+func collect(args []string) error {
+	var (
+		fs              = flag.NewFlagSet("collect", flag.ExitOnError)
+		httpAddr        = fs.String("http", "", "追踪地址的 HTTP 服务监听地址，例如 :80")
+		dnsAddr         = fs.String("dns", "", "DNS 服务监听地址，例如 :53")
+		domain          = fs.String("domain", "", "DNS 追踪域名，例如 canary.example.com")
+		answer          = fs.String("answer", "", "DNS A/AAAA 查询返回的地址，为空时只返回空应答")
+		smbAddr         = fs.String("smb", "", "SMB 服务监听地址，例如 :445")
+		kubeAddr        = fs.String("kube", "", "模拟 Kubernetes API 的 https 服务监听地址，例如 :6443")
+		kubeCert        = fs.String("kube-cert", "", "Kubernetes API 服务的证书文件，为空时使用自签名证书")
+		kubeKey         = fs.String("kube-key", "", "Kubernetes API 服务的私钥文件")
+		s3Addr          = fs.String("s3", "", "模拟 S3 的 HTTP 服务监听地址，例如 :9000")
+		myAddr          = fs.String("mysql", "", "MySQL 服务监听地址，例如 :3306")
+		pgAddr          = fs.String("postgres", "", "PostgreSQL 服务监听地址，例如 :5432")
+		keysFile        = fs.String("keys", "", "签名密钥文件，指定时校验追踪地址和凭据中的 token 签名")
+		strict          = fs.Bool("strict", false, "不记录未签名或签名错误的 token")
+		registry        = fs.String("registry", "", "token 注册表文件，用于告警和 SIEM 事件中的文件名、类型、追踪技术，指定时只告警已登记的 token")
+		logFile         = fs.String("log", "", "追踪记录输出文件，默认为标准输出")
+		siemFormat      = fs.String("siem", "", "SIEM 事件格式：cef、leef、ecs")
+		siemOutput      = fs.String("siem-out", "", "SIEM 事件输出文件或 syslog 地址（udp://host:514、tcp://host:514），默认为标准输出")
+		webhook         = fs.String("webhook", "", "告警 webhook 地址")
+		chat            = fs.String("chat", "", "告警机器人，格式为 类型:地址，类型为 dingtalk、wecom、feishu")
+		chatSecret      = fs.String("chat-secret", "", "告警机器人的签名密钥")
+		syslogAddr      = fs.String("syslog", "", "告警 syslog 地址，例如 udp://host:514")
+		smtpAddr        = fs.String("smtp", "", "告警邮件服务器地址，例如 smtp.example.com:587")
+		smtpFrom        = fs.String("smtp-from", "", "告警邮件发件人")
+		smtpTo          = fs.String("smtp-to", "", "告警邮件收件人，多个使用逗号分隔")
+		smtpUser        = fs.String("smtp-user", "", "告警邮件服务器的用户名，为空时不认证")
+		smtpPassword    = fs.String("smtp-password", "", "告警邮件服务器的密码")
+		alertWindow     = fs.Duration("alert-window", time.Hour, "告警去重窗口，窗口内同一 token 只告警一次")
+		alertInvalid    = fs.Bool("alert-invalid", false, "签名错误的 token 也告警")
+		alertUnverified = fs.Bool("alert-unverified", false, "未签名（指定 -keys 时）或未登记（指定 -registry 时）的 token 也告警")
+	)
+	_ = fs.Parse(args)
+
+	// 没有指定签名密钥时 signer 为 nil，不校验签名
+	var signer token.Signer
+	if *keysFile != "" {
+		keyring, err := token.OpenKeyring(*keysFile)
+		if err != nil {
+			return err
+		}
+		signer = keyring
+	}
+	var tokens *token.Registry
+	if *registry != "" {
+		r, err := token.OpenRegistry(*registry)
+		if err != nil {
+			return err
+		}
+		tokens = r
+	}
+
+	// 1、追踪记录输出：JSON Lines、SIEM 事件、告警
+	var w io.Writer = os.Stdout
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	var recorder collector.Recorder = collector.NewLogRecorder(w)
+
+	if *siemFormat != "" {
+		var out io.Writer = os.Stdout
+		switch network, addr, _ := strings.Cut(*siemOutput, "://"); {
+		case *siemOutput == "":
+		case network == "udp" || network == "tcp":
+			out = &collector.SyslogWriter{Network: network, Addr: addr}
+		default:
+			f, err := os.OpenFile(*siemOutput, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		recorder = &collector.SIEMRecorder{Format: *siemFormat, Writer: out, Registry: tokens, Next: recorder}
+	}
+
+	notifiers := make(map[string]collector.Notifier)
+	if *webhook != "" {
+		notifiers["webhook"] = &collector.WebhookNotifier{URL: *webhook}
+	}
+	if *chat != "" {
+		chatType, chatUrl, ok := strings.Cut(*chat, ":")
+		if !ok {
+			return fmt.Errorf("invalid -chat: %q", *chat)
+		}
+		notifiers["chat"] = &collector.ChatNotifier{Type: chatType, URL: chatUrl, Secret: *chatSecret}
+	}
+	if *syslogAddr != "" {
+		network, addr, ok := strings.Cut(*syslogAddr, "://")
+		if !ok {
+			network, addr = "udp", *syslogAddr
+		}
+		notifiers["syslog"] = &collector.SyslogNotifier{Network: network, Addr: addr}
+	}
+	if *smtpAddr != "" {
+		if *smtpFrom == "" || *smtpTo == "" {
+			return fmt.Errorf("missing -smtp-from or -smtp-to")
+		}
+		notifiers["smtp"] = &collector.SMTPNotifier{
+			Addr:     *smtpAddr,
+			From:     *smtpFrom,
+			To:       strings.Split(*smtpTo, ","),
+			Username: *smtpUser,
+			Password: *smtpPassword,
+		}
+	}
+	if len(notifiers) > 0 {
+		alert := &collector.AlertRecorder{
+			Notifiers:  notifiers,
+			Registry:   tokens,
+			Window:     *alertWindow,
+			Invalid:    *alertInvalid,
+			Unverified: *alertUnverified,
+			Next:       recorder,
+		}
+		defer alert.Wait()
+		recorder = alert
+	}
+
+	// 2、启动服务，任意服务退出时返回
+	var (
+		errs  = make(chan error)
+		count = 0
+		serve = func(name string, fn func() error) {
+			count++
+			go func() {
+				errs <- fmt.Errorf("%s: %w", name, fn())
+			}()
+		}
+	)
+	if *httpAddr != "" {
+		handler := &collector.TraceHandler{Signer: signer, Strict: *strict, Fingerprinter: &collector.Fingerprinter{}, Recorder: recorder}
+		serve("http", func() error {
+			return http.ListenAndServe(*httpAddr, handler)
+		})
+	}
+	if *dnsAddr != "" {
+		if *domain == "" {
+			return fmt.Errorf("missing -domain")
+		}
+		server := &collector.DNSServer{Addr: *dnsAddr, Domain: *domain, Answer: net.ParseIP(*answer), Signer: signer, Strict: *strict, Recorder: recorder}
+		serve("dns", server.ListenAndServe)
+	}
+	if *smbAddr != "" {
+		server := &collector.SMBServer{Addr: *smbAddr, Signer: signer, Strict: *strict, Recorder: recorder}
+		serve("smb", server.ListenAndServe)
+	}
+	if *kubeAddr != "" {
+		// kubeconfig 中的 server 为 https 地址，kubectl 不会使用 http 连接
+		cert, err := kubeCertificate(*kubeCert, *kubeKey)
+		if err != nil {
+			return err
+		}
+		server := &http.Server{
+			Addr:      *kubeAddr,
+			Handler:   &collector.KubeAPIHandler{Signer: signer, Strict: *strict, Recorder: recorder},
+			TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		}
+		serve("kube", func() error {
+			return server.ListenAndServeTLS("", "")
+		})
+	}
+	if *s3Addr != "" {
+		handler := &collector.S3Handler{Signer: signer, Strict: *strict, Recorder: recorder}
+		serve("s3", func() error {
+			return http.ListenAndServe(*s3Addr, handler)
+		})
+	}
+	if *myAddr != "" {
+		server := &collector.MySQLServer{Addr: *myAddr, Signer: signer, Strict: *strict, Recorder: recorder}
+		serve("mysql", server.ListenAndServe)
+	}
+	if *pgAddr != "" {
+		server := &collector.PostgresServer{Addr: *pgAddr, Signer: signer, Strict: *strict, Recorder: recorder}
+		serve("postgres", server.ListenAndServe)
+	}
+	if count == 0 {
+		return fmt.Errorf("no service to start, specify -http, -dns, -smb, -kube, -s3, -mysql or -postgres")
+	}
+	return <-errs
+}
+
+// kubeCertificate 读取 Kubernetes API 服务的证书，没有指定时生成自签名证书
+func kubeCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	if certFile == "" {
+		return collector.SelfSignedCertificate("kubernetes", "kubernetes.default", "kubernetes.default.svc")
+	}
+	return tls.LoadX509KeyPair(certFile, keyFile)
 }
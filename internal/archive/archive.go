@@ -0,0 +1,210 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"tracer/internal/ms-office"
+	"tracer/internal/open-document"
+	"tracer/internal/token"
+	"tracer/internal/web-page"
+	"tracer/pkg/utils"
+)
+
+// 添加的说明文件名称
+const ReadmeName = "README.html"
+
+const readmeTemp = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>备份说明</title></head>
+<body>
+<h3>备份说明</h3>
+<p>本压缩包为生产环境定期备份，包含配置文件、数据库导出及相关文档。</p>
+<p>恢复前请先联系运维负责人确认，账号及密码见内部文档。</p>
+</body>
+</html>
+`
+
+var ErrFormat = errors.New("unsupported archive format")
+
+// Member 添加了追踪信息的压缩包成员
+type Member struct {
+	Name  string // 压缩包中的路径
+	Token string
+}
+
+// tracer 成员的追踪函数，返回生成的 token
+type tracer func(srcFile, dstFile, traceUrl string, signer token.Signer) (tok string, err error)
+
+// withToken 生成 token 并添加到追踪地址路径末尾，用于不生成 token 的追踪函数
+func withToken(gen func(srcFile, dstFile, traceUrl string) error) tracer {
+	return func(srcFile, dstFile, traceUrl string, signer token.Signer) (string, error) {
+		tok := token.New()
+		err := gen(srcFile, dstFile, token.URL(signer, utils.UNCToUrl(traceUrl), tok))
+		if err != nil {
+			return "", err
+		}
+		return tok, nil
+	}
+}
+
+// tracers 根据扩展名选择追踪函数，不在其中的成员保持不变
+var tracers = map[string]tracer{
+	".docx":  withToken(ms_office.GenTracerDOCX),
+	".docm":  withToken(ms_office.GenTracerDOCX),
+	".xlsx":  withToken(ms_office.GenTracerXLSX),
+	".xlsm":  withToken(ms_office.GenTracerXLSX),
+	".pptx":  withToken(ms_office.GenTracerPPTX),
+	".pptm":  withToken(ms_office.GenTracerPPTX),
+	".doc":   withToken(ms_office.GenTracerDOC),
+	".xls":   withToken(ms_office.GenTracerXLS),
+	".ppt":   withToken(ms_office.GenTracerPPT),
+	".rtf":   withToken(ms_office.GenTracerRTF),
+	".odt":   withToken(open_document.GenTracer),
+	".ods":   withToken(open_document.GenTracer),
+	".odp":   withToken(open_document.GenTracer),
+	".html":  web_page.GenTracerHTML,
+	".htm":   web_page.GenTracerHTML,
+	".mht":   web_page.GenTracerMHT,
+	".mhtml": web_page.GenTracerMHT,
+	".svg":   web_page.GenTracerSVG,
+}
+
+// GenTracerArchive 生成可追踪的压缩包（zip、tar、tar.gz）
+// 对支持的成员添加追踪信息，每个成员使用不同的 token，其余成员、目录结构、顺序、时间、权限、注释保持不变
+// readme: 是否在末尾添加可追踪的 README.html，已存在同名成员时不添加
+// reg: 登记成员 token 的注册表，类型为成员的扩展名，说明为 压缩包文件名/成员路径，为空时不登记
+// signer: 签名成员的 token，为 nil 时不签名
+// 不支持 7z、rar 等格式
+func GenTracerArchive(srcFile, dstFile, traceUrl string, readme bool, reg *token.Registry, signer token.Signer) (members []Member, err error) {
+	var (
+		header []byte
+	)
+
+	// 1、根据文件头判断格式
+	f, err := os.Open(srcFile)
+	if err != nil {
+		return nil, err
+	}
+	header, _ = bufio.NewReader(f).Peek(512)
+	_ = f.Close()
+
+	// 2、临时目录，用于保存单个成员
+	tempDir, err := os.MkdirTemp("", "archive-trace-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+	t := &archiveTracer{traceUrl: traceUrl, signer: signer, tempDir: tempDir, readme: readme}
+
+	// 3、逐个处理成员
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")) || bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		err = t.traceZip(srcFile, dstFile)
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		err = t.traceTar(srcFile, dstFile, true)
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		err = t.traceTar(srcFile, dstFile, false)
+	default:
+		return nil, ErrFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 4、登记成员 token，collector 根据 token 找到压缩包和成员
+	if reg != nil {
+		for _, member := range t.members {
+			err = reg.Add(&token.Record{
+				Token: member.Token,
+				Kind:  strings.TrimPrefix(strings.ToLower(path.Ext(member.Name)), "."),
+				Memo:  filepath.Base(dstFile) + "/" + member.Name,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t.members, nil
+}
+
+// archiveTracer 处理压缩包成员
+type archiveTracer struct {
+	traceUrl string
+	signer   token.Signer
+	tempDir  string
+	readme   bool
+	members  []Member
+	count    int
+}
+
+// supported 判断成员是否需要添加追踪信息
+func supported(name string) bool {
+	_, ok := tracers[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// trace 对成员内容添加追踪信息，返回新的内容
+func (t *archiveTracer) trace(name string, data []byte) ([]byte, error) {
+	gen := tracers[strings.ToLower(path.Ext(name))]
+
+	// 每个成员使用单独的目录，保留文件名及扩展名
+	t.count++
+	dir := filepath.Join(t.tempDir, fmt.Sprint(t.count))
+	err := os.Mkdir(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	srcFile := filepath.Join(dir, "src"+path.Ext(name))
+	dstFile := filepath.Join(dir, path.Base(name))
+	err = os.WriteFile(srcFile, data, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := gen(srcFile, dstFile, t.traceUrl, t.signer)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	t.members = append(t.members, Member{Name: name, Token: tok})
+	return os.ReadFile(dstFile)
+}
+
+// readmeName README 的路径，所有成员位于同一个顶层目录时添加到该目录中
+// 已存在同名成员时返回 false
+func readmeName(names []string) (string, bool) {
+	name := path.Join(commonDir(names), ReadmeName)
+	return name, !contains(names, name)
+}
+
+// commonDir 所有成员位于同一个顶层目录时返回该目录，否则返回空
+// backup/a.docx, backup/db/ => backup
+func commonDir(names []string) string {
+	dir := ""
+	for _, name := range names {
+		top, _, ok := strings.Cut(strings.TrimPrefix(name, "./"), "/")
+		if !ok || (dir != "" && top != dir) {
+			return ""
+		}
+		dir = top
+	}
+	return dir
+}
+
+// contains 判断压缩包中是否已存在同名成员
+func contains(names []string, name string) bool {
+	for _, v := range names {
+		if strings.TrimPrefix(v, "./") == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tracer/internal/lure"
+	"tracer/internal/testutil"
+	"tracer/internal/token"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10"><rect width="10" height="10"/></svg>`
+
+var testTime = time.Date(2023, 6, 1, 8, 30, 0, 0, time.UTC)
+
+// testDOCX 使用诱饵文档模板生成的 docx 文件内容
+func testDOCX(t *testing.T) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "report.docx")
+	if err := lure.Generate("credentials", filename, lure.Options{Seed: 1, Date: testTime}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// checkTraced 检查成员及 README 已添加追踪信息
+func checkTraced(t *testing.T, members []Member, signer token.Signer, read func(name string) string, wantNames ...string) {
+	t.Helper()
+	if len(members) != len(wantNames) {
+		t.Fatalf("members = %+v", members)
+	}
+	for i, member := range members {
+		if member.Name != wantNames[i] || member.Token == "" {
+			t.Errorf("member %d = %+v, want %s", i, member, wantNames[i])
+			continue
+		}
+
+		content := read(member.Name)
+		if strings.HasSuffix(member.Name, ".docx") {
+			files := testutil.ReadZip(t, []byte(content))
+			content = files["word/_rels/settings.xml.rels"]
+		}
+		if !strings.Contains(content, "http://10.0.0.1/t/"+token.Encode(signer, member.Token)) {
+			t.Errorf("%s not traced: %s", member.Name, content)
+		}
+	}
+}
+
+func TestGenTracerArchiveZip(t *testing.T) {
+	dir := t.TempDir()
+	srcFile := filepath.Join(dir, "backup.zip")
+
+	// 1、生成压缩包：目录、存储、注释、时间
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	entries := []struct {
+		name    string
+		method  uint16
+		comment string
+		content string
+	}{
+		{"backup/", zip.Store, "", ""},
+		{"backup/notes.txt", zip.Store, "notes", "keep"},
+		{"backup/report.docx", zip.Deflate, "quarterly", testDOCX(t)},
+		{"backup/db/schema.sql", zip.Deflate, "", "create table t (id int);"},
+		{"backup/logo.svg", zip.Deflate, "", testSVG},
+	}
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: e.method, Comment: e.comment, Modified: testTime}
+		header.SetMode(0640)
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = fw.Write([]byte(e.content))
+	}
+	_ = w.SetComment("nightly backup")
+	_ = w.Close()
+	if err := os.WriteFile(srcFile, buf.Bytes(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2、添加追踪信息
+	dstFile := filepath.Join(dir, "traced.zip")
+	members, err := GenTracerArchive(srcFile, dstFile, "http://10.0.0.1/t", true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := testutil.ZipReader(t, b)
+	files := testutil.ReadZip(t, b)
+
+	// 3、顺序、文件头保持不变，README 添加到顶层目录末尾
+	if r.Comment != "nightly backup" || len(r.File) != len(entries)+1 {
+		t.Fatalf("comment = %s, files = %d", r.Comment, len(r.File))
+	}
+	for i, e := range entries {
+		f := r.File[i]
+		if f.Name != e.name || f.Method != e.method || f.Comment != e.comment || !f.Modified.Equal(testTime) {
+			t.Errorf("file %d = %s %d %q %v", i, f.Name, f.Method, f.Comment, f.Modified)
+		}
+		if !f.FileInfo().IsDir() && f.Mode().Perm() != 0640 {
+			t.Errorf("%s mode = %v", f.Name, f.Mode())
+		}
+	}
+	if readme := r.File[len(entries)]; readme.Name != "backup/"+ReadmeName || !readme.Modified.Equal(testTime) {
+		t.Errorf("readme = %s %v", readme.Name, readme.Modified)
+	}
+	if files["backup/notes.txt"] != "keep" || files["backup/db/schema.sql"] != entries[3].content {
+		t.Error("unsupported members changed")
+	}
+	checkTraced(t, members, nil, func(name string) string { return files[name] },
+		"backup/report.docx", "backup/logo.svg", "backup/"+ReadmeName)
+}
+
+func TestGenTracerArchiveTarGz(t *testing.T) {
+	dir := t.TempDir()
+	srcFile := filepath.Join(dir, "backup.tar.gz")
+
+	// 1、生成压缩包：gzip 文件头、所有者、权限、软链接
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Name = "backup.tar"
+	zw.ModTime = testTime
+	tw := tar.NewWriter(zw)
+	entries := []*tar.Header{
+		{Typeflag: tar.TypeDir, Name: "docs/", Mode: 0755},
+		{Typeflag: tar.TypeReg, Name: "docs/plan.docx", Mode: 0600, Uid: 1000, Uname: "ops"},
+		{Typeflag: tar.TypeSymlink, Name: "docs/latest.docx", Linkname: "plan.docx", Mode: 0777},
+		{Typeflag: tar.TypeReg, Name: "run.sh", Mode: 0755},
+	}
+	contents := []string{"", testDOCX(t), "", "#!/bin/sh\n"}
+	for i, header := range entries {
+		header.ModTime = testTime
+		header.Size = int64(len(contents[i]))
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tw.Write([]byte(contents[i]))
+	}
+	_ = tw.Close()
+	_ = zw.Close()
+	if err := os.WriteFile(srcFile, buf.Bytes(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2、添加追踪信息，成员 token 登记到注册表，追踪地址中为签名后的 token
+	reg, err := token.OpenRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstFile := filepath.Join(dir, "traced.tar.gz")
+	members, err := GenTracerArchive(srcFile, dstFile, "http://10.0.0.1/t", true, reg, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.Name != "backup.tar" || !zr.ModTime.Equal(testTime) {
+		t.Errorf("gzip header = %s %v", zr.Name, zr.ModTime)
+	}
+
+	// 3、顺序、文件头保持不变，成员不在同一个目录中时 README 添加到根目录
+	tr := tar.NewReader(zr)
+	var headers []*tar.Header
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		headers = append(headers, header)
+		files[header.Name] = string(data)
+	}
+	if len(headers) != len(entries)+1 || headers[len(entries)].Name != ReadmeName {
+		t.Fatalf("headers = %d", len(headers))
+	}
+	for i, want := range entries {
+		got := headers[i]
+		if got.Name != want.Name || got.Typeflag != want.Typeflag || got.Mode != want.Mode || got.Uname != want.Uname ||
+			got.Linkname != want.Linkname || !got.ModTime.Equal(testTime) {
+			t.Errorf("header %d = %+v", i, got)
+		}
+	}
+	if files["run.sh"] != "#!/bin/sh\n" {
+		t.Error("unsupported members changed")
+	}
+	checkTraced(t, members, keyring, func(name string) string { return files[name] }, "docs/plan.docx", ReadmeName)
+
+	// 4、根据 token 找到压缩包和成员
+	for _, member := range members {
+		record, ok := reg.Lookup(member.Token)
+		if !ok || record.Memo != "traced.tar.gz/"+member.Name {
+			t.Errorf("record = %+v", record)
+		}
+	}
+	if record, _ := reg.Lookup(members[0].Token); record == nil || record.Kind != "docx" {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestGenTracerArchiveFormat(t *testing.T) {
+	dir := t.TempDir()
+	srcFile := filepath.Join(dir, "backup.7z")
+	if err := os.WriteFile(srcFile, []byte("7z\xbc\xaf\x27\x1c\x00\x04"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenTracerArchive(srcFile, filepath.Join(dir, "out.7z"), "http://10.0.0.1/t", false, nil, nil); !errors.Is(err, ErrFormat) {
+		t.Errorf("error = %v, want ErrFormat", err)
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+	}{
+		{[]string{"backup/", "backup/a.docx", "backup/db/b.sql"}, "backup"},
+		{[]string{"./backup/a.docx", "./backup/b.docx"}, "backup"},
+		{[]string{"backup/a.docx", "other/b.docx"}, ""},
+		{[]string{"backup/a.docx", "b.docx"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := commonDir(tt.names); got != tt.want {
+			t.Errorf("commonDir(%q) = %s, want %s", tt.names, got, tt.want)
+		}
+	}
+}
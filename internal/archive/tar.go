@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// traceTar 处理 tar、tar.gz 压缩包
+// 修改的成员只更新大小，其余文件头（时间、权限、所有者、PAX 扩展等）保持不变；gzip 文件头保持不变
+func (t *archiveTracer) traceTar(srcFile, dstFile string, gz bool) (err error) {
+	var (
+		r      io.Reader
+		w      io.Writer
+		zw     *gzip.Writer
+		names  []string
+		latest time.Time
+		last   *tar.Header
+	)
+
+	in, err := os.Open(srcFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	out, err := os.Create(dstFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	r, w = in, out
+
+	if gz {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return err
+		}
+		zw = gzip.NewWriter(out)
+		zw.Header = zr.Header
+		r, w = zr, zw
+	}
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+
+	// 1、按原有顺序处理成员
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		names = append(names, header.Name)
+		if header.ModTime.After(latest) {
+			latest = header.ModTime
+		}
+		last = header
+
+		if !header.FileInfo().Mode().IsRegular() || !supported(header.Name) {
+			err = tw.WriteHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, tr)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		data, err = t.trace(header.Name, data)
+		if err != nil {
+			return err
+		}
+		header.Size = int64(len(data))
+		err = tw.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 2、添加 README，所有者与最后一个成员相同
+	if t.readme {
+		if name, ok := readmeName(names); ok {
+			data, err := t.trace(name, []byte(readmeTemp))
+			if err != nil {
+				return err
+			}
+			header := &tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     name,
+				Mode:     0644,
+				Size:     int64(len(data)),
+				ModTime:  latest,
+			}
+			if last != nil {
+				header.Uid, header.Gid = last.Uid, last.Gid
+				header.Uname, header.Gname = last.Uname, last.Gname
+				header.Format = last.Format
+			}
+			err = tw.WriteHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = tw.Write(data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+	if zw != nil {
+		return zw.Close()
+	}
+	return nil
+}
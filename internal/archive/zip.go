@@ -0,0 +1,133 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+)
+
+// zip 扩展字段及标志
+const (
+	zipExtraZip64    = 0x0001
+	zipFlagEncrypted = 0x1
+)
+
+// traceZip 处理 zip 压缩包
+// 不需要修改的成员直接复制压缩后的数据，修改的成员保留原有的文件头（名称、压缩方式、时间、属性、注释、扩展字段）
+func (t *archiveTracer) traceZip(srcFile, dstFile string) (err error) {
+	var (
+		names  []string
+		latest time.Time
+	)
+
+	r, err := zip.OpenReader(srcFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	out, err := os.Create(dstFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	w := zip.NewWriter(out)
+
+	// 1、按原有顺序处理成员
+	for _, f := range r.File {
+		names = append(names, f.Name)
+		if f.Modified.After(latest) {
+			latest = f.Modified
+		}
+
+		if f.FileInfo().IsDir() || f.Flags&zipFlagEncrypted != 0 || !supported(f.Name) {
+			err = w.Copy(f)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+		data, err = t.trace(f.Name, data)
+		if err != nil {
+			return err
+		}
+
+		// 使用原有的 DOS 时间和扩展时间戳，Modified 不为空时会再添加一个扩展时间戳
+		header := f.FileHeader
+		header.Modified = time.Time{}
+		header.Extra = zipExtra(header.Extra)
+		fw, err := w.CreateHeader(&header)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 2、添加 README
+	if t.readme {
+		if name, ok := readmeName(names); ok {
+			data, err := t.trace(name, []byte(readmeTemp))
+			if err != nil {
+				return err
+			}
+			fw, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: latest})
+			if err != nil {
+				return err
+			}
+			_, err = fw.Write(data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// 3、压缩包注释
+	err = w.SetComment(r.Comment)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// readZipFile 读取成员内容
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	return io.ReadAll(rc)
+}
+
+// zipExtra 删除 zip64 扩展字段，其中的大小已经失效，写入时会根据需要重新生成
+func zipExtra(extra []byte) []byte {
+	var b []byte
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if 4+size > len(extra) {
+			break
+		}
+		if id != zipExtraZip64 {
+			b = append(b, extra[:4+size]...)
+		}
+		extra = extra[4+size:]
+	}
+	return b
+}
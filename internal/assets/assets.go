@@ -13,3 +13,9 @@ var MSDrawing []byte
 
 //go:embed drawing.xml.tpl
 var MSDrawingTpl string
+
+//go:embed header.xml.tpl
+var MSHeaderTpl string
+
+//go:embed paragraph.xml.tpl
+var MSParagraphTpl string
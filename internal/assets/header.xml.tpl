@@ -0,0 +1,8 @@
+<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:hdr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+    <w:p>
+        <w:pPr>
+            <w:pStyle w:val="Header"/>
+        </w:pPr>
+    </w:p>
+</w:hdr>
@@ -0,0 +1,291 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tracer/internal/token"
+)
+
+// Alert 告警内容
+type Alert struct {
+	Hit    *Hit          `json:"hit"`
+	Record *token.Record `json:"record,omitempty"` // 注册表中的 token 记录，未登记时为空
+}
+
+// Subject 告警标题
+func (a *Alert) Subject() string {
+	if a.Record != nil && a.Record.Memo != "" {
+		return fmt.Sprintf("[tracer] %s 被访问", a.Record.Memo)
+	}
+	return fmt.Sprintf("[tracer] token %s 被触发", a.Hit.Token)
+}
+
+// Text 告警正文，每行一个字段，只输出非空字段
+func (a *Alert) Text() string {
+	var (
+		b    strings.Builder
+		line = func(name, value string) {
+			if value != "" {
+				b.WriteString(name + ": " + value + "\n")
+			}
+		}
+		user = a.Hit.User
+	)
+	if a.Hit.Domain != "" {
+		user = a.Hit.Domain + `\` + user
+	}
+
+	line("时间", a.Hit.Time.Format(time.RFC3339))
+	line("token", a.Hit.Token)
+	if a.Record != nil {
+		line("类型", a.Record.Kind)
+		line("文件", a.Record.Memo)
+	}
+	line("协议", a.Hit.Protocol)
+	line("来源", a.Hit.RemoteAddr)
+	line("请求", a.Hit.Query)
+	line("签名", a.Hit.Signature)
+	line("用户", user)
+	line("主机", a.Hit.Workstation)
+	line("凭据", a.Hit.Credential)
+	line("数据库", a.Hit.Database)
+	line("客户端", a.Hit.Client)
+	line("应用", strings.TrimSpace(a.Hit.App+" "+a.Hit.AppVersion))
+	line("操作系统", a.Hit.OS)
+	return b.String()
+}
+
+// Notifier 告警渠道
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+// AlertRecorder 在 token 首次触发时发送告警，同时将追踪记录交给 Next 记录
+// 告警在后台发送，失败时按照 Backoff 翻倍重试，不影响模拟服务的响应
+// 至少一个告警渠道发送成功后才进入去重窗口，全部失败时下一次触发重新告警
+// 任何人都可以构造 token 的请求，collector 校验签名时只告警签名有效的 token，设置 Registry 时只告警已登记的 token
+type AlertRecorder struct {
+	Notifiers  map[string]Notifier // 告警渠道名称 => 告警渠道
+	Routes     map[string][]string // token 或注册表中的类型 => 告警渠道名称，token 优先
+	Default    []string            // 没有匹配的路由时使用的告警渠道，为空时发送到全部告警渠道
+	Registry   *token.Registry     // 查找 token 对应的文件，可选
+	Window     time.Duration       // 去重窗口，窗口内同一 token 只告警一次，为 0 时只在首次触发时告警
+	Invalid    bool                // 签名错误的 token 也告警，默认不告警，签名错误的请求通常是伪造或扫描
+	Unverified bool                // 未签名（collector 校验签名时）或未登记（设置 Registry 时）的 token 也告警，默认不告警
+	MaxTokens  int                 // 去重记录的最大 token 数量，超过时删除最早的记录，默认 10000
+	Retries    int                 // 失败后的重试次数，默认 3，小于 0 时不重试
+	Backoff    time.Duration       // 第一次重试的间隔，默认 1 秒
+	Timeout    time.Duration       // 每次发送的超时时间，默认 10 秒
+	ErrorLog   *log.Logger         // 重试后依然失败时记录错误，为空时使用 log 包的默认 Logger
+	Next       Recorder
+
+	mu      sync.Mutex
+	last    map[string]time.Time // token => 最后一次告警成功的触发时间
+	sending map[string]time.Time // token => 正在发送的告警的触发时间
+	wg      sync.WaitGroup
+}
+
+func (r *AlertRecorder) Record(hit *Hit) error {
+	var err error
+	if r.Next != nil {
+		err = r.Next.Record(hit)
+	}
+	if hit.Token == "" || !r.verified(hit) {
+		return err
+	}
+
+	alert := &Alert{Hit: hit}
+	if r.Registry != nil {
+		alert.Record, _ = r.Registry.Lookup(hit.Token)
+		if alert.Record == nil && !r.Unverified {
+			return err
+		}
+	}
+	if !r.first(hit) {
+		return err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.done(hit, r.send(alert))
+	}()
+	return err
+}
+
+// verified 根据签名校验结果判断是否需要告警，collector 没有校验签名时 Signature 为空
+func (r *AlertRecorder) verified(hit *Hit) bool {
+	switch hit.Signature {
+	case SignatureInvalid:
+		return r.Invalid
+	case SignatureUnsigned:
+		return r.Unverified
+	}
+	return true
+}
+
+// send 并发发送到全部告警渠道，返回是否至少一个告警渠道发送成功
+func (r *AlertRecorder) send(alert *Alert) bool {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ok bool
+	)
+	for _, name := range r.route(alert) {
+		notifier, exists := r.Notifiers[name]
+		if !exists {
+			r.logf("alert %s: unknown notifier", name)
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, notifier Notifier) {
+			defer wg.Done()
+			if err := r.notify(notifier, alert); err != nil {
+				r.logf("alert %s: %v", name, err)
+				return
+			}
+			mu.Lock()
+			ok = true
+			mu.Unlock()
+		}(name, notifier)
+	}
+	wg.Wait()
+	return ok
+}
+
+// Wait 等待正在发送的告警完成
+func (r *AlertRecorder) Wait() {
+	r.wg.Wait()
+}
+
+// first 判断是否需要告警：去重窗口内没有告警成功，也没有正在发送的告警
+// 同时删除去重窗口之外的记录，Window 为 0 时记录保留到超过 MaxTokens
+func (r *AlertRecorder) first(hit *Hit) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last == nil {
+		r.last = make(map[string]time.Time)
+		r.sending = make(map[string]time.Time)
+	}
+	if r.Window > 0 {
+		for tok, last := range r.last {
+			if hit.Time.Sub(last) >= r.Window {
+				delete(r.last, tok)
+			}
+		}
+	}
+
+	for _, m := range []map[string]time.Time{r.last, r.sending} {
+		last, ok := m[hit.Token]
+		if ok && (r.Window <= 0 || hit.Time.Sub(last) < r.Window) {
+			return false
+		}
+	}
+	r.sending[hit.Token] = hit.Time
+	return true
+}
+
+// done 告警发送完成，成功时记录告警时间，失败时只清除发送状态
+// 记录超过 MaxTokens 时删除告警时间最早的记录，避免伪造大量 token 占用内存
+func (r *AlertRecorder) done(hit *Hit, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sending[hit.Token].Equal(hit.Time) {
+		delete(r.sending, hit.Token)
+	}
+	if ok && hit.Time.After(r.last[hit.Token]) {
+		r.last[hit.Token] = hit.Time
+	}
+
+	maxTokens := r.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 10000
+	}
+	for len(r.last) > maxTokens {
+		var (
+			oldest string
+			first  time.Time
+		)
+		for tok, last := range r.last {
+			if oldest == "" || last.Before(first) {
+				oldest, first = tok, last
+			}
+		}
+		delete(r.last, oldest)
+	}
+}
+
+// route 获取告警渠道名称：token 路由、类型路由、默认告警渠道、全部告警渠道
+func (r *AlertRecorder) route(alert *Alert) []string {
+	if names, ok := r.Routes[alert.Hit.Token]; ok {
+		return names
+	}
+	if alert.Record != nil {
+		if names, ok := r.Routes[alert.Record.Kind]; ok {
+			return names
+		}
+	}
+	if len(r.Default) > 0 {
+		return r.Default
+	}
+
+	names := make([]string, 0, len(r.Notifiers))
+	for name := range r.Notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// notify 发送告警，失败时重试
+func (r *AlertRecorder) notify(notifier Notifier, alert *Alert) error {
+	var (
+		retries = r.Retries
+		backoff = r.Backoff
+		timeout = r.Timeout
+	)
+	if retries == 0 {
+		retries = 3
+	} else if retries < 0 {
+		retries = 0
+	}
+	if backoff == 0 {
+		backoff = time.Second
+	}
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	var err error
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err = notifier.Notify(ctx, alert)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%d retries: %w", retries, err)
+}
+
+func (r *AlertRecorder) logf(format string, v ...any) {
+	if r.ErrorLog != nil {
+		r.ErrorLog.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
@@ -0,0 +1,349 @@
+package collector
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tracer/internal/token"
+)
+
+// testNotifier 记录告警，前 fail 次返回错误
+type testNotifier struct {
+	mu     sync.Mutex
+	fail   int
+	calls  int
+	alerts []*Alert
+}
+
+func (n *testNotifier) Notify(_ context.Context, alert *Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.calls++
+	if n.calls <= n.fail {
+		return errors.New("unavailable")
+	}
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func testAlert() *Alert {
+	return &Alert{
+		Hit: &Hit{
+			Time:       time.Date(2026, 10, 19, 3, 12, 0, 0, time.UTC),
+			Protocol:   "smb",
+			RemoteAddr: "10.0.0.8",
+			Token:      "abc234abc234abcd",
+			User:       "alice",
+			Domain:     "CORP",
+		},
+		Record: &token.Record{Token: "abc234abc234abcd", Kind: "docx", Memo: "工资表.docx"},
+	}
+}
+
+func TestAlertRecorder(t *testing.T) {
+	registry, err := token.OpenRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []*token.Record
+	for _, kind := range []string{"env", "docx", "xlsx"} {
+		record, err := registry.Mint(kind, "."+kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	var (
+		webhook  = &testNotifier{fail: 2}
+		mail     = &testNotifier{}
+		chat     = &testNotifier{}
+		logged   []*Hit
+		start    = time.Now()
+		env      = records[0]
+		tok      = records[1].Token
+		special  = records[2].Token
+		recorder = &AlertRecorder{
+			Notifiers: map[string]Notifier{"webhook": webhook, "mail": mail, "chat": chat},
+			Routes:    map[string][]string{special: {"chat"}, "env": {"mail"}},
+			Default:   []string{"webhook"},
+			Registry:  registry,
+			Window:    time.Hour,
+			Backoff:   time.Millisecond,
+			Next: RecorderFunc(func(hit *Hit) error {
+				logged = append(logged, hit)
+				return nil
+			}),
+		}
+	)
+
+	// 1、同一 token 在去重窗口内只告警一次，窗口之后再次告警；未登记的 token 不告警
+	for _, hit := range []*Hit{
+		{Time: start, Token: tok},
+		{Time: start.Add(time.Minute), Token: tok},
+		{Time: start.Add(2 * time.Hour), Token: tok},
+		{Time: start, Token: env.Token},
+		{Time: start, Token: special},
+		{Time: start, Token: ""},
+		{Time: start, Token: token.New()},
+	} {
+		if err = recorder.Record(hit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorder.Wait()
+
+	if len(logged) != 7 {
+		t.Errorf("logged = %d, want 7", len(logged))
+	}
+
+	// 2、默认渠道失败后重试
+	if len(webhook.alerts) != 2 || webhook.calls != 4 || webhook.alerts[0].Hit.Token != tok {
+		t.Errorf("webhook calls = %d, alerts = %d", webhook.calls, len(webhook.alerts))
+	}
+
+	// 3、按照类型、token 路由
+	if len(mail.alerts) != 1 || mail.alerts[0].Record == nil || mail.alerts[0].Record.Memo != ".env" {
+		t.Errorf("mail alerts = %+v", mail.alerts)
+	}
+	if len(chat.alerts) != 1 || chat.alerts[0].Hit.Token != special {
+		t.Errorf("chat alerts = %+v", chat.alerts)
+	}
+}
+
+func TestAlertRecorderFirst(t *testing.T) {
+	var (
+		notifier = &testNotifier{fail: 1}
+		output   strings.Builder
+		recorder = &AlertRecorder{
+			Notifiers: map[string]Notifier{"webhook": notifier},
+			Retries:   -1,
+			ErrorLog:  log.New(&output, "", 0),
+		}
+		start = time.Now()
+	)
+
+	// 窗口为 0 时只在首次告警成功后不再告警，不重试，失败时记录错误，下一次触发重新告警
+	tok := token.New()
+	for i := 0; i < 3; i++ {
+		_ = recorder.Record(&Hit{Time: start.Add(time.Duration(i) * 24 * time.Hour), Token: tok})
+		recorder.Wait()
+	}
+
+	if notifier.calls != 2 || len(notifier.alerts) != 1 || !strings.Contains(output.String(), "alert webhook: 0 retries: unavailable") {
+		t.Errorf("calls = %d, alerts = %d, log = %s", notifier.calls, len(notifier.alerts), output.String())
+	}
+}
+
+func TestAlertRecorderInvalid(t *testing.T) {
+	var (
+		notifier = &testNotifier{}
+		recorder = &AlertRecorder{Notifiers: map[string]Notifier{"webhook": notifier}}
+		start    = time.Now()
+	)
+
+	// 签名错误、未签名的 token 默认不告警，避免伪造 token 的请求触发大量告警
+	_ = recorder.Record(&Hit{Time: start, Token: token.New(), Signature: SignatureInvalid})
+	_ = recorder.Record(&Hit{Time: start, Token: token.New(), Signature: SignatureUnsigned})
+	_ = recorder.Record(&Hit{Time: start, Token: token.New(), Signature: SignatureValid})
+	recorder.Wait()
+	if notifier.calls != 1 {
+		t.Errorf("calls = %d, want 1", notifier.calls)
+	}
+
+	recorder.Invalid = true
+	_ = recorder.Record(&Hit{Time: start, Token: token.New(), Signature: SignatureInvalid})
+	_ = recorder.Record(&Hit{Time: start, Token: token.New(), Signature: SignatureUnsigned})
+	recorder.Wait()
+	if notifier.calls != 2 {
+		t.Errorf("calls = %d, want 2", notifier.calls)
+	}
+
+	recorder.Unverified = true
+	_ = recorder.Record(&Hit{Time: start, Token: token.New(), Signature: SignatureUnsigned})
+	recorder.Wait()
+	if notifier.calls != 3 {
+		t.Errorf("calls = %d, want 3", notifier.calls)
+	}
+}
+
+func TestAlertRecorderUnregistered(t *testing.T) {
+	registry, err := token.OpenRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		notifier = &testNotifier{}
+		recorder = &AlertRecorder{Notifiers: map[string]Notifier{"webhook": notifier}, Registry: registry}
+		start    = time.Now()
+	)
+
+	// 设置 Registry 时未登记的 token 默认不告警，Unverified 时告警且没有注册表记录
+	_ = recorder.Record(&Hit{Time: start, Token: token.New()})
+	recorder.Wait()
+	recorder.Unverified = true
+	_ = recorder.Record(&Hit{Time: start, Token: token.New()})
+	recorder.Wait()
+	if notifier.calls != 1 || notifier.alerts[0].Record != nil {
+		t.Errorf("calls = %d, alerts = %+v", notifier.calls, notifier.alerts)
+	}
+}
+
+func TestAlertRecorderEvict(t *testing.T) {
+	var (
+		notifier = &testNotifier{}
+		recorder = &AlertRecorder{Notifiers: map[string]Notifier{"webhook": notifier}, Window: time.Hour}
+		start    = time.Now()
+	)
+
+	// 去重窗口之外的记录在下一次触发时删除
+	for i := 0; i < 10; i++ {
+		_ = recorder.Record(&Hit{Time: start, Token: token.New()})
+	}
+	recorder.Wait()
+	if len(recorder.last) != 10 {
+		t.Fatalf("last = %d, want 10", len(recorder.last))
+	}
+
+	_ = recorder.Record(&Hit{Time: start.Add(2 * time.Hour), Token: token.New()})
+	recorder.Wait()
+	if len(recorder.last) != 1 || len(recorder.sending) != 0 || notifier.calls != 11 {
+		t.Errorf("last = %d, sending = %d, calls = %d", len(recorder.last), len(recorder.sending), notifier.calls)
+	}
+}
+
+func TestAlertRecorderMaxTokens(t *testing.T) {
+	var (
+		notifier = &testNotifier{}
+		recorder = &AlertRecorder{Notifiers: map[string]Notifier{"webhook": notifier}, MaxTokens: 5}
+		start    = time.Now()
+		first    = token.New()
+	)
+
+	// 窗口为 0 时记录超过 MaxTokens 后删除最早的记录，被删除的 token 再次触发时重新告警
+	_ = recorder.Record(&Hit{Time: start, Token: first})
+	recorder.Wait()
+	for i := 1; i < 10; i++ {
+		_ = recorder.Record(&Hit{Time: start.Add(time.Duration(i) * time.Second), Token: token.New()})
+		recorder.Wait()
+	}
+	if len(recorder.last) != 5 {
+		t.Fatalf("last = %d, want 5", len(recorder.last))
+	}
+
+	_ = recorder.Record(&Hit{Time: start.Add(time.Minute), Token: first})
+	recorder.Wait()
+	if notifier.calls != 11 || len(recorder.last) != 5 {
+		t.Errorf("calls = %d, last = %d", notifier.calls, len(recorder.last))
+	}
+}
+
+func TestAlertText(t *testing.T) {
+	alert := testAlert()
+	if got := alert.Subject(); got != "[tracer] 工资表.docx 被访问" {
+		t.Errorf("Subject() = %s", got)
+	}
+	want := "时间: 2026-10-19T03:12:00Z\ntoken: abc234abc234abcd\n类型: docx\n文件: 工资表.docx\n协议: smb\n来源: 10.0.0.8\n用户: CORP\\alice\n"
+	if got := alert.Text(); got != want {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got struct {
+		Alert  Alert
+		Header http.Header
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Header = r.Header
+		_ = json.NewDecoder(r.Body).Decode(&got.Alert)
+	}))
+	defer server.Close()
+
+	n := &WebhookNotifier{URL: server.URL, Header: http.Header{"Authorization": {"Bearer secret"}}}
+	if err := n.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Header.Get("Authorization") != "Bearer secret" || got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("header = %v", got.Header)
+	}
+	if got.Alert.Hit == nil || got.Alert.Hit.User != "alice" || got.Alert.Record == nil || got.Alert.Record.Kind != "docx" {
+		t.Errorf("alert = %+v", got.Alert)
+	}
+
+	// 状态码不是 2xx 时返回错误
+	n.URL = server.URL + "/missing"
+	server.Config.Handler = http.NotFoundHandler()
+	if err := n.Notify(context.Background(), testAlert()); err == nil {
+		t.Error("want error for 404")
+	}
+}
+
+func TestChatNotifier(t *testing.T) {
+	const secret = "SEC000"
+	tests := []struct {
+		chatType string
+		response string
+		check    func(r *http.Request, body map[string]any) bool
+		wantErr  bool
+	}{
+		{ChatDingTalk, `{"errcode":0,"errmsg":"ok"}`, func(r *http.Request, body map[string]any) bool {
+			// 签名：HMAC-SHA256(secret, timestamp + "\n" + secret)
+			h := hmac.New(sha256.New, []byte(secret))
+			h.Write([]byte(r.URL.Query().Get("timestamp") + "\n" + secret))
+			text, _ := body["text"].(map[string]any)
+			return r.URL.Query().Get("access_token") == "x" && body["msgtype"] == "text" &&
+				r.URL.Query().Get("sign") == base64.StdEncoding.EncodeToString(h.Sum(nil)) &&
+				strings.Contains(text["content"].(string), "CORP\\alice")
+		}, false},
+		{ChatWeCom, `{"errcode":0,"errmsg":"ok"}`, func(r *http.Request, body map[string]any) bool {
+			return body["msgtype"] == "text" && r.URL.Query().Get("sign") == ""
+		}, false},
+		{ChatFeishu, `{"code":0,"msg":"success"}`, func(r *http.Request, body map[string]any) bool {
+			// 签名：HMAC-SHA256(timestamp + "\n" + secret, "")
+			timestamp, _ := body["timestamp"].(string)
+			h := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+			return body["msg_type"] == "text" && body["sign"] == base64.StdEncoding.EncodeToString(h.Sum(nil))
+		}, false},
+		{ChatFeishu, `{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`, nil, true},
+		{ChatDingTalk, `{"errcode":310000,"errmsg":"sign not match"}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chatType, func(t *testing.T) {
+			ok := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				data, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(data, &body)
+				ok = tt.check == nil || tt.check(r, body)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			n := &ChatNotifier{Type: tt.chatType, URL: server.URL + "/robot/send?access_token=x", Secret: secret}
+			if err := n.Notify(context.Background(), testAlert()); (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v", err)
+			}
+			if !ok {
+				t.Error("unexpected request")
+			}
+		})
+	}
+
+	if err := (&ChatNotifier{Type: "slack"}).Notify(context.Background(), testAlert()); !errors.Is(err, ErrChat) {
+		t.Errorf("error = %v, want ErrChat", err)
+	}
+}
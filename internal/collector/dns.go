@@ -0,0 +1,225 @@
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tracer/internal/token"
+)
+
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+	dnsClassIN  = 1
+
+	dnsFlagQR = 0x8000
+	dnsFlagAA = 0x0400
+	dnsFlagRD = 0x0100
+
+	dnsRcodeFormErr = 1
+	dnsRcodeRefused = 5
+)
+
+var errDNS = errors.New("invalid dns message")
+
+// DNSServer 追踪域名的权威 DNS 服务
+// 从查询的子域名中解析 token，记录查询名称、递归服务器 IP
+type DNSServer struct {
+	Addr     string       // 监听地址，默认 :53
+	Domain   string       // 追踪域名，例如 canary.example.com
+	Answer   net.IP       // A/AAAA 查询返回的地址，为空时只返回空应答
+	TTL      uint32       // 应答 TTL，默认 0，避免递归服务器缓存
+	Signer   token.Signer // 签名密钥，为空时不校验 token 签名
+	Strict   bool         // 不记录未签名或签名错误的查询，依然正常应答
+	Recorder Recorder
+
+	mu   sync.Mutex
+	conn net.PacketConn
+}
+
+// dnsQuestion DNS 查询问题
+type dnsQuestion struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	raw   []byte // 问题部分的原始数据
+}
+
+// ListenAndServe 监听 UDP 并处理查询
+func (s *DNSServer) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":53"
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(conn)
+}
+
+// Serve 处理查询，直到 conn 关闭
+func (s *DNSServer) Serve(conn net.PacketConn) error {
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		resp := s.handle(buf[:n], addr)
+		if resp != nil {
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// Close 关闭服务
+func (s *DNSServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *DNSServer) handle(msg []byte, addr net.Addr) []byte {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg[2:])&dnsFlagQR != 0 {
+		return nil
+	}
+
+	question, err := parseDNSQuestion(msg)
+	if err != nil {
+		return dnsResponse(msg, nil, dnsRcodeFormErr, nil)
+	}
+
+	// 只应答追踪域名
+	name := strings.ToLower(strings.TrimSuffix(question.Name, "."))
+	domain := strings.ToLower(strings.Trim(s.Domain, "."))
+	if name != domain && !strings.HasSuffix(name, "."+domain) {
+		return dnsResponse(msg, question, dnsRcodeRefused, nil)
+	}
+
+	if value, ok := token.FromSubdomain(name, domain); ok && s.Recorder != nil {
+		if tok, signature, ok := verifyToken(s.Signer, s.Strict, value); ok {
+			host, _, _ := net.SplitHostPort(addr.String())
+			_ = s.Recorder.Record(&Hit{
+				Time:       time.Now(),
+				Protocol:   "dns",
+				RemoteAddr: host,
+				Token:      tok,
+				Query:      question.Name + " " + dnsTypeName(question.Type),
+				Signature:  signature,
+			})
+		}
+	}
+
+	var answer []byte
+	if ip4 := s.Answer.To4(); ip4 != nil && question.Type == dnsTypeA {
+		answer = ip4
+	} else if ip4 == nil && s.Answer != nil && question.Type == dnsTypeAAAA {
+		answer = s.Answer.To16()
+	}
+	if answer == nil {
+		return dnsResponse(msg, question, 0, nil)
+	}
+
+	rr := []byte{0xc0, 0x0c} // 指向问题中的名称
+	rr = binary.BigEndian.AppendUint16(rr, question.Type)
+	rr = binary.BigEndian.AppendUint16(rr, dnsClassIN)
+	rr = binary.BigEndian.AppendUint32(rr, s.TTL)
+	rr = binary.BigEndian.AppendUint16(rr, uint16(len(answer)))
+	rr = append(rr, answer...)
+	return dnsResponse(msg, question, 0, rr)
+}
+
+// parseDNSQuestion 解析第一个查询问题
+func parseDNSQuestion(msg []byte) (*dnsQuestion, error) {
+	if binary.BigEndian.Uint16(msg[4:]) == 0 {
+		return nil, errDNS
+	}
+
+	var labels []string
+	i := 12
+	for {
+		if i >= len(msg) {
+			return nil, errDNS
+		}
+		n := int(msg[i])
+		if n == 0 {
+			i++
+			break
+		}
+		// 查询中不应出现压缩指针
+		if n&0xc0 != 0 || i+1+n > len(msg) {
+			return nil, errDNS
+		}
+		labels = append(labels, string(msg[i+1:i+1+n]))
+		i += 1 + n
+	}
+
+	if i+4 > len(msg) {
+		return nil, errDNS
+	}
+	return &dnsQuestion{
+		Name:  strings.Join(labels, ".") + ".",
+		Type:  binary.BigEndian.Uint16(msg[i:]),
+		Class: binary.BigEndian.Uint16(msg[i+2:]),
+		raw:   msg[12 : i+4],
+	}, nil
+}
+
+// dnsResponse 生成权威应答
+func dnsResponse(req []byte, question *dnsQuestion, rcode uint16, answer []byte) []byte {
+	flags := binary.BigEndian.Uint16(req[2:])
+	flags = dnsFlagQR | dnsFlagAA | flags&0x7800 | flags&dnsFlagRD | rcode
+
+	resp := make([]byte, 12)
+	copy(resp, req[:2])
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	if question != nil {
+		binary.BigEndian.PutUint16(resp[4:], 1)
+		resp = append(resp, question.raw...)
+	}
+	if answer != nil {
+		binary.BigEndian.PutUint16(resp[6:], 1)
+		resp = append(resp, answer...)
+	}
+	return resp
+}
+
+func dnsTypeName(t uint16) string {
+	switch t {
+	case dnsTypeA:
+		return "A"
+	case dnsTypeAAAA:
+		return "AAAA"
+	case 2:
+		return "NS"
+	case 5:
+		return "CNAME"
+	case 6:
+		return "SOA"
+	case 15:
+		return "MX"
+	case 16:
+		return "TXT"
+	default:
+		return "TYPE" + strconv.Itoa(int(t))
+	}
+}
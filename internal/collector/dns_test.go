@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"tracer/internal/token"
+)
+
+// testResolver 将所有查询发送到本地 DNS 服务的递归服务器
+func testResolver(addr string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", addr)
+		},
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	hits := make(chan *Hit, 4)
+	server := &DNSServer{
+		Domain: "canary.test",
+		Answer: net.ParseIP("127.0.0.1"),
+		Recorder: RecorderFunc(func(hit *Hit) error {
+			hits <- hit
+			return nil
+		}),
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(conn)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resolver := testResolver(conn.LocalAddr().String())
+	ips, err := resolver.LookupIP(ctx, "ip4", "abcdef.canary.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("LookupIP() = %v", ips)
+	}
+
+	hit := <-hits
+	if hit.Protocol != "dns" || hit.Token != "abcdef" || hit.RemoteAddr != "127.0.0.1" {
+		t.Errorf("hit = %+v", hit)
+	}
+	if hit.Query != "abcdef.canary.test. A" {
+		t.Errorf("Query = %s", hit.Query)
+	}
+
+	// 非追踪域名拒绝应答
+	if _, err = resolver.LookupIP(ctx, "ip4", "example.com"); err == nil {
+		t.Error("want error for other domain")
+	}
+	select {
+	case hit = <-hits:
+		t.Errorf("unexpected hit %+v", hit)
+	default:
+	}
+}
+
+func TestParseDNSQuestion(t *testing.T) {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	msg = append(msg, 3, 'a', 'b', 'c', 4, 't', 'e', 's', 't', 0, 0, 1, 0, 1)
+
+	question, err := parseDNSQuestion(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if question.Name != "abc.test." || question.Type != dnsTypeA || question.Class != dnsClassIN {
+		t.Errorf("question = %+v", question)
+	}
+
+	if _, err = parseDNSQuestion(msg[:16]); err == nil {
+		t.Error("want error for truncated message")
+	}
+}
+
+func TestDNSServerSigned(t *testing.T) {
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, recorder := testHits()
+	server := &DNSServer{Domain: "canary.test", Signer: keyring, Strict: true, Recorder: recorder}
+	addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53}
+
+	// 只记录签名正确的查询，其余查询依然正常应答
+	tok := token.New()
+	for _, label := range []string{tok, "k1-" + tok + "-aaaaaaaaaaaaaaaa", keyring.Sign(tok)} {
+		msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, byte(len(label))}
+		msg = append(msg, label...)
+		msg = append(msg, 6, 'c', 'a', 'n', 'a', 'r', 'y', 4, 't', 'e', 's', 't', 0, 0, 1, 0, 1)
+		if resp := server.handle(msg, addr); resp == nil || resp[3]&0x0f != 0 {
+			t.Errorf("%s response = %v", label, resp)
+		}
+	}
+	if len(hits) != 1 {
+		t.Fatalf("hits = %d, want 1", len(hits))
+	}
+	if hit := <-hits; hit.Token != tok || hit.Signature != SignatureValid {
+		t.Errorf("hit = %+v", hit)
+	}
+}
@@ -0,0 +1,257 @@
+package collector
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 识别出的应用名称
+const (
+	AppOffice     = "Microsoft Office" // 只能确定是 Office，无法确定具体的应用
+	AppWord       = "Microsoft Word"
+	AppExcel      = "Microsoft Excel"
+	AppPowerPoint = "Microsoft PowerPoint"
+	AppOutlook    = "Microsoft Outlook"
+	AppWebDAV     = "Windows WebDAV"
+	AppLibre      = "LibreOffice"
+	AppWPS        = "WPS Office"
+)
+
+// Fingerprint 客户端识别结果
+type Fingerprint struct {
+	App     string `json:"app,omitempty"`     // 应用，例如 Microsoft Word、LibreOffice、Chrome
+	Version string `json:"version,omitempty"` // 应用版本
+	OS      string `json:"os,omitempty"`      // 操作系统，例如 Windows 10/11、macOS 14.1
+}
+
+// fingerprintRule 根据 User-Agent 识别应用，match 为 User-Agent 中的子匹配
+type fingerprintRule struct {
+	pattern *regexp.Regexp
+	parse   func(match []string, header http.Header) Fingerprint
+}
+
+var (
+	// officeYears Office 请求中的年份 => 版本，Office 2016 之后的版本都使用 2014
+	officeYears = map[string]string{"2010": "14.0", "2013": "15.0", "2014": "16.0"}
+
+	officeApps = map[string]string{
+		"word": AppWord, "winword": AppWord,
+		"excel":      AppExcel,
+		"powerpoint": AppPowerPoint, "powerpnt": AppPowerPoint,
+		"outlook": AppOutlook,
+	}
+
+	fingerprintRules = []fingerprintRule{
+		// Microsoft Office/16.0 (Windows NT 10.0; Microsoft Word 16.0.17029; Pro)
+		// Microsoft Office/16.0 (Macintosh; Mac OS X 10.15; Microsoft Word 16.78.3)
+		{regexp.MustCompile(`(?i)Microsoft Office/[\d.]+ \([^)]*?;\s*Microsoft (\w+) ([\d.]+)`), func(m []string, _ http.Header) Fingerprint {
+			return Fingerprint{App: officeApp(m[1]), Version: m[2]}
+		}},
+		// Microsoft Office Word 2014，Microsoft Office PowerPoint 2014 (16.0.17029) Windows NT 10.0
+		{regexp.MustCompile(`(?i)Microsoft Office (Word|Excel|PowerPoint|Outlook) (\d{4})(?: \(([\d.]+)\))?`), func(m []string, header http.Header) Fingerprint {
+			version := m[3]
+			if version == "" {
+				version = officeVersion(header, officeYears[m[2]])
+			}
+			return Fingerprint{App: officeApp(m[1]), Version: version}
+		}},
+		// OPTIONS 请求使用 Protocol Discovery，HEAD 请求使用 Existence Discovery
+		{regexp.MustCompile(`(?i)Microsoft Office (?:Protocol|Existence) Discovery`), func(_ []string, header http.Header) Fingerprint {
+			return Fingerprint{App: AppOffice, Version: officeVersion(header, "")}
+		}},
+		// 图片等资源使用 WinINet：Mozilla/4.0 (compatible; ms-office; MSOffice 16)
+		{regexp.MustCompile(`(?i)ms-office; MSOffice (\d+)`), func(m []string, header http.Header) Fingerprint {
+			return Fingerprint{App: AppOffice, Version: officeVersion(header, m[1]+".0")}
+		}},
+		// 资源管理器或 Office 通过 WebDAV 重定向器访问 UNC 路径：Microsoft-WebDAV-MiniRedir/10.0.19045
+		{regexp.MustCompile(`(?i)Microsoft-WebDAV-MiniRedir/([\d.]+)`), func(m []string, _ http.Header) Fingerprint {
+			return Fingerprint{App: AppWebDAV, Version: m[1], OS: windowsBuild(m[1])}
+		}},
+		{regexp.MustCompile(`(?i)LibreOffice(?:[ /]([\d.]+))?`), func(m []string, _ http.Header) Fingerprint {
+			return Fingerprint{App: AppLibre, Version: m[1]}
+		}},
+		{regexp.MustCompile(`(?i)(?:WPS Office|Kingsoft Office|\bwps)[ /]?([\d.]+)?`), func(m []string, _ http.Header) Fingerprint {
+			return Fingerprint{App: AppWPS, Version: m[1]}
+		}},
+		{regexp.MustCompile(`GoogleImageProxy`), func(_ []string, _ http.Header) Fingerprint {
+			return Fingerprint{App: "Gmail Image Proxy"}
+		}},
+		{regexp.MustCompile(`Edg/([\d.]+)`), browser("Edge")},
+		{regexp.MustCompile(`Firefox/([\d.]+)`), browser("Firefox")},
+		{regexp.MustCompile(`Chrome/([\d.]+)`), browser("Chrome")},
+		{regexp.MustCompile(`Version/([\d.]+).*Safari/`), browser("Safari")},
+		{regexp.MustCompile(`^(curl|Wget|python-requests|Go-http-client|PowerShell)/([\d.]+)`), func(m []string, _ http.Header) Fingerprint {
+			return Fingerprint{App: m[1], Version: m[2]}
+		}},
+	}
+
+	windowsVersions = map[string]string{"10.0": "Windows 10/11", "6.3": "Windows 8.1", "6.2": "Windows 8", "6.1": "Windows 7", "6.0": "Windows Vista"}
+	windowsPattern  = regexp.MustCompile(`Windows NT (\d+\.\d+)`)
+	macPattern      = regexp.MustCompile(`Mac OS X (\d+[._]\d+(?:[._]\d+)?)`)
+	iosPattern      = regexp.MustCompile(`(?:iPhone|CPU) OS (\d+_\d+(?:_\d+)?)`)
+	androidPattern  = regexp.MustCompile(`Android ([\d.]+)`)
+)
+
+// ClassifyRequest 根据单个请求的 User-Agent 和请求头识别客户端
+func ClassifyRequest(r *http.Request) Fingerprint {
+	ua := r.UserAgent()
+
+	var fp Fingerprint
+	for _, rule := range fingerprintRules {
+		if match := rule.pattern.FindStringSubmatch(ua); match != nil {
+			fp = rule.parse(match, r.Header)
+			break
+		}
+	}
+	if fp.OS == "" {
+		fp.OS = userAgentOS(ua)
+	}
+	return fp
+}
+
+// Fingerprinter 根据同一来源最近的请求序列识别客户端
+// Word 打开远程模板时先发送 OPTIONS（Protocol Discovery）、HEAD（Existence Discovery），再使用 Word 的 User-Agent 发送 GET；
+// WebDAV 重定向器的请求只能确定操作系统，Office 的 WinINet 请求只能确定主版本。
+// 同一来源的请求识别结果互相补充，应用优先使用更具体的结果
+type Fingerprinter struct {
+	Window time.Duration // 关联同一来源请求的时间，默认 1 分钟
+
+	mu       sync.Mutex
+	sessions map[string]*fingerprintSession // 来源 IP => 最近的识别结果
+}
+
+type fingerprintSession struct {
+	fp   Fingerprint
+	last time.Time
+}
+
+// Observe 记录请求并返回合并了同一来源之前请求的识别结果，没有 token 的请求也需要记录
+func (f *Fingerprinter) Observe(r *http.Request, now time.Time) Fingerprint {
+	var (
+		fp     = ClassifyRequest(r)
+		host   = remoteHost(r.RemoteAddr)
+		window = f.Window
+	)
+	if window == 0 {
+		window = time.Minute
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.sessions == nil {
+		f.sessions = make(map[string]*fingerprintSession)
+	}
+	for key, s := range f.sessions {
+		if now.Sub(s.last) > window {
+			delete(f.sessions, key)
+		}
+	}
+
+	s, ok := f.sessions[host]
+	if !ok {
+		s = &fingerprintSession{}
+		f.sessions[host] = s
+	}
+	s.fp = mergeFingerprint(fp, s.fp)
+	s.last = now
+	return s.fp
+}
+
+// mergeFingerprint 合并识别结果，应用使用更具体的结果，具体程度相同时使用 a
+// 版本跟随应用，为空时使用同一应用或 Office 的版本；操作系统为空或只能确定为 Windows 10/11 时使用另一个结果
+func mergeFingerprint(a, b Fingerprint) Fingerprint {
+	if appRank(b.App) > appRank(a.App) {
+		a, b = b, a
+	}
+	if a.Version == "" && (a.App == b.App || b.App == AppOffice) {
+		a.Version = b.Version
+	}
+	if a.OS == "" || (a.OS == windowsVersions["10.0"] && strings.HasPrefix(b.OS, "Windows 1")) {
+		a.OS = b.OS
+	}
+	return a
+}
+
+// appRank 应用的具体程度：具体的应用 > Office > WebDAV 重定向器 > 未识别
+func appRank(app string) int {
+	switch app {
+	case "":
+		return 0
+	case AppWebDAV:
+		return 1
+	case AppOffice:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// officeApp Office 应用名称
+func officeApp(name string) string {
+	if app, ok := officeApps[strings.ToLower(name)]; ok {
+		return app
+	}
+	return "Microsoft " + name
+}
+
+// officeVersion Office 主版本，优先使用 X-Office-Major-Version 请求头
+func officeVersion(header http.Header, version string) string {
+	if major := header.Get("X-Office-Major-Version"); major != "" {
+		return major + ".0"
+	}
+	return version
+}
+
+// browser 浏览器识别规则
+func browser(name string) func(match []string, header http.Header) Fingerprint {
+	return func(match []string, _ http.Header) Fingerprint {
+		return Fingerprint{App: name, Version: match[1]}
+	}
+}
+
+// userAgentOS 根据 User-Agent 识别操作系统
+func userAgentOS(ua string) string {
+	if m := windowsPattern.FindStringSubmatch(ua); m != nil {
+		if name, ok := windowsVersions[m[1]]; ok {
+			return name
+		}
+		return "Windows NT " + m[1]
+	}
+	if m := iosPattern.FindStringSubmatch(ua); m != nil {
+		return "iOS " + strings.ReplaceAll(m[1], "_", ".")
+	}
+	if m := macPattern.FindStringSubmatch(ua); m != nil {
+		return "macOS " + strings.ReplaceAll(m[1], "_", ".")
+	}
+	if m := androidPattern.FindStringSubmatch(ua); m != nil {
+		return "Android " + m[1]
+	}
+	if strings.Contains(ua, "Linux") || strings.Contains(ua, "X11") {
+		return "Linux"
+	}
+	return ""
+}
+
+// windowsBuild 根据内部版本号区分 Windows 10 和 11：10.0.22000 之后为 Windows 11
+func windowsBuild(version string) string {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return "Windows"
+	}
+	if len(parts) < 3 || parts[0]+"."+parts[1] != "10.0" {
+		return userAgentOS("Windows NT " + parts[0] + "." + parts[1])
+	}
+	build, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return "Windows 10/11"
+	}
+	if build >= 22000 {
+		return "Windows 11"
+	}
+	return "Windows 10"
+}
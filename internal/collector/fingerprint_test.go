@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyRequest(t *testing.T) {
+	tests := []struct {
+		ua     string
+		header map[string]string
+		want   Fingerprint
+	}{
+		{"Microsoft Office/16.0 (Windows NT 10.0; Microsoft Word 16.0.17029; Pro)", nil, Fingerprint{AppWord, "16.0.17029", "Windows 10/11"}},
+		{"Microsoft Office/16.0 (Macintosh; Mac OS X 10_15_7; Microsoft PowerPoint 16.78.3)", nil, Fingerprint{AppPowerPoint, "16.78.3", "macOS 10.15.7"}},
+		{"Microsoft Office Word 2014", nil, Fingerprint{AppWord, "16.0", ""}},
+		{"Microsoft Office Excel 2013", nil, Fingerprint{AppExcel, "15.0", ""}},
+		{"Microsoft Office Protocol Discovery", map[string]string{"X-Office-Major-Version": "16"}, Fingerprint{AppOffice, "16.0", ""}},
+		{"Microsoft Office Existence Discovery", nil, Fingerprint{AppOffice, "", ""}},
+		{"Mozilla/4.0 (compatible; MSIE 7.0; Windows NT 6.1; Trident/7.0; ms-office; MSOffice 15)", nil, Fingerprint{AppOffice, "15.0", "Windows 7"}},
+		{"Microsoft-WebDAV-MiniRedir/10.0.22631", nil, Fingerprint{AppWebDAV, "10.0.22631", "Windows 11"}},
+		{"Microsoft-WebDAV-MiniRedir/10.0.19045", nil, Fingerprint{AppWebDAV, "10.0.19045", "Windows 10"}},
+		{"LibreOffice 7.6.4.1", nil, Fingerprint{AppLibre, "7.6.4.1", ""}},
+		{"Mozilla/5.0 (X11; Linux x86_64) LibreOffice", nil, Fingerprint{AppLibre, "", "Linux"}},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91", nil, Fingerprint{"Edge", "120.0.2210.91", "Windows 10/11"}},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", nil, Fingerprint{"Safari", "17.1", "iOS 17.1"}},
+		{"Mozilla/5.0 (Windows NT 5.1; Win64; x64) GoogleImageProxy", nil, Fingerprint{"Gmail Image Proxy", "", "Windows NT 5.1"}},
+		{"curl/8.4.0", nil, Fingerprint{"curl", "8.4.0", ""}},
+		{"", nil, Fingerprint{}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/t/", nil)
+		r.Header.Set("User-Agent", tt.ua)
+		for key, value := range tt.header {
+			r.Header.Set(key, value)
+		}
+		if got := ClassifyRequest(r); got != tt.want {
+			t.Errorf("ClassifyRequest(%q) = %+v, want %+v", tt.ua, got, tt.want)
+		}
+	}
+}
+
+// testRequest 模拟客户端的一个请求
+type testRequest struct {
+	method string
+	path   string
+	ua     string
+}
+
+func TestTraceHandlerFingerprint(t *testing.T) {
+	const tok = "abc234abc234abcd"
+	tests := []struct {
+		name     string
+		requests []testRequest
+		want     Fingerprint
+	}{
+		{"word template", []testRequest{
+			{http.MethodOptions, "/t/", "Microsoft Office Protocol Discovery"},
+			{http.MethodHead, "/t/" + tok, "Microsoft Office Existence Discovery"},
+			{http.MethodGet, "/t/" + tok, "Microsoft Office Word 2014"},
+		}, Fingerprint{AppWord, "16.0", ""}},
+		{"word unc", []testRequest{
+			{http.MethodOptions, "/", "Microsoft-WebDAV-MiniRedir/10.0.19045"},
+			{"PROPFIND", "/t", "Microsoft-WebDAV-MiniRedir/10.0.19045"},
+			{http.MethodGet, "/t/" + tok, "Microsoft Office Word 2014"},
+		}, Fingerprint{AppWord, "16.0", "Windows 10"}},
+		{"powerpoint image", []testRequest{
+			{http.MethodGet, "/t/" + tok, "Mozilla/4.0 (compatible; MSIE 7.0; Windows NT 10.0; Win64; x64; Trident/7.0; .NET4.0C; .NET4.0E; ms-office; MSOffice 16)"},
+		}, Fingerprint{AppOffice, "16.0", "Windows 10/11"}},
+		{"libreoffice", []testRequest{
+			{"PROPFIND", "/t/" + tok, "LibreOffice 7.6.4.1"},
+			{http.MethodGet, "/t/" + tok, "LibreOffice 7.6.4.1"},
+		}, Fingerprint{AppLibre, "7.6.4.1", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, recorder := testHits()
+			h := &TraceHandler{Fingerprinter: &Fingerprinter{}, Recorder: recorder}
+			for _, req := range tt.requests {
+				r := httptest.NewRequest(req.method, req.path, nil)
+				r.RemoteAddr = "10.0.0.8:50123"
+				r.Header.Set("User-Agent", req.ua)
+				h.ServeHTTP(httptest.NewRecorder(), r)
+			}
+
+			// 最后一个请求的记录包含整个请求序列的识别结果
+			var hit *Hit
+			for len(hits) > 0 {
+				hit = <-hits
+			}
+			if hit == nil {
+				t.Fatal("no hit")
+			}
+			if got := (Fingerprint{hit.App, hit.AppVersion, hit.OS}); got != tt.want {
+				t.Errorf("fingerprint = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprinterWindow(t *testing.T) {
+	f := &Fingerprinter{Window: time.Minute}
+	now := time.Now()
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("User-Agent", "Microsoft-WebDAV-MiniRedir/10.0.22631")
+	f.Observe(r, now)
+
+	// 窗口之后不再关联，其他来源不关联
+	r = httptest.NewRequest(http.MethodGet, "/t/abc234abc234abcd", nil)
+	r.Header.Set("User-Agent", "Microsoft Office Word 2014")
+	if got := f.Observe(r, now.Add(2*time.Minute)); got.OS != "" {
+		t.Errorf("Observe() = %+v, want no os", got)
+	}
+	r.RemoteAddr = "10.0.0.9:1234"
+	if got := f.Observe(r, now.Add(2*time.Minute)); got.App != AppWord || got.OS != "" {
+		t.Errorf("Observe(other) = %+v", got)
+	}
+}
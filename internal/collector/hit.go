@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Hit 追踪记录
+type Hit struct {
+	Time       time.Time `json:"time"`
+	Protocol   string    `json:"protocol"` // http、smb、dns、kubernetes、s3、mysql、postgres 等
+	RemoteAddr string    `json:"remoteAddr"`
+	Token      string    `json:"token,omitempty"`
+	Query      string    `json:"query,omitempty"`     // DNS 查询名称及类型，HTTP 请求方法及地址，SMB 的 UNC 路径
+	Signature  string    `json:"signature,omitempty"` // token 签名校验结果：valid、unsigned、invalid，未设置签名密钥时为空
+
+	// NTLM 认证信息
+	User        string `json:"user,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	Workstation string `json:"workstation,omitempty"`
+
+	// 凭据诱饵信息
+	Credential string `json:"credential,omitempty"` // bearer token、access key id 等
+	Database   string `json:"database,omitempty"`
+	Client     string `json:"client,omitempty"` // 客户端名称及版本
+
+	// 客户端识别结果，根据 User-Agent、请求头和同一来源的请求序列识别
+	App        string `json:"app,omitempty"`
+	AppVersion string `json:"appVersion,omitempty"`
+	OS         string `json:"os,omitempty"`
+}
+
+// Recorder 记录追踪信息
+type Recorder interface {
+	Record(hit *Hit) error
+}
+
+// RecorderFunc 函数形式的 Recorder
+type RecorderFunc func(hit *Hit) error
+
+func (f RecorderFunc) Record(hit *Hit) error {
+	return f(hit)
+}
+
+// LogRecorder 以 JSON Lines 格式输出追踪记录
+type LogRecorder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewLogRecorder 创建 LogRecorder
+func NewLogRecorder(w io.Writer) *LogRecorder {
+	return &LogRecorder{w: w}
+}
+
+func (r *LogRecorder) Record(hit *Hit) error {
+	b, err := json.Marshal(hit)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"tracer/internal/token"
+)
+
+// token 签名校验结果
+const (
+	SignatureValid    = "valid"
+	SignatureUnsigned = "unsigned"
+	SignatureInvalid  = "invalid"
+)
+
+// transparentGIF 1x1 透明 gif
+var transparentGIF = []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+
+// TraceHandler 追踪地址的 HTTP 服务
+// 从路径中解析 token 并记录请求，GET、HEAD 返回 1x1 透明 gif，OPTIONS、PROPFIND 返回最小的 WebDAV 应答
+// 设置 Signer 时校验 token 签名，避免从诱饵文件中提取追踪地址后伪造其他 token 的请求
+// 设置 Fingerprinter 时识别打开文件的应用、版本和操作系统
+type TraceHandler struct {
+	Signer        token.Signer   // 签名密钥，为空时不校验
+	Strict        bool           // 未签名或签名错误的请求返回 404 且不记录，否则记录校验结果
+	Fingerprinter *Fingerprinter // 客户端识别，为空时不识别
+	Recorder      Recorder
+}
+
+func (h *TraceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 1、Office 会先请求上级目录，没有 token 的请求也用于识别客户端
+	now := time.Now()
+	var fp Fingerprint
+	if h.Fingerprinter != nil {
+		fp = h.Fingerprinter.Observe(r, now)
+	}
+
+	// 2、解析、校验 token；WebDAV 探测请求没有 token 时依然应答并记录
+	var (
+		value, found   = token.FromPath(r.URL.Path)
+		tok, signature string
+		dav            = r.Method == http.MethodOptions || r.Method == methodPropfind
+	)
+	if found {
+		var ok bool
+		tok, signature, ok = verifyToken(h.Signer, h.Strict, value)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+	} else if !dav {
+		http.NotFound(w, r)
+		return
+	}
+
+	if h.Recorder != nil {
+		_ = h.Recorder.Record(&Hit{
+			Time:       now,
+			Protocol:   "http",
+			RemoteAddr: remoteHost(r.RemoteAddr),
+			Token:      tok,
+			Query:      r.Method + " " + r.URL.RequestURI(),
+			Signature:  signature,
+			Client:     r.UserAgent(),
+			App:        fp.App,
+			AppVersion: fp.Version,
+			OS:         fp.OS,
+		})
+	}
+
+	// 3、应答
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "image/gif")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(transparentGIF)
+	case http.MethodOptions:
+		writeDAVOptions(w)
+	case methodPropfind:
+		writeDAVPropfind(w, r, found, now)
+	default:
+		w.Header().Set("Allow", davAllow)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifyToken 校验追踪地址或凭据中的 token 签名，返回 token 及校验结果
+// signer 为空时不校验，strict 为 true 时未通过校验返回 false
+func verifyToken(signer token.Signer, strict bool, value string) (tok, signature string, ok bool) {
+	if signer == nil {
+		return strings.ToLower(token.Strip(value)), "", true
+	}
+
+	tok, err := signer.Verify(value)
+	switch {
+	case err == nil:
+		return tok, SignatureValid, true
+	case errors.Is(err, token.ErrUnsigned):
+		return tok, SignatureUnsigned, !strict
+	default:
+		return tok, SignatureInvalid, !strict
+	}
+}
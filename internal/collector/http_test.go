@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tracer/internal/token"
+)
+
+// testGet 请求地址，返回状态码和响应内容
+func testGet(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, body
+}
+
+func TestTraceHandler(t *testing.T) {
+	hits, recorder := testHits()
+	server := httptest.NewServer(&TraceHandler{Recorder: recorder})
+	defer server.Close()
+
+	code, body := testGet(t, server.URL+"/t/ABC234ABC234ABCD?v=img")
+	if code != http.StatusOK || !bytes.Equal(body, transparentGIF) {
+		t.Errorf("response = %d %q", code, body)
+	}
+	hit := <-hits
+	if hit.Protocol != "http" || hit.Token != "abc234abc234abcd" || hit.Query != "GET /t/ABC234ABC234ABCD?v=img" || hit.Signature != "" {
+		t.Errorf("hit = %+v", hit)
+	}
+
+	// 没有 token 时不记录
+	if code, _ = testGet(t, server.URL+"/favicon.ico"); code != http.StatusNotFound {
+		t.Errorf("favicon = %d", code)
+	}
+	select {
+	case hit = <-hits:
+		t.Errorf("unexpected hit %+v", hit)
+	default:
+	}
+}
+
+func TestTraceHandlerSigned(t *testing.T) {
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := token.New()
+	signed := keyring.Sign(tok)
+	forged := strings.Replace(signed, tok, token.New(), 1)
+
+	tests := []struct {
+		name      string
+		strict    bool
+		path      string
+		wantCode  int
+		wantSign  string
+		wantToken string
+	}{
+		{"valid", false, "/t/" + signed, http.StatusOK, SignatureValid, tok},
+		{"valid strict", true, "/t/" + signed + "/infra.git/info/refs", http.StatusOK, SignatureValid, tok},
+		{"unsigned", false, "/t/" + tok, http.StatusOK, SignatureUnsigned, tok},
+		{"forged", false, "/t/" + forged, http.StatusOK, SignatureInvalid, token.Strip(forged)},
+		{"unsigned strict", true, "/t/" + tok, http.StatusNotFound, "", ""},
+		{"forged strict", true, "/t/" + forged, http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, recorder := testHits()
+			server := httptest.NewServer(&TraceHandler{Signer: keyring, Strict: tt.strict, Recorder: recorder})
+			defer server.Close()
+
+			if code, _ := testGet(t, server.URL+tt.path); code != tt.wantCode {
+				t.Errorf("code = %d, want %d", code, tt.wantCode)
+			}
+			select {
+			case hit := <-hits:
+				if hit.Signature != tt.wantSign || hit.Token != tt.wantToken {
+					t.Errorf("hit = %+v", hit)
+				}
+			default:
+				if tt.wantSign != "" {
+					t.Error("hit not recorded")
+				}
+			}
+		})
+	}
+}
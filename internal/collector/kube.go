@@ -0,0 +1,113 @@
+package collector
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"tracer/internal/credential"
+	"tracer/internal/token"
+)
+
+const kubeUnauthorized = `{"kind":"Status","apiVersion":"v1","metadata":{},"status":"Failure","message":"Unauthorized","reason":"Unauthorized","code":401}` + "\n"
+
+// KubeAPIHandler 模拟 Kubernetes API 服务
+// 记录请求中的 bearer token 后返回 401，配合 credential.GenKubeconfig 使用
+// kubeconfig 中的 server 为 https 地址，需要使用 http.Server.ListenAndServeTLS，证书可以使用 SelfSignedCertificate 生成
+type KubeAPIHandler struct {
+	Signer   token.Signer // 校验 bearer token 中的 token 签名，为空时不校验
+	Strict   bool         // 不记录未签名或签名错误的 token，依然返回 401
+	Recorder Recorder
+}
+
+func (h *KubeAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bearer, ok := bearerToken(r.Header.Get("Authorization"))
+	if ok && h.Recorder != nil {
+		var tok, signature string
+		if value, found := credential.FromBearerToken(bearer); found {
+			tok, signature, ok = verifyToken(h.Signer, h.Strict, value)
+		}
+		if ok {
+			_ = h.Recorder.Record(&Hit{
+				Time:       time.Now(),
+				Protocol:   "kubernetes",
+				RemoteAddr: remoteHost(r.RemoteAddr),
+				Token:      tok,
+				Query:      r.Method + " " + r.URL.RequestURI(),
+				Signature:  signature,
+				Credential: bearer,
+				Client:     r.UserAgent(),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, private")
+	w.WriteHeader(http.StatusUnauthorized)
+	_, _ = w.Write([]byte(kubeUnauthorized))
+}
+
+// SelfSignedCertificate 生成自签名证书，用于 Kubernetes API 服务的 https 监听
+// kubeconfig 中已跳过证书校验，不需要受信任的证书
+// hosts: 证书中的域名或 IP 地址，例如 kubernetes.default.svc
+func SelfSignedCertificate(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "kube-apiserver"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// bearerToken 从 Authorization 请求头中获取 bearer token
+func bearerToken(auth string) (string, bool) {
+	scheme, bearer, ok := strings.Cut(auth, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	bearer = strings.TrimSpace(bearer)
+	return bearer, bearer != ""
+}
+
+// remoteHost 去掉远程地址中的端口
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
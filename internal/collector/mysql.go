@@ -0,0 +1,331 @@
+package collector
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"tracer/internal/credential"
+	"tracer/internal/token"
+)
+
+// MySQL 客户端能力标志
+const (
+	mysqlLongPassword             = 0x00000001
+	mysqlConnectWithDB            = 0x00000008
+	mysqlProtocol41               = 0x00000200
+	mysqlSSL                      = 0x00000800
+	mysqlSecureConnection         = 0x00008000
+	mysqlPluginAuth               = 0x00080000
+	mysqlConnectAttrs             = 0x00100000
+	mysqlPluginAuthLenencData     = 0x00200000
+	mysqlServerCapabilities       = mysqlLongPassword | mysqlConnectWithDB | mysqlProtocol41 | mysqlSecureConnection | mysqlPluginAuth | mysqlConnectAttrs | mysqlPluginAuthLenencData
+	mysqlHandshakeResponseMinSize = 32
+
+	mysqlErrAccessDenied = 1045
+	mysqlCharsetUTF8     = 0x21
+)
+
+var errMySQL = errors.New("invalid mysql packet")
+
+// MySQLServer 模拟 MySQL 服务
+// 发送握手包，记录客户端握手响应中的用户名、数据库、客户端名称及版本后返回 Access denied
+// 不支持 SSL，客户端要求 SSL 时连接失败
+type MySQLServer struct {
+	Addr     string       // 监听地址，默认 :3306
+	Version  string       // 服务端版本，默认 5.7.44-log
+	Signer   token.Signer // 校验用户名中的 token 签名，为空时不校验
+	Strict   bool         // 不记录未签名或签名错误的 token，依然拒绝登录
+	Recorder Recorder
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// mysqlHandshake 客户端握手响应
+type mysqlHandshake struct {
+	User     string
+	Database string
+	Attrs    map[string]string
+}
+
+// ListenAndServe 监听并处理连接
+func (s *MySQLServer) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":3306"
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 处理连接，直到 listener 关闭
+func (s *MySQLServer) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close 关闭服务
+func (s *MySQLServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *MySQLServer) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	// 1、发送握手包
+	if err := writeMySQLPacket(conn, 0, s.handshake()); err != nil {
+		return
+	}
+
+	// 2、读取握手响应
+	seq, payload, err := readMySQLPacket(conn)
+	if err != nil {
+		return
+	}
+	resp, err := parseMySQLHandshake(payload)
+	if err != nil {
+		return
+	}
+
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	if s.Recorder != nil {
+		var (
+			tok, signature string
+			ok             = true
+		)
+		if value, found := credential.FromDBUser(resp.User); found {
+			tok, signature, ok = verifyToken(s.Signer, s.Strict, value)
+		}
+		if ok {
+			_ = s.Recorder.Record(&Hit{
+				Time:       time.Now(),
+				Protocol:   "mysql",
+				RemoteAddr: host,
+				Token:      tok,
+				Signature:  signature,
+				User:       resp.User,
+				Database:   resp.Database,
+				Client:     mysqlClient(resp.Attrs),
+			})
+		}
+	}
+
+	// 3、拒绝登录
+	msg := fmt.Sprintf("Access denied for user '%s'@'%s' (using password: YES)", resp.User, host)
+	_ = writeMySQLPacket(conn, seq+1, mysqlError(mysqlErrAccessDenied, "28000", msg))
+}
+
+// handshake 生成 Protocol::HandshakeV10
+func (s *MySQLServer) handshake() []byte {
+	version := s.Version
+	if version == "" {
+		version = "5.7.44-log"
+	}
+
+	scramble := make([]byte, 20)
+	_, _ = rand.Read(scramble)
+	for i := range scramble {
+		// scramble 不能包含 0
+		scramble[i] = scramble[i]&0x7f | 0x01
+	}
+
+	b := []byte{10}
+	b = append(b, version...)
+	b = append(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, 1) // connection id
+	b = append(b, scramble[:8]...)
+	b = append(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(mysqlServerCapabilities&0xffff))
+	b = append(b, mysqlCharsetUTF8)
+	b = binary.LittleEndian.AppendUint16(b, 0x0002) // SERVER_STATUS_AUTOCOMMIT
+	b = binary.LittleEndian.AppendUint16(b, uint16(mysqlServerCapabilities>>16))
+	b = append(b, byte(len(scramble)+1))
+	b = append(b, make([]byte, 10)...)
+	b = append(b, scramble[8:]...)
+	b = append(b, 0)
+	b = append(b, "mysql_native_password"...)
+	return append(b, 0)
+}
+
+// parseMySQLHandshake 解析 Protocol::HandshakeResponse41
+func parseMySQLHandshake(b []byte) (*mysqlHandshake, error) {
+	if len(b) < mysqlHandshakeResponseMinSize {
+		return nil, errMySQL
+	}
+	capabilities := binary.LittleEndian.Uint32(b)
+	if capabilities&mysqlProtocol41 == 0 || capabilities&mysqlSSL != 0 {
+		return nil, errMySQL
+	}
+
+	r := &mysqlReader{b: b[mysqlHandshakeResponseMinSize:]}
+	resp := &mysqlHandshake{User: r.cstring()}
+
+	// auth response
+	switch {
+	case capabilities&mysqlPluginAuthLenencData != 0:
+		r.lenencString()
+	case capabilities&mysqlSecureConnection != 0:
+		r.next(int(r.byte()))
+	default:
+		r.cstring()
+	}
+	if capabilities&mysqlConnectWithDB != 0 {
+		resp.Database = r.cstring()
+	}
+	if capabilities&mysqlPluginAuth != 0 {
+		r.cstring()
+	}
+	if capabilities&mysqlConnectAttrs != 0 {
+		attrs := &mysqlReader{b: []byte(r.lenencString())}
+		resp.Attrs = make(map[string]string)
+		for len(attrs.b) > 0 && attrs.err == nil {
+			key := attrs.lenencString()
+			resp.Attrs[key] = attrs.lenencString()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return resp, nil
+}
+
+// mysqlClient 根据连接属性生成客户端名称及版本，例如 mysql libmysql/8.0.33
+func mysqlClient(attrs map[string]string) string {
+	client := attrs["_client_name"]
+	if version := attrs["_client_version"]; version != "" {
+		client += "/" + version
+	}
+	if program := attrs["program_name"]; program != "" {
+		client = program + " " + client
+	}
+	return client
+}
+
+// mysqlError 生成 ERR_Packet
+func mysqlError(code uint16, state, msg string) []byte {
+	b := []byte{0xff}
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = append(b, '#')
+	b = append(b, state...)
+	return append(b, msg...)
+}
+
+// readMySQLPacket 读取数据包，返回序号和内容
+func readMySQLPacket(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if length > 1<<20 {
+		return 0, nil, errMySQL
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[3], payload, nil
+}
+
+// writeMySQLPacket 输出数据包
+func writeMySQLPacket(w io.Writer, seq byte, payload []byte) error {
+	length := len(payload)
+	header := []byte{byte(length), byte(length >> 8), byte(length >> 16), seq}
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// mysqlReader 按顺序读取字段，越界时记录错误
+type mysqlReader struct {
+	b   []byte
+	err error
+}
+
+func (r *mysqlReader) next(n int) []byte {
+	if r.err != nil || n > len(r.b) {
+		r.err = errMySQL
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *mysqlReader) byte() byte {
+	v := r.next(1)
+	if v == nil {
+		return 0
+	}
+	return v[0]
+}
+
+// cstring 读取以 0 结尾的字符串，缺少结尾时读取剩余内容
+func (r *mysqlReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.b, 0)
+	if i < 0 {
+		return string(r.next(len(r.b)))
+	}
+	v := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return v
+}
+
+// lenencString 读取长度编码的字符串
+func (r *mysqlReader) lenencString() string {
+	var size int
+	switch v := r.byte(); v {
+	case 0xfc:
+		size = 2
+	case 0xfd:
+		size = 3
+	case 0xfe:
+		size = 8
+	default:
+		return string(r.next(int(v)))
+	}
+
+	buf := make([]byte, 8)
+	copy(buf, r.next(size))
+	n := binary.LittleEndian.Uint64(buf)
+	if n > uint64(len(r.b)) {
+		r.err = errMySQL
+		return ""
+	}
+	return string(r.next(int(n)))
+}
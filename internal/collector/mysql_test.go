@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"tracer/internal/credential"
+	"tracer/internal/token"
+)
+
+// testMySQLHandshakeResponse 生成 HandshakeResponse41，连接属性与 libmysql 相同
+func testMySQLHandshakeResponse(user, database string, attrs [][2]string) []byte {
+	capabilities := uint32(mysqlProtocol41 | mysqlSecureConnection | mysqlPluginAuth | mysqlConnectWithDB | mysqlConnectAttrs | mysqlPluginAuthLenencData)
+	b := binary.LittleEndian.AppendUint32(nil, capabilities)
+	b = binary.LittleEndian.AppendUint32(b, 1<<24)
+	b = append(b, mysqlCharsetUTF8)
+	b = append(b, make([]byte, 23)...)
+	b = append(append(b, user...), 0)
+	b = append(b, 20)
+	b = append(b, make([]byte, 20)...)
+	b = append(append(b, database...), 0)
+	b = append(append(b, "mysql_native_password"...), 0)
+
+	var attr []byte
+	for _, kv := range attrs {
+		for _, v := range kv {
+			attr = append(append(attr, byte(len(v))), v...)
+		}
+	}
+	return append(append(b, byte(len(attr))), attr...)
+}
+
+func TestMySQLServer(t *testing.T) {
+	hits, recorder := testHits()
+	server := &MySQLServer{Recorder: recorder}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// 1、握手包
+	seq, handshake, err := readMySQLPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 0 || handshake[0] != 10 || !strings.HasPrefix(string(handshake[1:]), "5.7.44-log\x00") {
+		t.Fatalf("handshake = %q", handshake)
+	}
+
+	// 2、握手响应
+	attrs := [][2]string{{"_client_name", "libmysql"}, {"_client_version", "8.0.33"}, {"program_name", "mysql"}}
+	err = writeMySQLPacket(conn, 1, testMySQLHandshakeResponse("app_abc234abc234abcd", "prod", attrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, resp, err := readMySQLPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 2 || resp[0] != 0xff || binary.LittleEndian.Uint16(resp[1:]) != mysqlErrAccessDenied {
+		t.Errorf("response = %d %q", seq, resp)
+	}
+	if !strings.Contains(string(resp), "Access denied for user 'app_abc234abc234abcd'@'127.0.0.1'") {
+		t.Errorf("response = %q", resp)
+	}
+
+	hit := <-hits
+	if hit.Protocol != "mysql" || hit.Token != "abc234abc234abcd" || hit.User != "app_abc234abc234abcd" || hit.Database != "prod" {
+		t.Errorf("hit = %+v", hit)
+	}
+	if hit.Client != "mysql libmysql/8.0.33" {
+		t.Errorf("Client = %s", hit.Client)
+	}
+}
+
+func TestMySQLServerSigned(t *testing.T) {
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, recorder := testHits()
+	server := &MySQLServer{Signer: keyring, Strict: true, Recorder: recorder}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	// 只记录签名正确的用户名，其余连接依然拒绝登录
+	tok := token.New()
+	for _, value := range []string{tok, "k1-" + tok + "-aaaaaaaaaaaaaaaa", keyring.Sign(tok)} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err = readMySQLPacket(conn); err != nil {
+			t.Fatal(err)
+		}
+		if err = writeMySQLPacket(conn, 1, testMySQLHandshakeResponse(credential.DBUser(value), "prod", nil)); err != nil {
+			t.Fatal(err)
+		}
+		if _, resp, err := readMySQLPacket(conn); err != nil || resp[0] != 0xff {
+			t.Errorf("%s response = %q, %v", value, resp, err)
+		}
+		_ = conn.Close()
+	}
+
+	if hit := <-hits; hit.Token != tok || hit.Signature != SignatureValid || len(hits) != 0 {
+		t.Errorf("hit = %+v, hits = %d", hit, len(hits))
+	}
+}
+
+func TestParseMySQLHandshake(t *testing.T) {
+	b := testMySQLHandshakeResponse("root", "", nil)
+	if _, err := parseMySQLHandshake(b[:len(b)-30]); err == nil {
+		t.Error("want error")
+	}
+
+	// SSLRequest
+	b = binary.LittleEndian.AppendUint32(nil, mysqlProtocol41|mysqlSSL)
+	if _, err := parseMySQLHandshake(append(b, make([]byte, 28)...)); err == nil {
+		t.Error("want error")
+	}
+}
@@ -0,0 +1,195 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"tracer/internal/credential"
+	"tracer/internal/token"
+)
+
+const (
+	pgProtocol3       = 196608   // 3.0
+	pgSSLRequest      = 80877103 // 1234.5679
+	pgGSSENCRequest   = 80877104 // 1234.5680
+	pgCancelRequest   = 80877102 // 1234.5678
+	pgStartupMaxSize  = 10000
+	pgInvalidPassword = "28P01"
+)
+
+var errPostgres = errors.New("invalid postgres message")
+
+// PostgresServer 模拟 PostgreSQL 服务
+// 拒绝 SSL/GSSAPI 加密请求，记录启动消息中的用户名、数据库、应用名称及协议版本后返回认证失败
+// 启动消息中没有客户端版本，Client 为 application_name 和协议版本，例如 psql protocol/3.0
+type PostgresServer struct {
+	Addr     string       // 监听地址，默认 :5432
+	Signer   token.Signer // 校验用户名中的 token 签名，为空时不校验
+	Strict   bool         // 不记录未签名或签名错误的 token，依然拒绝登录
+	Recorder Recorder
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// ListenAndServe 监听并处理连接
+func (s *PostgresServer) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":5432"
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 处理连接，直到 listener 关闭
+func (s *PostgresServer) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close 关闭服务
+func (s *PostgresServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *PostgresServer) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	// 1、读取启动消息，拒绝加密请求后客户端会以明文重新发送
+	var protocol uint32
+	var params map[string]string
+	for params == nil {
+		msg, err := readPostgresStartup(conn)
+		if err != nil {
+			return
+		}
+
+		protocol = binary.BigEndian.Uint32(msg)
+		switch protocol {
+		case pgSSLRequest, pgGSSENCRequest:
+			if _, err = conn.Write([]byte{'N'}); err != nil {
+				return
+			}
+		case pgCancelRequest:
+			return
+		default:
+			if protocol>>16 != pgProtocol3>>16 {
+				_ = writePostgresError(conn, "0A000", fmt.Sprintf("unsupported frontend protocol %d.%d", protocol>>16, protocol&0xffff))
+				return
+			}
+			params, err = parsePostgresParams(msg[4:])
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	user := params["user"]
+	if s.Recorder != nil {
+		host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		var (
+			tok, signature string
+			ok             = true
+		)
+		if value, found := credential.FromDBUser(user); found {
+			tok, signature, ok = verifyToken(s.Signer, s.Strict, value)
+		}
+		if ok {
+			_ = s.Recorder.Record(&Hit{
+				Time:       time.Now(),
+				Protocol:   "postgres",
+				RemoteAddr: host,
+				Token:      tok,
+				Signature:  signature,
+				User:       user,
+				Database:   params["database"],
+				Client:     fmt.Sprintf("%s protocol/%d.%d", params["application_name"], protocol>>16, protocol&0xffff),
+			})
+		}
+	}
+
+	// 2、拒绝登录
+	_ = writePostgresError(conn, pgInvalidPassword, fmt.Sprintf("password authentication failed for user \"%s\"", user))
+}
+
+// readPostgresStartup 读取启动消息（没有消息类型），返回协议版本及之后的内容
+func readPostgresStartup(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(binary.BigEndian.Uint32(header))
+	if length < 8 || length > pgStartupMaxSize {
+		return nil, errPostgres
+	}
+	msg := make([]byte, length-4)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// parsePostgresParams 解析启动参数：name\0value\0...\0
+func parsePostgresParams(b []byte) (map[string]string, error) {
+	params := make(map[string]string)
+	fields := bytes.Split(b, []byte{0})
+	for i := 0; i+1 < len(fields); i += 2 {
+		if len(fields[i]) == 0 {
+			break
+		}
+		params[string(fields[i])] = string(fields[i+1])
+	}
+	if params["user"] == "" {
+		return nil, errPostgres
+	}
+	return params, nil
+}
+
+// writePostgresError 输出 ErrorResponse
+func writePostgresError(w io.Writer, code, msg string) error {
+	var body []byte
+	for _, field := range [][2]string{{"S", "FATAL"}, {"V", "FATAL"}, {"C", code}, {"M", msg}} {
+		body = append(body, field[0]...)
+		body = append(body, field[1]...)
+		body = append(body, 0)
+	}
+	body = append(body, 0)
+
+	b := []byte{'E'}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)+4))
+	_, err := w.Write(append(b, body...))
+	return err
+}
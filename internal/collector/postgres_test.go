@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"tracer/internal/credential"
+	"tracer/internal/token"
+)
+
+// testPostgresStartup 生成启动消息
+func testPostgresStartup(protocol uint32, params ...string) []byte {
+	body := binary.BigEndian.AppendUint32(nil, protocol)
+	for _, v := range params {
+		body = append(append(body, v...), 0)
+	}
+	if len(params) > 0 {
+		body = append(body, 0)
+	}
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(body)+4)), body...)
+}
+
+func TestPostgresServer(t *testing.T) {
+	hits, recorder := testHits()
+	server := &PostgresServer{Recorder: recorder}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// 1、SSLRequest，服务端不支持 SSL
+	if _, err = conn.Write(testPostgresStartup(pgSSLRequest)); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1)
+	if _, err = io.ReadFull(conn, b); err != nil || b[0] != 'N' {
+		t.Fatalf("SSLRequest = %q, %v", b, err)
+	}
+
+	// 2、StartupMessage
+	_, err = conn.Write(testPostgresStartup(pgProtocol3, "user", "app_abc234abc234abcd", "database", "prod", "application_name", "psql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) < 5 || resp[0] != 'E' || int(binary.BigEndian.Uint32(resp[1:]))+1 != len(resp) {
+		t.Fatalf("response = %q", resp)
+	}
+	if !strings.Contains(string(resp), "C28P01\x00") || !strings.Contains(string(resp), `user "app_abc234abc234abcd"`) {
+		t.Errorf("response = %q", resp)
+	}
+
+	hit := <-hits
+	if hit.Protocol != "postgres" || hit.Token != "abc234abc234abcd" || hit.User != "app_abc234abc234abcd" || hit.Database != "prod" {
+		t.Errorf("hit = %+v", hit)
+	}
+	if hit.Client != "psql protocol/3.0" || hit.RemoteAddr != "127.0.0.1" {
+		t.Errorf("hit = %+v", hit)
+	}
+}
+
+func TestPostgresServerSigned(t *testing.T) {
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, recorder := testHits()
+	server := &PostgresServer{Signer: keyring, Strict: true, Recorder: recorder}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	// 只记录签名正确的用户名，其余连接依然拒绝登录
+	tok := token.New()
+	for _, value := range []string{tok, "k1-" + tok + "-aaaaaaaaaaaaaaaa", keyring.Sign(tok)} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = conn.Write(testPostgresStartup(pgProtocol3, "user", credential.DBUser(value), "database", "prod")); err != nil {
+			t.Fatal(err)
+		}
+		if resp, err := io.ReadAll(conn); err != nil || len(resp) == 0 || resp[0] != 'E' {
+			t.Errorf("%s response = %q, %v", value, resp, err)
+		}
+		_ = conn.Close()
+	}
+
+	if hit := <-hits; hit.Token != tok || hit.Signature != SignatureValid || len(hits) != 0 {
+		t.Errorf("hit = %+v, hits = %d", hit, len(hits))
+	}
+}
+
+func TestParsePostgresParams(t *testing.T) {
+	if _, err := parsePostgresParams([]byte("database\x00prod\x00\x00")); err == nil {
+		t.Error("want error")
+	}
+}
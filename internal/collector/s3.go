@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+
+	"tracer/internal/credential"
+	"tracer/internal/token"
+)
+
+// s3Error S3 错误响应
+type s3Error struct {
+	XMLName        xml.Name `xml:"Error"`
+	Code           string   `xml:"Code"`
+	Message        string   `xml:"Message"`
+	AWSAccessKeyId string   `xml:"AWSAccessKeyId,omitempty"`
+	RequestId      string   `xml:"RequestId"`
+}
+
+// S3Handler 模拟 S3 兼容服务
+// 记录请求签名中的 access key id 后返回 InvalidAccessKeyId，配合 credential.GenAWSCredentials 使用
+// access key id 中不能写入签名，签名后的 token 为临时凭据的 session token
+type S3Handler struct {
+	Signer   token.Signer // 校验 session token 中的 token 签名，为空时不校验
+	Strict   bool         // 不记录没有 session token 或签名错误的请求，依然返回 InvalidAccessKeyId
+	Recorder Recorder
+}
+
+func (h *S3Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := s3AccessKeyId(r)
+	if key != "" && h.Recorder != nil {
+		var (
+			tok, signature string
+			ok             = true
+		)
+		if value, found := credential.FromAccessKeyId(key); found {
+			tok, signature, ok = h.verify(value, s3SecurityToken(r))
+		}
+		if ok {
+			_ = h.Recorder.Record(&Hit{
+				Time:       time.Now(),
+				Protocol:   "s3",
+				RemoteAddr: remoteHost(r.RemoteAddr),
+				Token:      tok,
+				Query:      r.Method + " " + r.URL.Path,
+				Signature:  signature,
+				Credential: key,
+				Client:     r.UserAgent(),
+			})
+		}
+	}
+
+	requestId := make([]byte, 8)
+	_, _ = rand.Read(requestId)
+
+	resp := &s3Error{
+		Code:           "InvalidAccessKeyId",
+		Message:        "The AWS Access Key Id you provided does not exist in our records.",
+		AWSAccessKeyId: key,
+		RequestId:      strings.ToUpper(hex.EncodeToString(requestId)),
+	}
+	if key == "" {
+		resp.Code, resp.Message = "AccessDenied", "Access Denied"
+	}
+	b, _ := xml.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("x-amz-request-id", resp.RequestId)
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = w.Write(append([]byte(xml.Header), b...))
+}
+
+// verify 校验 session token 的签名，session token 中的 token 与 access key id 不一致时签名错误
+// 不校验或没有 session token 时使用 access key id 中的 token，没有 session token 的校验结果为未签名
+func (h *S3Handler) verify(tok, session string) (string, string, bool) {
+	if h.Signer == nil || session == "" {
+		return verifyToken(h.Signer, h.Strict, tok)
+	}
+
+	value, signature, ok := verifyToken(h.Signer, h.Strict, session)
+	if value != tok {
+		return tok, SignatureInvalid, !h.Strict
+	}
+	return value, signature, ok
+}
+
+// s3SecurityToken 获取请求中的 session token，支持请求头和预签名 URL
+func s3SecurityToken(r *http.Request) string {
+	if v := r.Header.Get("X-Amz-Security-Token"); v != "" {
+		return v
+	}
+	return r.URL.Query().Get("X-Amz-Security-Token")
+}
+
+// s3AccessKeyId 获取请求签名中的 access key id
+// 支持 Signature V4、V2 请求头和预签名 URL
+func s3AccessKeyId(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(auth, "AWS4-HMAC-SHA256 "):
+		// AWS4-HMAC-SHA256 Credential=AKIA.../20240101/us-east-1/s3/aws4_request, SignedHeaders=..., Signature=...
+		for _, field := range strings.Split(auth[len("AWS4-HMAC-SHA256 "):], ",") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(field), "Credential="); ok {
+				key, _, _ := strings.Cut(v, "/")
+				return key
+			}
+		}
+	case strings.HasPrefix(auth, "AWS "):
+		// AWS AKIA...:signature
+		key, _, _ := strings.Cut(auth[len("AWS "):], ":")
+		return key
+	}
+
+	query := r.URL.Query()
+	if v := query.Get("X-Amz-Credential"); v != "" {
+		key, _, _ := strings.Cut(v, "/")
+		return key
+	}
+	return query.Get("AWSAccessKeyId")
+}
@@ -0,0 +1,289 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tracer/internal/token"
+)
+
+// SIEM 事件格式
+const (
+	FormatCEF  = "cef"  // ArcSight Common Event Format
+	FormatLEEF = "leef" // QRadar Log Event Extended Format 2.0
+	FormatECS  = "ecs"  // Elastic Common Schema JSON
+)
+
+const (
+	siemVendor  = "tracer"
+	siemProduct = "collector"
+	siemVersion = "1.0"
+	ecsVersion  = "8.11.0"
+
+	// cefSeverity 诱饵被触发即为高危事件，CEF 严重程度为 0-10
+	cefSeverity = 8
+)
+
+var ErrSIEMFormat = errors.New("unsupported siem format")
+
+// SIEMRecorder 以 CEF、LEEF 或 ECS JSON 格式输出追踪记录，每行一条事件
+// Writer 可以是文件、os.Stdout 或 SyslogWriter（TCP、UDP syslog）
+type SIEMRecorder struct {
+	Format   string // cef、leef、ecs
+	Writer   io.Writer
+	Registry *token.Registry // 查找 token 对应的诱饵文件，可选
+	Next     Recorder
+
+	mu sync.Mutex
+}
+
+func (r *SIEMRecorder) Record(hit *Hit) error {
+	var err error
+	if r.Next != nil {
+		err = r.Next.Record(hit)
+	}
+
+	var record *token.Record
+	if r.Registry != nil && hit.Token != "" {
+		record, _ = r.Registry.Lookup(hit.Token)
+	}
+	line, formatErr := FormatEvent(r.Format, hit, record)
+	if formatErr != nil {
+		return formatErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, writeErr := r.Writer.Write([]byte(line + "\n"))
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// FormatEvent 将追踪记录转换为 SIEM 事件，record 为注册表中的 token 记录，可以为空
+func FormatEvent(format string, hit *Hit, record *token.Record) (string, error) {
+	e := newEvent(hit, record)
+	switch format {
+	case FormatCEF:
+		return e.cef(), nil
+	case FormatLEEF:
+		return e.leef(), nil
+	case FormatECS:
+		return e.ecs()
+	default:
+		return "", fmt.Errorf("%w: %q", ErrSIEMFormat, format)
+	}
+}
+
+// event SIEM 事件字段
+type event struct {
+	*Hit
+	fileName  string // 诱饵文件名
+	format    string // 诱饵类型，例如 docx、kubeconfig
+	technique string // 追踪技术，注册表中没有记录时使用追踪地址中的 v 参数
+	method    string // HTTP 请求方法
+	uri       string // HTTP 请求地址
+	dnsName   string // DNS 查询名称
+	dnsType   string // DNS 查询类型
+}
+
+func newEvent(hit *Hit, record *token.Record) *event {
+	e := &event{Hit: hit}
+	if record != nil {
+		e.fileName = record.Memo
+		e.format = record.Kind
+		e.technique = record.Technique
+	}
+
+	// Query：HTTP 为 "方法 地址"，DNS 为 "名称 类型"
+	first, second, _ := strings.Cut(hit.Query, " ")
+	switch hit.Protocol {
+	case "dns":
+		e.dnsName, e.dnsType = strings.TrimSuffix(first, "."), second
+	case "http", "kubernetes", "s3":
+		e.method, e.uri = first, second
+		// 追踪地址中的查询参数 v 为实际触发的追踪技术，优先于注册表中逗号分隔的全部追踪技术
+		if u, err := url.ParseRequestURI(second); err == nil {
+			if v := u.Query().Get("v"); v != "" {
+				e.technique = v
+			}
+		}
+	}
+	return e
+}
+
+// cefHeaderEscaper CEF 头部字段转义 \ 和 |
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+
+// cefEscaper CEF 扩展字段转义 \、= 和换行
+var cefEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+
+// cef CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func (e *event) cef() string {
+	header := []string{"CEF:0", siemVendor, siemProduct, siemVersion, e.Protocol, "Decoy triggered", strconv.Itoa(cefSeverity)}
+	for i := range header {
+		header[i] = cefHeaderEscaper.Replace(header[i])
+	}
+
+	// 标准字段之外使用 cs1-cs6、flexString1-2 自定义字段
+	fields := [][2]string{
+		{"rt", strconv.FormatInt(e.Time.UnixMilli(), 10)},
+		{"app", e.Protocol},
+		{"src", e.RemoteAddr},
+		{"suser", e.User},
+		{"sntdom", e.Domain},
+		{"shost", e.Workstation},
+		{"requestMethod", e.method},
+		{"request", e.uri},
+		{"requestClientApplication", e.Client},
+		{"fname", e.fileName},
+		{"fileType", e.format},
+		{"cs1Label", label("token", e.Token)},
+		{"cs1", e.Token},
+		{"cs2Label", label("technique", e.technique)},
+		{"cs2", e.technique},
+		{"cs3Label", label("dnsQuery", e.dnsName)},
+		{"cs3", e.dnsName},
+		{"cs4Label", label("dnsType", e.dnsType)},
+		{"cs4", e.dnsType},
+		{"cs5Label", label("signature", e.Signature)},
+		{"cs5", e.Signature},
+		{"cs6Label", label("credential", e.Credential)},
+		{"cs6", e.Credential},
+		{"destinationServiceName", e.Database},
+		{"flexString1Label", label("app", e.App)},
+		{"flexString1", strings.TrimSpace(e.App + " " + e.AppVersion)},
+		{"flexString2Label", label("os", e.OS)},
+		{"flexString2", e.OS},
+	}
+	var ext []string
+	for _, field := range fields {
+		if field[1] != "" {
+			ext = append(ext, field[0]+"="+cefEscaper.Replace(field[1]))
+		}
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(ext, " ")
+}
+
+// leefEscaper LEEF 属性使用制表符分隔，值中的制表符和换行替换为空格
+var leefEscaper = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+
+// leef LEEF:2.0|Vendor|Product|Version|EventID|DelimiterCharacter|Attributes
+func (e *event) leef() string {
+	fields := [][2]string{
+		{"devTime", e.Time.UTC().Format("Jan 02 2006 15:04:05.000 UTC")},
+		{"devTimeFormat", "MMM dd yyyy HH:mm:ss.SSS z"},
+		{"cat", "decoy"},
+		{"sev", strconv.Itoa(cefSeverity)},
+		{"proto", e.Protocol},
+		{"src", e.RemoteAddr},
+		{"usrName", e.User},
+		{"domain", e.Domain},
+		{"identHostName", e.Workstation},
+		{"method", e.method},
+		{"url", e.uri},
+		{"userAgent", e.Client},
+		{"token", e.Token},
+		{"fileName", e.fileName},
+		{"fileType", e.format},
+		{"technique", e.technique},
+		{"dnsQuery", e.dnsName},
+		{"dnsType", e.dnsType},
+		{"signature", e.Signature},
+		{"credential", e.Credential},
+		{"database", e.Database},
+		{"app", e.App},
+		{"appVersion", e.AppVersion},
+		{"os", e.OS},
+	}
+	var attrs []string
+	for _, field := range fields {
+		if field[1] != "" {
+			attrs = append(attrs, field[0]+"="+leefEscaper.Replace(field[1]))
+		}
+	}
+
+	header := []string{"LEEF:2.0", siemVendor, siemProduct, siemVersion, e.Protocol, "x09"}
+	for i := range header {
+		header[i] = strings.ReplaceAll(header[i], "|", " ")
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(attrs, "\t")
+}
+
+// ecs Elastic Common Schema，空字段不输出
+func (e *event) ecs() (string, error) {
+	doc := make(map[string]any)
+	set := func(path string, value any) {
+		if s, ok := value.(string); ok && s == "" {
+			return
+		}
+		keys := strings.Split(path, ".")
+		m := doc
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := m[key].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				m[key] = child
+			}
+			m = child
+		}
+		m[keys[len(keys)-1]] = value
+	}
+
+	set("@timestamp", e.Time.UTC().Format(time.RFC3339Nano))
+	set("ecs.version", ecsVersion)
+	set("message", "Decoy triggered")
+	set("event.kind", "alert")
+	set("event.category", []string{"intrusion_detection"})
+	set("event.type", []string{"indicator"})
+	set("event.action", "decoy-triggered")
+	set("event.module", siemVendor)
+	set("event.dataset", siemVendor+".hit")
+	set("event.severity", cefSeverity)
+	set("observer.vendor", siemVendor)
+	set("observer.product", siemProduct)
+	set("observer.type", "honeypot")
+	set("network.protocol", e.Protocol)
+	set("source.ip", e.RemoteAddr)
+	set("user.name", e.User)
+	set("user.domain", e.Domain)
+	set("source.domain", e.Workstation)
+	set("http.request.method", e.method)
+	set("url.original", e.uri)
+	set("user_agent.original", e.Client)
+	set("user_agent.name", e.App)
+	set("user_agent.version", e.AppVersion)
+	set("user_agent.os.full", e.OS)
+	set("dns.question.name", e.dnsName)
+	set("dns.question.type", e.dnsType)
+	set("file.name", e.fileName)
+	set("tracer.token", e.Token)
+	set("tracer.format", e.format)
+	set("tracer.technique", e.technique)
+	set("tracer.signature", e.Signature)
+	set("tracer.credential", e.Credential)
+	set("tracer.database", e.Database)
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// label 自定义字段的值为空时不输出名称
+func label(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"tracer/internal/token"
+)
+
+var testHitTime = time.Date(2026, 10, 19, 3, 12, 0, 0, time.UTC)
+
+func TestFormatEventCEF(t *testing.T) {
+	hit := &Hit{
+		Time:       testHitTime,
+		Protocol:   "http",
+		RemoteAddr: "10.0.0.8",
+		Token:      "abc234abc234abcd",
+		Query:      "GET /t/abc234abc234abcd?v=prefetch",
+		Client:     "Mozilla/5.0 (Windows NT 10.0; a=b|c)",
+	}
+	record := &token.Record{Token: "abc234abc234abcd", Kind: "html", Memo: `C:\share\index.html`, Technique: "html-prefetch,html-image"}
+
+	got, err := FormatEvent(FormatCEF, hit, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `CEF:0|tracer|collector|1.0|http|Decoy triggered|8|rt=1792379520000 app=http src=10.0.0.8 requestMethod=GET ` +
+		`request=/t/abc234abc234abcd?v\=prefetch requestClientApplication=Mozilla/5.0 (Windows NT 10.0; a\=b|c) ` +
+		`fname=C:\\share\\index.html fileType=html cs1Label=token cs1=abc234abc234abcd cs2Label=technique cs2=prefetch`
+	if got != want {
+		t.Errorf("cef = %s\nwant  %s", got, want)
+	}
+}
+
+func TestFormatEventLEEF(t *testing.T) {
+	hit := &Hit{
+		Time:       testHitTime,
+		Protocol:   "dns",
+		RemoteAddr: "8.8.8.8",
+		Token:      "abc234abc234abcd",
+		Query:      "abc234abc234abcd.canary.test. AAAA",
+		Signature:  SignatureValid,
+	}
+	record := &token.Record{Token: "abc234abc234abcd", Kind: "docx", Memo: "工资表.docx", Technique: "docx-template"}
+
+	got, err := FormatEvent(FormatLEEF, hit, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "LEEF:2.0|tracer|collector|1.0|dns|x09|devTime=Oct 19 2026 03:12:00.000 UTC\tdevTimeFormat=MMM dd yyyy HH:mm:ss.SSS z\t" +
+		"cat=decoy\tsev=8\tproto=dns\tsrc=8.8.8.8\ttoken=abc234abc234abcd\tfileName=工资表.docx\tfileType=docx\t" +
+		"technique=docx-template\tdnsQuery=abc234abc234abcd.canary.test\tdnsType=AAAA\tsignature=valid"
+	if got != want {
+		t.Errorf("leef = %q\nwant   %q", got, want)
+	}
+}
+
+func TestFormatEventECS(t *testing.T) {
+	hit := &Hit{
+		Time:        testHitTime,
+		Protocol:    "smb",
+		RemoteAddr:  "10.0.0.8",
+		Token:       "abc234abc234abcd",
+		User:        "alice",
+		Domain:      "CORP",
+		Workstation: "WS01",
+	}
+
+	got, err := FormatEvent(FormatECS, hit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Timestamp string `json:"@timestamp"`
+		Event     struct {
+			Kind     string   `json:"kind"`
+			Category []string `json:"category"`
+		} `json:"event"`
+		Source map[string]string `json:"source"`
+		User   map[string]string `json:"user"`
+		Tracer map[string]string `json:"tracer"`
+		URL    any               `json:"url"`
+	}
+	if err = json.Unmarshal([]byte(got), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Timestamp != "2026-10-19T03:12:00Z" || doc.Event.Kind != "alert" || doc.Event.Category[0] != "intrusion_detection" {
+		t.Errorf("ecs = %s", got)
+	}
+	if doc.Source["ip"] != "10.0.0.8" || doc.Source["domain"] != "WS01" || doc.User["name"] != "alice" || doc.User["domain"] != "CORP" {
+		t.Errorf("ecs = %s", got)
+	}
+	if doc.Tracer["token"] != "abc234abc234abcd" || len(doc.Tracer) != 1 || doc.URL != nil {
+		t.Errorf("ecs = %s", got)
+	}
+
+	if _, err = FormatEvent("csv", hit, nil); !errors.Is(err, ErrSIEMFormat) {
+		t.Errorf("error = %v, want ErrSIEMFormat", err)
+	}
+}
+
+func TestSIEMRecorder(t *testing.T) {
+	registry, err := token.OpenRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := registry.Mint("kubeconfig", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hit := &Hit{Time: testHitTime, Protocol: "kubernetes", RemoteAddr: "10.0.0.8", Token: record.Token, Query: "GET /api"}
+
+	// 1、输出到文件，每行一条事件
+	var buf bytes.Buffer
+	next, nextRecorder := testHits()
+	r := &SIEMRecorder{Format: FormatCEF, Writer: &buf, Registry: registry, Next: nextRecorder}
+	for i := 0; i < 2; i++ {
+		if err = r.Record(hit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || len(next) != 2 || !strings.Contains(lines[0], "fname=config fileType=kubeconfig") {
+		t.Errorf("output = %q", buf.String())
+	}
+
+	// 2、输出到 UDP syslog
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	r = &SIEMRecorder{Format: FormatLEEF, Writer: &SyslogWriter{Addr: conn.LocalAddr().String(), Hostname: "collector"}}
+	if err = r.Record(hit); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := string(b[:n]); !strings.Contains(msg, " collector tracer ") || !strings.Contains(msg, " event - LEEF:2.0|tracer|") || strings.HasSuffix(msg, "\n") {
+		t.Errorf("syslog = %q", msg)
+	}
+}
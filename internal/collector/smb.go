@@ -0,0 +1,392 @@
+package collector
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"tracer/internal/token"
+)
+
+const (
+	smb2HeaderSize = 64
+
+	smb2Negotiate    = 0x0000
+	smb2SessionSetup = 0x0001
+	smb2Logoff       = 0x0002
+	smb2TreeConnect  = 0x0003
+	smb2Create       = 0x0005
+
+	smb2ShareTypeDisk = 0x01
+
+	smb2FlagsServerToRedir = 0x00000001
+
+	statusMoreProcessingRequired = 0xc0000016
+	statusObjectNameNotFound     = 0xc0000034
+	statusNotSupported           = 0xc00000bb
+)
+
+var (
+	smb1ProtocolId = []byte{0xff, 'S', 'M', 'B'}
+	smb2ProtocolId = []byte{0xfe, 'S', 'M', 'B'}
+)
+
+var errSMB = errors.New("invalid smb message")
+
+// smbSession 连接的会话状态
+type smbSession struct {
+	id         []byte
+	remoteAddr string
+	info       *NTLMInfo // NTLMSSP 认证信息，认证前为空
+	tree       string    // TREE_CONNECT 的共享路径，例如 \\host\share
+	recorded   bool
+}
+
+// SMBServer 最小实现的 SMB2 服务
+// 完成协商和 NTLMSSP 认证后接受登录，从 TREE_CONNECT 的共享路径和 CREATE 的文件名中解析 token，打开文件时返回文件不存在；
+// 记录 AUTHENTICATE 消息中的用户名、域名、主机名和 UNC 路径，连接关闭前没有收到带有 token 的路径时只记录认证信息
+type SMBServer struct {
+	Addr     string       // 监听地址，默认 :445
+	Domain   string       // NTLM 域名，默认 WORKGROUP
+	Host     string       // NTLM 主机名，默认 FILESERVER
+	Signer   token.Signer // 校验 token 签名，为空时不校验
+	Strict   bool         // 只记录签名有效的 token
+	Recorder Recorder
+
+	mu       sync.Mutex
+	listener net.Listener
+}
+
+// ListenAndServe 监听并处理连接
+func (s *SMBServer) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":445"
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 处理连接，直到 listener 关闭
+func (s *SMBServer) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close 关闭服务
+func (s *SMBServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *SMBServer) handle(conn net.Conn) {
+	session := &smbSession{id: make([]byte, 8)}
+	_, _ = rand.Read(session.id)
+	session.remoteAddr, _, _ = net.SplitHostPort(conn.RemoteAddr().String())
+
+	defer func() {
+		_ = conn.Close()
+
+		// 没有收到带有 token 的路径时只记录认证信息
+		s.record(session, "", "")
+	}()
+
+	for {
+		_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+		msg, err := readSMBMessage(conn)
+		if err != nil {
+			return
+		}
+
+		// SMB1 协商，客户端支持 SMB2 时回复 SMB2 协商响应
+		if bytes.HasPrefix(msg, smb1ProtocolId) {
+			if !bytes.Contains(msg, []byte("SMB 2.")) {
+				return
+			}
+			err = writeSMBMessage(conn, s.negotiateResponse(make([]byte, smb2HeaderSize), 0x02ff))
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		if len(msg) < smb2HeaderSize || !bytes.HasPrefix(msg, smb2ProtocolId) {
+			return
+		}
+
+		command := binary.LittleEndian.Uint16(msg[12:])
+		switch {
+		case command == smb2Negotiate:
+			dialect := selectDialect(msg[smb2HeaderSize:])
+			if dialect == 0 {
+				_ = writeSMBMessage(conn, smb2Error(msg, statusNotSupported))
+				return
+			}
+			err = writeSMBMessage(conn, s.negotiateResponse(msg, dialect))
+		case command == smb2SessionSetup:
+			err = s.sessionSetup(conn, msg, session)
+		case session.info == nil || command == smb2Logoff:
+			// 认证前只处理协商和 SESSION_SETUP
+			_ = writeSMBMessage(conn, smb2Error(msg, statusNotSupported))
+			return
+		case command == smb2TreeConnect:
+			err = s.treeConnect(conn, msg, session)
+		case command == smb2Create:
+			err = s.create(conn, msg, session)
+		default:
+			// 认证后的其它请求（查询 DFS 引用等）返回不支持，等待客户端打开文件
+			err = writeSMBMessage(conn, smb2Error(msg, statusNotSupported))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// sessionSetup 处理 SESSION_SETUP 请求
+// 收到 NEGOTIATE 时返回 CHALLENGE，收到 AUTHENTICATE 时保存认证信息并接受登录
+func (s *SMBServer) sessionSetup(conn net.Conn, msg []byte, session *smbSession) error {
+	body := msg[smb2HeaderSize:]
+	if len(body) < 24 {
+		return errSMB
+	}
+
+	offset := int(binary.LittleEndian.Uint16(body[12:]))
+	length := int(binary.LittleEndian.Uint16(body[14:]))
+	if offset+length > len(msg) {
+		return errSMB
+	}
+
+	var (
+		blob   []byte
+		status uint32
+	)
+	ntlm := findNTLM(msg[offset : offset+length])
+	switch ntlmType(ntlm) {
+	case ntlmMessageNegotiate:
+		domain, host := s.Domain, s.Host
+		if domain == "" {
+			domain = "WORKGROUP"
+		}
+		if host == "" {
+			host = "FILESERVER"
+		}
+		blob, status = spnegoResp(ntlmChallenge(domain, host)), statusMoreProcessingRequired
+	case ntlmMessageAuthenticate:
+		info, err := parseNTLMAuthenticate(ntlm)
+		if err != nil {
+			return err
+		}
+		session.info = info
+		blob, status = spnegoAccept(), 0
+	default:
+		return errSMB
+	}
+
+	resp := make([]byte, 8)
+	binary.LittleEndian.PutUint16(resp[0:], 9)
+	binary.LittleEndian.PutUint16(resp[4:], smb2HeaderSize+8)
+	binary.LittleEndian.PutUint16(resp[6:], uint16(len(blob)))
+	resp = append(resp, blob...)
+
+	header := smb2Header(msg, status)
+	copy(header[40:48], session.id)
+	return writeSMBMessage(conn, append(header, resp...))
+}
+
+// treeConnect 处理 TREE_CONNECT 请求，任意共享均返回磁盘共享，共享路径中有 token 时记录
+func (s *SMBServer) treeConnect(conn net.Conn, msg []byte, session *smbSession) error {
+	body := msg[smb2HeaderSize:]
+	if len(body) < 8 {
+		return errSMB
+	}
+
+	offset := int(binary.LittleEndian.Uint16(body[4:]))
+	length := int(binary.LittleEndian.Uint16(body[6:]))
+	if offset+length > len(msg) {
+		return errSMB
+	}
+	session.tree = decodeUTF16(msg[offset : offset+length])
+	s.recordPath(session, session.tree)
+
+	resp := make([]byte, 16)
+	binary.LittleEndian.PutUint16(resp[0:], 16)
+	resp[2] = smb2ShareTypeDisk
+	binary.LittleEndian.PutUint32(resp[12:], 0x001f01ff) // MaximalAccess
+
+	header := smb2Header(msg, 0)
+	binary.LittleEndian.PutUint32(header[36:], 1) // TreeId
+	copy(header[40:48], session.id)
+	return writeSMBMessage(conn, append(header, resp...))
+}
+
+// create 处理 CREATE 请求，文件路径中有 token 时记录，返回文件不存在
+func (s *SMBServer) create(conn net.Conn, msg []byte, session *smbSession) error {
+	body := msg[smb2HeaderSize:]
+	if len(body) < 56 {
+		return errSMB
+	}
+
+	offset := int(binary.LittleEndian.Uint16(body[44:]))
+	length := int(binary.LittleEndian.Uint16(body[46:]))
+	if offset+length > len(msg) {
+		return errSMB
+	}
+	name := decodeUTF16(msg[offset : offset+length])
+	s.recordPath(session, strings.TrimSuffix(session.tree, `\`)+`\`+name)
+
+	return writeSMBMessage(conn, smb2Error(msg, statusObjectNameNotFound))
+}
+
+// recordPath 从 UNC 路径中解析 token，解析成功时记录
+func (s *SMBServer) recordPath(session *smbSession, path string) {
+	if value, ok := token.FromPath(strings.ReplaceAll(path, `\`, "/")); ok {
+		s.record(session, path, value)
+	}
+}
+
+// record 记录认证信息，每个连接只记录一次
+// path: UNC 路径，value: 路径中的 token，为空时只记录认证信息
+func (s *SMBServer) record(session *smbSession, path, value string) {
+	if session.info == nil || session.recorded || s.Recorder == nil {
+		return
+	}
+	session.recorded = true
+
+	hit := &Hit{
+		Time:        time.Now(),
+		Protocol:    "smb",
+		RemoteAddr:  session.remoteAddr,
+		Query:       path,
+		User:        session.info.User,
+		Domain:      session.info.Domain,
+		Workstation: session.info.Workstation,
+	}
+	if value != "" {
+		tok, signature, ok := verifyToken(s.Signer, s.Strict, value)
+		if !ok {
+			return
+		}
+		hit.Token, hit.Signature = tok, signature
+	}
+	_ = s.Recorder.Record(hit)
+}
+
+// negotiateResponse 生成 SMB2 NEGOTIATE 响应
+func (s *SMBServer) negotiateResponse(msg []byte, dialect uint16) []byte {
+	blob := spnegoInit()
+
+	resp := make([]byte, 64)
+	binary.LittleEndian.PutUint16(resp[0:], 65)
+	binary.LittleEndian.PutUint16(resp[2:], 0x0001) // SMB2_NEGOTIATE_SIGNING_ENABLED
+	binary.LittleEndian.PutUint16(resp[4:], dialect)
+	_, _ = rand.Read(resp[8:24]) // ServerGuid
+	binary.LittleEndian.PutUint32(resp[28:], 65536)
+	binary.LittleEndian.PutUint32(resp[32:], 65536)
+	binary.LittleEndian.PutUint32(resp[36:], 65536)
+	binary.LittleEndian.PutUint64(resp[40:], fileTime(time.Now()))
+	binary.LittleEndian.PutUint16(resp[56:], smb2HeaderSize+64)
+	binary.LittleEndian.PutUint16(resp[58:], uint16(len(blob)))
+	resp = append(resp, blob...)
+
+	header := smb2Header(msg, 0)
+	binary.LittleEndian.PutUint16(header[12:], smb2Negotiate)
+	return append(header, resp...)
+}
+
+// selectDialect 选择 SMB2 协议版本，只支持 2.0.2 和 2.1
+func selectDialect(body []byte) uint16 {
+	if len(body) < 36 {
+		return 0
+	}
+
+	count := int(binary.LittleEndian.Uint16(body[2:]))
+	if len(body) < 36+count*2 {
+		return 0
+	}
+
+	var selected uint16
+	for i := 0; i < count; i++ {
+		dialect := binary.LittleEndian.Uint16(body[36+i*2:])
+		if (dialect == 0x0202 || dialect == 0x0210) && dialect > selected {
+			selected = dialect
+		}
+	}
+	return selected
+}
+
+// smb2Header 根据请求生成响应头
+func smb2Header(req []byte, status uint32) []byte {
+	header := make([]byte, smb2HeaderSize)
+	copy(header, smb2ProtocolId)
+	binary.LittleEndian.PutUint16(header[4:], smb2HeaderSize)
+	binary.LittleEndian.PutUint32(header[8:], status)
+	copy(header[12:14], req[12:14])               // Command
+	binary.LittleEndian.PutUint16(header[14:], 1) // CreditResponse
+	binary.LittleEndian.PutUint32(header[16:], smb2FlagsServerToRedir)
+	copy(header[24:32], req[24:32]) // MessageId
+	copy(header[40:48], req[40:48]) // SessionId
+	return header
+}
+
+// smb2Error 生成 SMB2 错误响应
+func smb2Error(req []byte, status uint32) []byte {
+	resp := make([]byte, 9)
+	binary.LittleEndian.PutUint16(resp[0:], 9)
+	return append(smb2Header(req, status), resp...)
+}
+
+// readSMBMessage 读取 NetBIOS 会话消息
+func readSMBMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+	if header[0] != 0 || length > 1<<20 {
+		return nil, errSMB
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeSMBMessage 输出 NetBIOS 会话消息
+func writeSMBMessage(w io.Writer, msg []byte) error {
+	length := len(msg)
+	header := []byte{0, byte(length >> 16), byte(length >> 8), byte(length)}
+	_, err := w.Write(append(header, msg...))
+	return err
+}
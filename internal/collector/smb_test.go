@@ -0,0 +1,271 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"tracer/internal/token"
+)
+
+// testSMB2Request 生成 SMB2 请求
+func testSMB2Request(command uint16, messageId uint64, body []byte) []byte {
+	header := make([]byte, smb2HeaderSize)
+	copy(header, smb2ProtocolId)
+	binary.LittleEndian.PutUint16(header[4:], smb2HeaderSize)
+	binary.LittleEndian.PutUint16(header[12:], command)
+	binary.LittleEndian.PutUint64(header[24:], messageId)
+	return append(header, body...)
+}
+
+// testSessionSetup 生成 SESSION_SETUP 请求
+func testSessionSetup(messageId uint64, blob []byte) []byte {
+	body := make([]byte, 24)
+	binary.LittleEndian.PutUint16(body[0:], 25)
+	binary.LittleEndian.PutUint16(body[12:], smb2HeaderSize+24)
+	binary.LittleEndian.PutUint16(body[14:], uint16(len(blob)))
+	return testSMB2Request(smb2SessionSetup, messageId, append(body, blob...))
+}
+
+// testTreeConnect 生成 TREE_CONNECT 请求
+func testTreeConnect(messageId uint64, path string) []byte {
+	b := encodeUTF16(path)
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint16(body[0:], 9)
+	binary.LittleEndian.PutUint16(body[4:], smb2HeaderSize+8)
+	binary.LittleEndian.PutUint16(body[6:], uint16(len(b)))
+	return testSMB2Request(smb2TreeConnect, messageId, append(body, b...))
+}
+
+// testCreate 生成 CREATE 请求
+func testCreate(messageId uint64, name string) []byte {
+	b := encodeUTF16(name)
+	body := make([]byte, 56)
+	binary.LittleEndian.PutUint16(body[0:], 57)
+	binary.LittleEndian.PutUint16(body[44:], smb2HeaderSize+56)
+	binary.LittleEndian.PutUint16(body[46:], uint16(len(b)))
+	return testSMB2Request(smb2Create, messageId, append(body, b...))
+}
+
+// testNTLMAuthenticate 生成 NTLMSSP AUTHENTICATE 消息
+func testNTLMAuthenticate(domain, user, workstation string) []byte {
+	msg := make([]byte, 64)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], ntlmMessageAuthenticate)
+	binary.LittleEndian.PutUint32(msg[60:], ntlmNegotiateUnicode)
+
+	for i, value := range []string{domain, user, workstation} {
+		b := encodeUTF16(value)
+		offset := 28 + i*8
+		binary.LittleEndian.PutUint16(msg[offset:], uint16(len(b)))
+		binary.LittleEndian.PutUint16(msg[offset+2:], uint16(len(b)))
+		binary.LittleEndian.PutUint32(msg[offset+4:], uint32(len(msg)))
+		msg = append(msg, b...)
+	}
+	return msg
+}
+
+func TestSMBServer(t *testing.T) {
+	hits := make(chan *Hit, 1)
+	server := &SMBServer{Recorder: RecorderFunc(func(hit *Hit) error {
+		hits <- hit
+		return nil
+	})}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer func() {
+		_ = server.Close()
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// 1、协商
+	negotiate := make([]byte, 36)
+	binary.LittleEndian.PutUint16(negotiate[0:], 36)
+	binary.LittleEndian.PutUint16(negotiate[2:], 3)
+	for _, dialect := range []uint16{0x0202, 0x0210, 0x0311} {
+		negotiate = binary.LittleEndian.AppendUint16(negotiate, dialect)
+	}
+	if err = writeSMBMessage(conn, testSMB2Request(smb2Negotiate, 0, negotiate)); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := readSMBMessage(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dialect := binary.LittleEndian.Uint16(resp[smb2HeaderSize+4:]); dialect != 0x0210 {
+		t.Fatalf("dialect = %#x, want 0x0210", dialect)
+	}
+
+	// 2、NTLMSSP NEGOTIATE
+	ntlmNegotiate := append(append([]byte{}, ntlmSignature...), 1, 0, 0, 0, 0, 0, 0, 0)
+	if err = writeSMBMessage(conn, testSessionSetup(1, spnegoResp(ntlmNegotiate))); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = readSMBMessage(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := binary.LittleEndian.Uint32(resp[8:]); status != statusMoreProcessingRequired {
+		t.Fatalf("status = %#x, want %#x", status, statusMoreProcessingRequired)
+	}
+	if ntlmType(findNTLM(resp)) != ntlmMessageChallenge {
+		t.Fatal("missing ntlmssp challenge")
+	}
+
+	// 3、NTLMSSP AUTHENTICATE
+	if err = writeSMBMessage(conn, testSessionSetup(2, testNTLMAuthenticate("CORP", "alice", "WS01"))); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = readSMBMessage(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := binary.LittleEndian.Uint32(resp[8:]); status != 0 {
+		t.Fatalf("status = %#x, want 0", status)
+	}
+
+	// 4、TREE_CONNECT 共享路径，CREATE 打开 token 目录中的文件
+	tok := token.New()
+	if err = writeSMBMessage(conn, testTreeConnect(3, `\\127.0.0.1\share`)); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = readSMBMessage(conn); err != nil {
+		t.Fatal(err)
+	}
+	if status := binary.LittleEndian.Uint32(resp[8:]); status != 0 || resp[smb2HeaderSize+2] != smb2ShareTypeDisk {
+		t.Fatalf("tree connect status = %#x", status)
+	}
+	if err = writeSMBMessage(conn, testCreate(4, tok+`\template.dotx`)); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = readSMBMessage(conn); err != nil {
+		t.Fatal(err)
+	}
+	if status := binary.LittleEndian.Uint32(resp[8:]); status != statusObjectNameNotFound {
+		t.Fatalf("create status = %#x, want %#x", status, statusObjectNameNotFound)
+	}
+
+	hit := <-hits
+	if hit.Protocol != "smb" || hit.User != "alice" || hit.Domain != "CORP" || hit.Workstation != "WS01" {
+		t.Errorf("hit = %+v", hit)
+	}
+	if hit.Token != tok || hit.Query != `\\127.0.0.1\share\`+tok+`\template.dotx` {
+		t.Errorf("Token = %s, Query = %s", hit.Token, hit.Query)
+	}
+	if hit.RemoteAddr != "127.0.0.1" {
+		t.Errorf("RemoteAddr = %s", hit.RemoteAddr)
+	}
+
+	// 每个连接只记录一次
+	_ = conn.Close()
+	select {
+	case hit = <-hits:
+		t.Errorf("unexpected hit %+v", hit)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSMBServerNoToken(t *testing.T) {
+	hits := make(chan *Hit, 1)
+	server := &SMBServer{Recorder: RecorderFunc(func(hit *Hit) error {
+		hits <- hit
+		return nil
+	})}
+	client, conn := net.Pipe()
+	go server.handle(conn)
+
+	// 认证后关闭连接，只记录认证信息
+	go func() {
+		_ = writeSMBMessage(client, testSessionSetup(1, testNTLMAuthenticate("CORP", "bob", "WS02")))
+	}()
+	if _, err := readSMBMessage(client); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Close()
+
+	hit := <-hits
+	if hit.User != "bob" || hit.Token != "" || hit.Query != "" {
+		t.Errorf("hit = %+v", hit)
+	}
+}
+
+func TestSMBServerSigned(t *testing.T) {
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := make(chan *Hit, 3)
+	server := &SMBServer{Signer: keyring, Strict: true, Recorder: RecorderFunc(func(hit *Hit) error {
+		hits <- hit
+		return nil
+	})}
+
+	// 只记录签名正确的路径，每个连接使用单独的 session
+	tok := token.New()
+	for _, value := range []string{tok, "k1-" + tok + "-aaaaaaaaaaaaaaaa", keyring.Sign(tok)} {
+		session := &smbSession{remoteAddr: "127.0.0.1", info: &NTLMInfo{User: "bob"}}
+		server.recordPath(session, `\\10.0.0.1\share\`+value+`\a.docx`)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("hits = %d, want 1", len(hits))
+	}
+	if hit := <-hits; hit.Token != tok || hit.Signature != SignatureValid {
+		t.Errorf("hit = %+v", hit)
+	}
+}
+
+func TestSMBServerSMB1(t *testing.T) {
+	server := &SMBServer{}
+	client, conn := net.Pipe()
+	go server.handle(conn)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	msg := append(append([]byte{}, smb1ProtocolId...), 0x72)
+	msg = append(msg, make([]byte, 27)...)
+	msg = append(msg, []byte("\x02NT LM 0.12\x00\x02SMB 2.002\x00\x02SMB 2.???\x00")...)
+	go func() {
+		_ = writeSMBMessage(client, msg)
+	}()
+
+	resp, err := readSMBMessage(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(resp, smb2ProtocolId) {
+		t.Fatal("want smb2 response")
+	}
+	if dialect := binary.LittleEndian.Uint16(resp[smb2HeaderSize+4:]); dialect != 0x02ff {
+		t.Errorf("dialect = %#x, want 0x02ff", dialect)
+	}
+}
+
+func TestParseNTLMAuthenticate(t *testing.T) {
+	info, err := parseNTLMAuthenticate(testNTLMAuthenticate("域", "用户", "PC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Domain != "域" || info.User != "用户" || info.Workstation != "PC" {
+		t.Errorf("info = %+v", info)
+	}
+
+	if _, err = parseNTLMAuthenticate([]byte("NTLMSSP\x00")); err == nil {
+		t.Error("want error")
+	}
+}
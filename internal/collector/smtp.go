@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier 通过 SMTP 发送告警邮件
+// 服务器支持 STARTTLS 时自动启用，Username 不为空时使用 PLAIN 认证（未启用 TLS 时只允许连接本机）
+type SMTPNotifier struct {
+	Addr      string // 服务器地址，host:port
+	From      string
+	To        []string
+	Username  string
+	Password  string
+	TLSConfig *tls.Config // STARTTLS 使用的配置，为空时校验服务器证书
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, alert *Alert) error {
+	host, _, err := net.SplitHostPort(n.Addr)
+	if err != nil {
+		return err
+	}
+
+	// 1、连接服务器，整个会话使用 ctx 的超时时间
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() {
+		_ = c.Close()
+	}()
+
+	// 2、STARTTLS、认证
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		config := n.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: host}
+		}
+		err = c.StartTLS(config)
+		if err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", n.Username, n.Password, host))
+		if err != nil {
+			return err
+		}
+	}
+
+	// 3、发送邮件
+	err = c.Mail(n.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		err = c.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	msg, err := n.message(alert)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message 生成 quoted-printable 编码的纯文本邮件
+func (n *SMTPNotifier) message(alert *Alert) ([]byte, error) {
+	to := make([]string, len(n.To))
+	for i, addr := range n.To {
+		to[i] = (&mail.Address{Address: addr}).String()
+	}
+	header := []string{
+		"From: " + (&mail.Address{Address: n.From}).String(),
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.BEncoding.Encode("utf-8", alert.Subject()),
+		"Date: " + time.Now().Format("Mon, 02 Jan 2006 15:04:05 -0700"),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+	}
+
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	_, err := qp.Write([]byte(strings.ReplaceAll(alert.Text(), "\n", "\r\n")))
+	if err != nil {
+		return nil, err
+	}
+	err = qp.Close()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(strings.Join(header, "\r\n")+"\r\n\r\n"), body.Bytes()...), nil
+}
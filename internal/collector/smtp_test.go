@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSMTPServer 最小的 SMTP 服务，接收一封邮件后返回 MAIL FROM、RCPT TO 及邮件内容
+func testSMTPServer(t *testing.T) (string, chan []string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	result := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		var (
+			r        = bufio.NewReader(conn)
+			commands []string
+			data     strings.Builder
+			inData   bool
+		)
+		_, _ = conn.Write([]byte("220 localhost ESMTP\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					_, _ = conn.Write([]byte("250 OK\r\n"))
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+
+			cmd := strings.TrimSpace(line)
+			switch verb := strings.ToUpper(strings.Fields(cmd)[0]); verb {
+			case "EHLO":
+				_, _ = conn.Write([]byte("250-localhost\r\n250 8BITMIME\r\n"))
+			case "DATA":
+				inData = true
+				_, _ = conn.Write([]byte("354 Go ahead\r\n"))
+			case "QUIT":
+				_, _ = conn.Write([]byte("221 Bye\r\n"))
+				result <- append(commands, data.String())
+				return
+			default:
+				commands = append(commands, cmd)
+				_, _ = conn.Write([]byte("250 OK\r\n"))
+			}
+		}
+	}()
+	return l.Addr().String(), result
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, result := testSMTPServer(t)
+	n := &SMTPNotifier{Addr: addr, From: "tracer@example.com", To: []string{"soc@example.com", "oncall@example.com"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Notify(ctx, testAlert()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-result
+	want := []string{"MAIL FROM:<tracer@example.com> BODY=8BITMIME", "RCPT TO:<soc@example.com>", "RCPT TO:<oncall@example.com>"}
+	if len(got) != 4 || strings.Join(got[:3], "\n") != strings.Join(want, "\n") {
+		t.Fatalf("commands = %q", got)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got[3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if subject != "[tracer] 工资表.docx 被访问" || msg.Header.Get("To") != "<soc@example.com>, <oncall@example.com>" {
+		t.Errorf("header = %v", msg.Header)
+	}
+	if !strings.Contains(string(body), "用户: CORP\\alice\r\n") {
+		t.Errorf("body = %s", body)
+	}
+}
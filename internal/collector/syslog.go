@@ -0,0 +1,171 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	syslogFacilityLocal0  = 16
+	syslogSeverityWarning = 4
+
+	// syslogEnterpriseId 结构化数据 ID 使用的私有企业编号，RFC 5424 中的示例编号
+	syslogEnterpriseId = "32473"
+)
+
+// SyslogNotifier 以 RFC 5424 格式发送告警到 syslog 服务
+// 告警标题为消息内容，追踪记录中的字段为结构化数据（tracer@32473）；TCP 使用 RFC 6587 的长度前缀分帧
+type SyslogNotifier struct {
+	Network  string // udp、tcp，默认 udp
+	Addr     string // 服务器地址，host:port
+	Hostname string // 默认为本机主机名
+	AppName  string // 默认 tracer
+	Facility int    // 默认 16（local0）
+	Severity int    // 默认 4（warning）
+}
+
+func (n *SyslogNotifier) Notify(ctx context.Context, alert *Alert) error {
+	var (
+		hit    = alert.Hit
+		params = [][2]string{
+			{"token", hit.Token},
+			{"protocol", hit.Protocol},
+			{"src", hit.RemoteAddr},
+			{"query", hit.Query},
+			{"signature", hit.Signature},
+			{"user", hit.User},
+			{"domain", hit.Domain},
+			{"workstation", hit.Workstation},
+			{"credential", hit.Credential},
+			{"database", hit.Database},
+			{"client", hit.Client},
+			{"app", strings.TrimSpace(hit.App + " " + hit.AppVersion)},
+			{"os", hit.OS},
+		}
+	)
+	if alert.Record != nil {
+		params = append(params, [2]string{"kind", alert.Record.Kind}, [2]string{"memo", alert.Record.Memo})
+	}
+
+	w := &SyslogWriter{
+		Network:  n.Network,
+		Addr:     n.Addr,
+		Hostname: n.Hostname,
+		AppName:  n.AppName,
+		Facility: n.Facility,
+		Severity: n.Severity,
+	}
+	return w.write(ctx, hit.Time, "alert", "[tracer@"+syslogEnterpriseId+syslogParams(params)+"]", alert.Subject())
+}
+
+// SyslogWriter 发送 RFC 5424 格式的 syslog 消息，每条消息使用新的连接
+// 字段含义及默认值与 SyslogNotifier 相同；作为 io.Writer 使用时每次 Write 发送一条消息，例如 SIEMRecorder 的输出
+type SyslogWriter struct {
+	Network  string
+	Addr     string
+	Hostname string
+	AppName  string
+	Facility int
+	Severity int
+	Timeout  time.Duration // Write 的超时时间，默认 10 秒
+}
+
+// Write 将 p 作为一条消息发送，去掉末尾的换行
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := w.write(ctx, time.Now(), "event", "", strings.TrimRight(string(p), "\r\n"))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// write 发送消息，sd 为结构化数据，为空时使用 -
+func (w *SyslogWriter) write(ctx context.Context, t time.Time, msgId, sd, msg string) error {
+	var (
+		network  = w.Network
+		hostname = w.Hostname
+		appName  = w.AppName
+		facility = w.Facility
+		severity = w.Severity
+	)
+	if network == "" {
+		network = "udp"
+	}
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	if appName == "" {
+		appName = "tracer"
+	}
+	if facility == 0 {
+		facility = syslogFacilityLocal0
+	}
+	if severity == 0 {
+		severity = syslogSeverityWarning
+	}
+	if sd == "" {
+		sd = "-"
+	}
+
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	// MSG 不添加 BOM，CEF、LEEF 解析器要求消息以 CEF:、LEEF: 开头
+	line := "<" + strconv.Itoa(facility*8+severity) + ">1 " + t.UTC().Format("2006-01-02T15:04:05.000000Z") + " " +
+		syslogName(hostname) + " " + syslogName(appName) + " " + strconv.Itoa(os.Getpid()) + " " + syslogName(msgId) + " " +
+		sd + " " + strings.ReplaceAll(msg, "\n", " ")
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, w.Addr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if !strings.HasPrefix(network, "udp") {
+		line = strconv.Itoa(len(line)) + " " + line
+	}
+	_, err = conn.Write([]byte(line))
+	return err
+}
+
+// syslogName 头部字段只允许可打印的 ASCII 字符，为空时使用 -
+func syslogName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// syslogParams 生成结构化数据的参数，跳过空值，转义 "、\、]
+func syslogParams(params [][2]string) string {
+	var b strings.Builder
+	escape := strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+	for _, param := range params {
+		if param[1] == "" {
+			continue
+		}
+		b.WriteString(" " + param[0] + `="` + escape.Replace(param[1]) + `"`)
+	}
+	return b.String()
+}
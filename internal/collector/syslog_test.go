@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogNotifier(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 1、UDP：一个数据包一条消息
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	n := &SyslogNotifier{Addr: conn.LocalAddr().String(), Hostname: "collector 1"}
+	if err = n.Notify(ctx, testAlert()); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	re := regexp.MustCompile(`^<132>1 2026-10-19T03:12:00\.000000Z collector1 tracer \d+ alert ` +
+		`\[tracer@32473 token="abc234abc234abcd" protocol="smb" src="10\.0\.0\.8" user="alice" domain="CORP" kind="docx" memo="工资表\.docx"\] ` +
+		`\[tracer\] 工资表\.docx 被访问$`)
+	if msg := string(buf[:size]); !re.MatchString(msg) {
+		t.Errorf("udp message = %q", msg)
+	}
+
+	// 2、TCP：长度前缀分帧，转义结构化数据
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = l.Close()
+	}()
+	received := make(chan string, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = c.Close()
+		}()
+		r := bufio.NewReader(c)
+		prefix, _ := r.ReadString(' ')
+		size, _ := strconv.Atoi(strings.TrimSpace(prefix))
+		msg := make([]byte, size)
+		_, _ = io.ReadFull(r, msg)
+		received <- string(msg)
+	}()
+
+	alert := testAlert()
+	alert.Hit.Client = `Microsoft Office Word "2016" [x]`
+	n = &SyslogNotifier{Network: "tcp", Addr: l.Addr().String(), Facility: 10, Severity: 1}
+	if err = n.Notify(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-received
+	if !strings.HasPrefix(msg, "<81>1 ") || !strings.Contains(msg, `client="Microsoft Office Word \"2016\" [x\]"`) {
+		t.Errorf("tcp message = %q", msg)
+	}
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	methodPropfind = "PROPFIND"
+
+	// davAllow 只读的 WebDAV 服务支持的方法
+	davAllow = "OPTIONS, GET, HEAD, PROPFIND"
+)
+
+// writeDAVOptions 应答 OPTIONS
+// Word 打开远程模板前发送 OPTIONS 判断服务是否支持 WebDAV，返回 404 或 405 时 Office 可能长时间等待或弹出错误提示；
+// 声明只读的 WebDAV 1 级服务后 Office 直接继续 PROPFIND、GET
+func writeDAVOptions(w http.ResponseWriter) {
+	w.Header().Set("Allow", davAllow)
+	w.Header().Set("DAV", "1")
+	w.Header().Set("MS-Author-Via", "DAV")
+	w.Header().Set("Content-Length", "0")
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeDAVPropfind 应答 PROPFIND，不解析请求中的属性，总是返回全部属性
+// 包含 token 的路径为文件（大小与 GET 返回的 gif 一致），其余路径为空目录；Depth 为 1 时也只返回目录本身
+func writeDAVPropfind(w http.ResponseWriter, r *http.Request, file bool, now time.Time) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(r.Body, 1<<16))
+
+	// 修改时间使用前一天，避免与请求时间相同
+	var (
+		href     = r.URL.EscapedPath()
+		modified = now.Add(-24 * time.Hour).UTC()
+		prop     = davProp{
+			DisplayName:  path.Base("/" + strings.Trim(r.URL.Path, "/")),
+			CreationDate: modified.Format(time.RFC3339),
+			LastModified: modified.Format(http.TimeFormat),
+		}
+	)
+	if file {
+		prop.ContentLength = strconv.Itoa(len(transparentGIF))
+		prop.ContentType = "image/gif"
+	} else {
+		prop.ResourceType.Collection = &struct{}{}
+		if !strings.HasSuffix(href, "/") {
+			href += "/"
+		}
+	}
+
+	b, err := xml.Marshal(davMultistatus{
+		Response: davResponse{
+			Href:     href,
+			Propstat: davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"},
+		},
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(b)))
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(b)
+}
+
+// davMultistatus PROPFIND 应答，RFC 4918
+type davMultistatus struct {
+	XMLName  xml.Name    `xml:"DAV: multistatus"`
+	Response davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href     string      `xml:"href"`
+	Propstat davPropstat `xml:"propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"prop"`
+	Status string  `xml:"status"`
+}
+
+type davProp struct {
+	DisplayName  string `xml:"displayname"`
+	ResourceType struct {
+		Collection *struct{} `xml:"collection"`
+	} `xml:"resourcetype"`
+	ContentLength string `xml:"getcontentlength,omitempty"`
+	ContentType   string `xml:"getcontenttype,omitempty"`
+	CreationDate  string `xml:"creationdate"`
+	LastModified  string `xml:"getlastmodified"`
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// davResult PROPFIND 应答中检查的字段
+type davResult struct {
+	Href       string    `xml:"response>href"`
+	Status     string    `xml:"response>propstat>status"`
+	Collection *struct{} `xml:"response>propstat>prop>resourcetype>collection"`
+	Length     string    `xml:"response>propstat>prop>getcontentlength"`
+}
+
+func TestTraceHandlerWebDAV(t *testing.T) {
+	const tok = "abc234abc234abcd"
+	hits, recorder := testHits()
+	server := httptest.NewServer(&TraceHandler{Fingerprinter: &Fingerprinter{}, Recorder: recorder})
+	defer server.Close()
+
+	// Word 打开远程模板的请求序列
+	steps := []struct {
+		method   string
+		path     string
+		ua       string
+		depth    string
+		wantCode int
+		check    func(resp *http.Response, body []byte) bool
+	}{
+		{http.MethodOptions, "/t/", "Microsoft Office Protocol Discovery", "", http.StatusOK, func(resp *http.Response, body []byte) bool {
+			return resp.Header.Get("DAV") == "1" && resp.Header.Get("MS-Author-Via") == "DAV" &&
+				strings.Contains(resp.Header.Get("Allow"), "PROPFIND") && len(body) == 0
+		}},
+		{methodPropfind, "/t", "Microsoft-WebDAV-MiniRedir/10.0.19045", "1", http.StatusMultiStatus, func(_ *http.Response, body []byte) bool {
+			var result davResult
+			return xml.Unmarshal(body, &result) == nil && result.Href == "/t/" && result.Collection != nil && result.Status == "HTTP/1.1 200 OK"
+		}},
+		{methodPropfind, "/t/" + tok, "Microsoft-WebDAV-MiniRedir/10.0.19045", "0", http.StatusMultiStatus, func(resp *http.Response, body []byte) bool {
+			var result davResult
+			return xml.Unmarshal(body, &result) == nil && result.Href == "/t/"+tok && result.Collection == nil &&
+				result.Length == "43" && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/xml")
+		}},
+		{http.MethodHead, "/t/" + tok, "Microsoft Office Existence Discovery", "", http.StatusOK, func(resp *http.Response, _ []byte) bool {
+			return resp.Header.Get("Content-Type") == "image/gif"
+		}},
+		{http.MethodGet, "/t/" + tok, "Microsoft Office Word 2014", "", http.StatusOK, func(_ *http.Response, body []byte) bool {
+			return string(body) == string(transparentGIF)
+		}},
+		{"LOCK", "/t/" + tok, "Microsoft Office Word 2014", "", http.StatusMethodNotAllowed, func(resp *http.Response, _ []byte) bool {
+			return resp.Header.Get("Allow") == davAllow
+		}},
+	}
+
+	start := time.Now()
+	for _, step := range steps {
+		req, err := http.NewRequest(step.method, server.URL+step.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", step.ua)
+		if step.depth != "" {
+			req.Header.Set("Depth", step.depth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != step.wantCode || !step.check(resp, body) {
+			t.Errorf("%s %s = %d %v %s", step.method, step.path, resp.StatusCode, resp.Header, body)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("elapsed = %v", elapsed)
+	}
+
+	// 每个请求都有记录，没有 token 的探测请求 token 为空
+	if len(hits) != len(steps) {
+		t.Fatalf("hits = %d, want %d", len(hits), len(steps))
+	}
+	for i, step := range steps {
+		hit := <-hits
+		wantToken := tok
+		if !strings.Contains(step.path, tok) {
+			wantToken = ""
+		}
+		if hit.Query != step.method+" "+step.path || hit.Token != wantToken {
+			t.Errorf("hit %d = %+v", i, hit)
+		}
+		if i == len(steps)-1 && (hit.App != AppWord || hit.OS != "Windows 10") {
+			t.Errorf("fingerprint = %s %s", hit.App, hit.OS)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 即时通讯机器人类型
+const (
+	ChatDingTalk = "dingtalk"
+	ChatWeCom    = "wecom"
+	ChatFeishu   = "feishu"
+)
+
+var ErrChat = errors.New("unsupported chat type")
+
+// WebhookNotifier 以 JSON 格式 POST 告警内容
+type WebhookNotifier struct {
+	URL    string
+	Header http.Header  // 额外的请求头，例如 Authorization
+	Client *http.Client // 为空时使用 http.DefaultClient
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	_, err = postJSON(ctx, n.Client, n.URL, n.Header, body)
+	return err
+}
+
+// ChatNotifier 通过钉钉、企业微信、飞书群机器人的 webhook 发送文本告警
+// 钉钉、飞书设置了签名校验时需要配置 Secret，企业微信不支持签名
+type ChatNotifier struct {
+	Type   string // dingtalk、wecom、feishu
+	URL    string // 机器人 webhook 地址
+	Secret string // 签名密钥，可选
+	Client *http.Client
+}
+
+func (n *ChatNotifier) Notify(ctx context.Context, alert *Alert) error {
+	var (
+		text      = alert.Subject() + "\n" + alert.Text()
+		now       = time.Now()
+		webhook   = n.URL
+		msg       map[string]any
+		errorCode string
+	)
+
+	// 1、根据机器人类型生成消息及签名
+	switch n.Type {
+	case ChatDingTalk, ChatWeCom:
+		msg = map[string]any{"msgtype": "text", "text": map[string]string{"content": text}}
+		errorCode = "errcode"
+		if n.Type == ChatDingTalk && n.Secret != "" {
+			timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+			h := hmac.New(sha256.New, []byte(n.Secret))
+			h.Write([]byte(timestamp + "\n" + n.Secret))
+			u, err := url.Parse(webhook)
+			if err != nil {
+				return err
+			}
+			query := u.Query()
+			query.Set("timestamp", timestamp)
+			query.Set("sign", base64.StdEncoding.EncodeToString(h.Sum(nil)))
+			u.RawQuery = query.Encode()
+			webhook = u.String()
+		}
+	case ChatFeishu:
+		msg = map[string]any{"msg_type": "text", "content": map[string]string{"text": text}}
+		errorCode = "code"
+		if n.Secret != "" {
+			timestamp := strconv.FormatInt(now.Unix(), 10)
+			h := hmac.New(sha256.New, []byte(timestamp+"\n"+n.Secret))
+			msg["timestamp"] = timestamp
+			msg["sign"] = base64.StdEncoding.EncodeToString(h.Sum(nil))
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrChat, n.Type)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	resp, err := postJSON(ctx, n.Client, webhook, nil, body)
+	if err != nil {
+		return err
+	}
+
+	// 2、HTTP 状态码为 200 时依然可能失败，例如签名错误、触发频率限制
+	var result map[string]any
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return fmt.Errorf("%s response: %w", n.Type, err)
+	}
+	if code, ok := result[errorCode].(float64); ok && code != 0 {
+		return fmt.Errorf("%s response: %s", n.Type, resp)
+	}
+	return nil
+}
+
+// postJSON 发送 JSON 请求，状态码不是 2xx 时返回错误
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
@@ -0,0 +1,81 @@
+package credential
+
+import (
+	"path/filepath"
+	"strings"
+
+	"tracer/internal/token"
+)
+
+const awsCredentialsTemp = `[default]
+aws_access_key_id = ${key}
+aws_secret_access_key = ${secret}
+`
+
+const awsSessionTokenTemp = `aws_session_token = ${session}
+`
+
+const awsConfigTemp = `[default]
+region = ${region}
+output = json
+endpoint_url = ${endpoint}
+`
+
+// AccessKeyId 将 token 编码为 AWS access key id（AKIA + 16 位大写字母和数字，共 20 位）
+func AccessKeyId(tok string) string {
+	return "AKIA" + strings.ToUpper(tok)
+}
+
+// SessionAccessKeyId 将 token 编码为临时凭据的 access key id（ASIA + 16 位大写字母和数字），与 session token 一起使用
+func SessionAccessKeyId(tok string) string {
+	return "ASIA" + strings.ToUpper(tok)
+}
+
+// FromAccessKeyId 从 access key id 中解析 token，支持长期凭据（AKIA）和临时凭据（ASIA）
+func FromAccessKeyId(key string) (string, bool) {
+	if len(key) != 20 || !strings.HasPrefix(key, "AKIA") && !strings.HasPrefix(key, "ASIA") || !token.Valid(key[4:]) {
+		return "", false
+	}
+	return strings.ToLower(key[4:]), true
+}
+
+// GenAWSCredentials 生成 AWS 凭据文件（~/.aws/credentials），access key id 中包含 token
+// endpoint 不为空时在同一目录生成 config，将 endpoint_url 指向 collector 的 S3 服务，
+// 攻击者使用凭据调用 AWS CLI/SDK 时会把 access key id 发送到 collector
+// 不使用 endpoint 时只能通过 CloudTrail 等方式发现 access key id 被使用
+// access key id 长度固定，不能写入签名，signer 不为 nil 时生成临时凭据，aws_session_token 为签名后的 token，
+// AWS CLI/SDK 在请求头 X-Amz-Security-Token 中发送
+func GenAWSCredentials(dstFile, endpoint string, reg *token.Registry, signer token.Signer) (tok string, err error) {
+	tok, err = mint(reg, KindAWS, dstFile)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		temp = awsCredentialsTemp
+		key  = AccessKeyId(tok)
+		vars []string
+	)
+	if session := token.Encode(signer, tok); session != tok {
+		temp += awsSessionTokenTemp
+		key = SessionAccessKeyId(tok)
+		vars = append(vars, "${session}", session)
+	}
+	vars = append(vars, "${key}", key, "${secret}", randomString(40, secretChars))
+
+	err = writeFile(dstFile, temp, vars...)
+	if err != nil {
+		return "", err
+	}
+	if endpoint == "" {
+		return tok, nil
+	}
+
+	err = writeFile(filepath.Join(filepath.Dir(dstFile), "config"), awsConfigTemp,
+		"${region}", "us-east-1",
+		"${endpoint}", endpoint)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
@@ -0,0 +1,59 @@
+package credential
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+
+	"tracer/internal/token"
+)
+
+// 生成的凭据类型，登记到 token 注册表
+const (
+	KindAWS        = "aws"
+	KindKubeconfig = "kubeconfig"
+	KindEnv        = "env"
+	KindGit        = "git"
+	KindSSH        = "ssh"
+)
+
+var ErrRegistry = errors.New("token registry is required")
+
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz0123456789"
+	secretChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+)
+
+// mint 在注册表中登记新的 token，memo 为生成的文件路径
+func mint(reg *token.Registry, kind, dstFile string) (string, error) {
+	if reg == nil {
+		return "", ErrRegistry
+	}
+	record, err := reg.Mint(kind, dstFile)
+	if err != nil {
+		return "", err
+	}
+	return record.Token, nil
+}
+
+// randomString 生成随机字符串，用于密码、secret 等不需要追踪的字段
+func randomString(n int, chars string) string {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(chars)))
+	for i := range b {
+		v, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = chars[v.Int64()]
+	}
+	return string(b)
+}
+
+// writeFile 替换模板中的变量后写入文件，凭据文件只允许当前用户读写
+func writeFile(dstFile, temp string, vars ...string) error {
+	content := strings.NewReplacer(vars...).Replace(temp)
+	return os.WriteFile(dstFile, []byte(content), 0600)
+}
@@ -0,0 +1,244 @@
+package credential
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/token"
+)
+
+func readFile(t *testing.T, filename string) string {
+	t.Helper()
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// checkRecord 检查 token 已登记到注册表
+func checkRecord(t *testing.T, reg *token.Registry, tok, kind, dstFile string) {
+	t.Helper()
+	record, ok := reg.Lookup(tok)
+	if !ok || record.Kind != kind || record.Memo != dstFile {
+		t.Errorf("Lookup(%s) = %v, %v", tok, record, ok)
+	}
+}
+
+func newRegistry(t *testing.T) *token.Registry {
+	reg, err := token.OpenRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
+
+func TestGenAWSCredentials(t *testing.T) {
+	reg := newRegistry(t)
+	dstFile := filepath.Join(t.TempDir(), "credentials")
+	tok, err := GenAWSCredentials(dstFile, "http://10.0.0.1:9000", reg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecord(t, reg, tok, KindAWS, dstFile)
+
+	content := readFile(t, dstFile)
+	key := AccessKeyId(tok)
+	if len(key) != 20 || !strings.Contains(content, "aws_access_key_id = "+key+"\n") {
+		t.Errorf("credentials = %s", content)
+	}
+	if got, ok := FromAccessKeyId(key); !ok || got != tok || strings.Contains(content, "aws_session_token") {
+		t.Errorf("FromAccessKeyId() = %s, %v", got, ok)
+	}
+	config := readFile(t, filepath.Join(filepath.Dir(dstFile), "config"))
+	if !strings.Contains(config, "endpoint_url = http://10.0.0.1:9000\n") {
+		t.Errorf("config = %s", config)
+	}
+}
+
+func TestGenKubeconfig(t *testing.T) {
+	reg := newRegistry(t)
+	dstFile := filepath.Join(t.TempDir(), "config")
+	tok, err := GenKubeconfig(dstFile, "https://10.0.0.1:6443", reg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecord(t, reg, tok, KindKubeconfig, dstFile)
+
+	content := readFile(t, dstFile)
+	if !strings.Contains(content, "    server: https://10.0.0.1:6443\n") {
+		t.Errorf("kubeconfig = %s", content)
+	}
+	i := strings.Index(content, "    token: ")
+	if i < 0 {
+		t.Fatalf("kubeconfig = %s", content)
+	}
+	bearer := strings.TrimSpace(content[i+len("    token: "):])
+	if got, ok := FromBearerToken(bearer); !ok || got != tok {
+		t.Errorf("FromBearerToken(%s) = %s, %v", bearer, got, ok)
+	}
+}
+
+func TestGenEnv(t *testing.T) {
+	tests := []struct {
+		driver string
+		host   string
+		want   string
+	}{
+		{"mysql", "10.0.0.1", "mysql://app_${token}:"},
+		{"postgres", "10.0.0.1:15432", "postgresql://app_${token}:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			reg := newRegistry(t)
+			dstFile := filepath.Join(t.TempDir(), ".env")
+			tok, err := GenEnv(dstFile, tt.driver, tt.host, reg, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRecord(t, reg, tok, KindEnv, dstFile)
+
+			content := readFile(t, dstFile)
+			if !strings.Contains(content, strings.Replace(tt.want, "${token}", tok, 1)) ||
+				!strings.Contains(content, "DB_HOST=10.0.0.1\n") {
+				t.Errorf(".env = %s", content)
+			}
+			if got, ok := FromDBUser(DBUser(tok)); !ok || got != tok {
+				t.Errorf("FromDBUser() = %s, %v", got, ok)
+			}
+		})
+	}
+
+	_, err := GenEnv(filepath.Join(t.TempDir(), ".env"), "oracle", "10.0.0.1", newRegistry(t), nil)
+	if !errors.Is(err, ErrDriver) {
+		t.Errorf("GenEnv(oracle) error = %v", err)
+	}
+}
+
+func TestGenGitConfig(t *testing.T) {
+	reg := newRegistry(t)
+	dstFile := filepath.Join(t.TempDir(), "config")
+	tok, err := GenGitConfig(dstFile, "https://git.example.com/t", reg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecord(t, reg, tok, KindGit, dstFile)
+
+	content := readFile(t, dstFile)
+	if !strings.Contains(content, "\turl = https://git.example.com/t/"+tok+"/infra.git\n") {
+		t.Errorf("git config = %s", content)
+	}
+}
+
+func TestGenSSHConfig(t *testing.T) {
+	reg := newRegistry(t)
+	dstFile := filepath.Join(t.TempDir(), "config")
+	tok, err := GenSSHConfig(dstFile, "canary.example.com", reg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecord(t, reg, tok, KindSSH, dstFile)
+
+	content := readFile(t, dstFile)
+	if !strings.Contains(content, "    HostName "+tok+".canary.example.com\n") {
+		t.Errorf("ssh config = %s", content)
+	}
+	if strings.Contains(strings.ToLower(content), "proxycommand") {
+		t.Errorf("ssh config contains ProxyCommand: %s", content)
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	_, err := GenSSHConfig(filepath.Join(t.TempDir(), "config"), "canary.example.com", nil, nil)
+	if !errors.Is(err, ErrRegistry) {
+		t.Errorf("GenSSHConfig(nil) error = %v", err)
+	}
+}
+
+// checkSigned 检查凭据中签名后的 token 可以通过校验
+func checkSigned(t *testing.T, k *token.Keyring, value, tok string) {
+	t.Helper()
+	got, err := k.Verify(value)
+	if err != nil || got != tok {
+		t.Errorf("Verify(%s) = %s, %v", value, got, err)
+	}
+}
+
+func TestSignedCredentials(t *testing.T) {
+	k, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	// 1、kubeconfig: bearer token 的 secret 部分
+	reg := newRegistry(t)
+	tok, err := GenKubeconfig(filepath.Join(dir, "kubeconfig"), "https://10.0.0.1:6443", reg, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := readFile(t, filepath.Join(dir, "kubeconfig"))
+	bearer := strings.TrimSpace(content[strings.Index(content, "    token: ")+len("    token: "):])
+	value, ok := FromBearerToken(bearer)
+	if !ok {
+		t.Fatalf("FromBearerToken(%s) = %v", bearer, ok)
+	}
+	checkSigned(t, k, value, tok)
+
+	// 2、.env: 数据库用户名
+	tok, err = GenEnv(filepath.Join(dir, ".env"), "postgres", "10.0.0.1", reg, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = readFile(t, filepath.Join(dir, ".env"))
+	user := strings.TrimSpace(content[strings.Index(content, "DB_USERNAME=")+len("DB_USERNAME="):])
+	user, _, _ = strings.Cut(user, "\n")
+	if value, ok = FromDBUser(user); !ok {
+		t.Fatalf("FromDBUser(%s) = %v", user, ok)
+	}
+	checkSigned(t, k, value, tok)
+
+	// 3、AWS: 临时凭据，session token 为签名后的 token
+	awsDir := filepath.Join(dir, "aws")
+	if err = os.Mkdir(awsDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	tok, err = GenAWSCredentials(filepath.Join(awsDir, "credentials"), "", reg, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = readFile(t, filepath.Join(awsDir, "credentials"))
+	if !strings.Contains(content, "aws_access_key_id = "+SessionAccessKeyId(tok)+"\n") {
+		t.Errorf("credentials = %s", content)
+	}
+	session := strings.TrimSpace(content[strings.Index(content, "aws_session_token = ")+len("aws_session_token = "):])
+	checkSigned(t, k, session, tok)
+
+	// 4、git: remote 地址的路径
+	tok, err = GenGitConfig(filepath.Join(dir, "gitconfig"), "https://git.example.com/t", reg, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = readFile(t, filepath.Join(dir, "gitconfig"))
+	i := strings.Index(content, "https://git.example.com")
+	value, ok = token.FromPath(strings.TrimPrefix(strings.Fields(content[i:])[0], "https://git.example.com"))
+	if !ok {
+		t.Fatalf("git config = %s", content)
+	}
+	checkSigned(t, k, value, tok)
+
+	// 5、ssh: HostName 的子域名
+	tok, err = GenSSHConfig(filepath.Join(dir, "sshconfig"), "canary.example.com", reg, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = readFile(t, filepath.Join(dir, "sshconfig"))
+	host := strings.Fields(content[strings.Index(content, "HostName "):])[1]
+	if value, ok = token.FromSubdomain(host, "canary.example.com"); !ok {
+		t.Fatalf("ssh config = %s", content)
+	}
+	checkSigned(t, k, value, tok)
+}
@@ -0,0 +1,75 @@
+package credential
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"tracer/internal/token"
+)
+
+const envTemp = `# ${driver} production database
+DB_CONNECTION=${driver}
+DB_HOST=${host}
+DB_PORT=${port}
+DB_DATABASE=${database}
+DB_USERNAME=${user}
+DB_PASSWORD=${password}
+DATABASE_URL=${scheme}://${user}:${password}@${host}:${port}/${database}
+`
+
+var ErrDriver = errors.New("unsupported database driver")
+
+// 数据库默认端口和连接字符串的协议
+var drivers = map[string][2]string{
+	"mysql":    {"3306", "mysql"},
+	"postgres": {"5432", "postgresql"},
+}
+
+// DBUser 将 token 编码为数据库用户名，value 可以是签名后的 token
+func DBUser(value string) string {
+	return "app_" + value
+}
+
+// FromDBUser 从数据库用户名中解析 token，签名后的 token 原样返回，需要使用 Signer.Verify 校验
+func FromDBUser(user string) (string, bool) {
+	value, ok := strings.CutPrefix(strings.ToLower(user), "app_")
+	if !ok || !token.Valid(value) && !token.Signed(value) {
+		return "", false
+	}
+	return value, true
+}
+
+// GenEnv 生成 .env 文件，数据库连接字符串指向 collector 的 MySQL/PostgreSQL 服务
+// 客户端连接时在握手中发送包含 token 的用户名
+// driver: mysql、postgres
+// host: collector 地址，可以带端口，不带端口时使用默认端口
+// signer 不为 nil 时用户名中使用签名后的 token
+func GenEnv(dstFile, driver, host string, reg *token.Registry, signer token.Signer) (tok string, err error) {
+	d, ok := drivers[driver]
+	if !ok {
+		return "", ErrDriver
+	}
+	port := d[0]
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host, port = h, p
+	}
+
+	tok, err = mint(reg, KindEnv, dstFile)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFile(dstFile, envTemp,
+		"${driver}", driver,
+		"${scheme}", d[1],
+		"${host}", host,
+		"${port}", port,
+		"${database}", "prod",
+		"${user}", DBUser(token.Encode(signer, tok)),
+		"${password}", randomString(24, lowerChars))
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
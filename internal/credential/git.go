@@ -0,0 +1,62 @@
+package credential
+
+import (
+	"tracer/internal/token"
+)
+
+const gitConfigTemp = `[core]
+	repositoryformatversion = 0
+	filemode = true
+	bare = false
+	logallrefupdates = true
+[remote "origin"]
+	url = ${url}
+	fetch = +refs/heads/*:refs/remotes/origin/*
+[branch "main"]
+	remote = origin
+	merge = refs/heads/main
+`
+
+// ssh 配置不使用 ProxyCommand、LocalCommand 等会在连接时执行命令的选项
+const sshConfigTemp = `Host ${alias}
+    HostName ${host}
+    User ${user}
+    Port 22
+    IdentityFile ~/.ssh/id_ed25519_${alias}
+    StrictHostKeyChecking accept-new
+`
+
+// GenGitConfig 生成 git 仓库配置（.git/config），remote 地址的路径中包含 token
+// 执行 git fetch/pull 时访问 <remoteBase>/<token>/infra.git/info/refs
+// signer 不为 nil 时路径中使用签名后的 token
+func GenGitConfig(dstFile, remoteBase string, reg *token.Registry, signer token.Signer) (tok string, err error) {
+	tok, err = mint(reg, KindGit, dstFile)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFile(dstFile, gitConfigTemp, "${url}", token.URL(signer, remoteBase, tok)+"/infra.git")
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// GenSSHConfig 生成 ssh 配置（~/.ssh/config），HostName 为包含 token 的追踪域名子域名
+// 执行 ssh 时解析域名，collector 的 DNS 服务记录查询
+// signer 不为 nil 时子域名使用签名后的 token
+func GenSSHConfig(dstFile, domain string, reg *token.Registry, signer token.Signer) (tok string, err error) {
+	tok, err = mint(reg, KindSSH, dstFile)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFile(dstFile, sshConfigTemp,
+		"${alias}", "bastion",
+		"${host}", token.Subdomain(signer, tok, domain),
+		"${user}", "ops")
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
@@ -0,0 +1,68 @@
+package credential
+
+import (
+	"strings"
+
+	"tracer/internal/token"
+)
+
+const kubeconfigTemp = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    insecure-skip-tls-verify: true
+    server: ${server}
+  name: ${cluster}
+contexts:
+- context:
+    cluster: ${cluster}
+    namespace: default
+    user: ${user}
+  name: ${user}@${cluster}
+current-context: ${user}@${cluster}
+preferences: {}
+users:
+- name: ${user}
+  user:
+    token: ${token}
+`
+
+// BearerToken 将 token 编码为 bootstrap token 格式（6 位 id + "." + 16 位 secret）
+// value 为签名后的 token 时 secret 部分为签名后的 token
+func BearerToken(value string) string {
+	return randomString(6, lowerChars) + "." + value
+}
+
+// FromBearerToken 从 bootstrap token 中解析 token，签名后的 token 原样返回，需要使用 Signer.Verify 校验
+func FromBearerToken(bearer string) (string, bool) {
+	i := strings.LastIndex(bearer, ".")
+	if i < 0 {
+		return "", false
+	}
+	value := strings.ToLower(bearer[i+1:])
+	if !token.Valid(value) && !token.Signed(value) {
+		return "", false
+	}
+	return value, true
+}
+
+// GenKubeconfig 生成 kubeconfig，server 指向 collector 的 Kubernetes API 服务
+// kubectl 会在请求头 Authorization: Bearer 中发送包含 token 的凭据
+// 跳过证书校验，collector 可以使用自签名证书
+// signer 不为 nil 时 bearer token 中使用签名后的 token
+func GenKubeconfig(dstFile, server string, reg *token.Registry, signer token.Signer) (tok string, err error) {
+	tok, err = mint(reg, KindKubeconfig, dstFile)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFile(dstFile, kubeconfigTemp,
+		"${server}", server,
+		"${cluster}", "prod-cluster",
+		"${user}", "cluster-admin",
+		"${token}", BearerToken(token.Encode(signer, tok)))
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
@@ -0,0 +1,345 @@
+package detect
+
+import (
+	"archive/zip"
+	"html"
+	"io"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	ms_office "tracer/internal/ms-office"
+	"tracer/internal/token"
+
+	"github.com/beevik/etree"
+)
+
+// Severity 发现的可能性
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"   // 常见检测工具默认规则即可发现
+	SeverityMedium Severity = "medium" // 检查外部资源时可以发现
+	SeverityLow    Severity = "low"    // 分析人员对比 XML 时可以发现
+)
+
+// Finding 检测结果
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Part     string   `json:"part"`   // 包内路径，例如 word/_rels/settings.xml.rels
+	Detail   string   `json:"detail"` // 匹配的内容，例如地址、Id
+}
+
+// Rule 检测规则
+type Rule struct {
+	Name        string
+	Severity    Severity
+	Description string
+	check       func(p *officePackage) []Finding
+}
+
+// officePackage 解压后的文件，包内路径 => 内容
+type officePackage struct {
+	parts map[string]string
+	names []string
+}
+
+const (
+	relTypeBase     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	relTemplate     = relTypeBase + "attachedTemplate"
+	relImage        = relTypeBase + "image"
+	relFrame        = relTypeBase + "frame"
+	relSubDocument  = relTypeBase + "subDocument"
+	relExternalPath = relTypeBase + "externalLinkPath"
+	relOleObject    = relTypeBase + "oleObject"
+)
+
+var (
+	urlPattern      = regexp.MustCompile(`(?i)\b(?:https?|file)://[^\s"'<>()]+`)
+	attrUrlPattern  = regexp.MustCompile(`(?i)="((?:https?|file)://[^"]+)"|WEBSERVICE\("((?:https?|file)://[^"]+)"`)
+	tinyPattern     = regexp.MustCompile(`<(?:a:ext|wp:extent|xdr:ext)\s+cx="1"\s+cy="1"`)
+	templatePattern = regexp.MustCompile(`name="(?:Content Placeholder X|Image \d+)"`)
+)
+
+// namespaceHosts XML 命名空间使用的域名，不属于外部资源
+var namespaceHosts = []string{
+	"schemas.openxmlformats.org", "schemas.microsoft.com", "purl.org", "www.w3.org",
+	"ns.adobe.com", "openoffice.org", "docs.oasis-open.org", "schemas.libreoffice.org",
+}
+
+// KnownDomains 常见追踪服务、OAST 平台、请求记录服务的域名
+var KnownDomains = []string{
+	"canarytokens.com", "canarytokens.org", "canarytokens.net", "thinkst.com",
+	"interact.sh", "oast.fun", "oast.live", "oast.me", "oast.online", "oast.pro", "oast.site",
+	"burpcollaborator.net", "oastify.com", "dnslog.cn", "ceye.io", "requestbin.net",
+	"webhook.site", "pipedream.net", "ngrok.io", "ngrok-free.app", "ngrok.app",
+}
+
+// Rules 内置检测规则，参考开源的 canarytoken 检测工具
+var Rules = []*Rule{
+	{
+		Name:        "remote-template",
+		Severity:    SeverityHigh,
+		Description: "attachedTemplate 指向远程地址或 UNC 路径",
+		check:       relsRule(relTemplate),
+	},
+	{
+		Name:        "external-frame",
+		Severity:    SeverityHigh,
+		Description: "外部框架、子文档、OLE 对象",
+		check:       relsRule(relFrame, relSubDocument, relOleObject),
+	},
+	{
+		Name:        "known-domain",
+		Severity:    SeverityHigh,
+		Description: "地址为常见追踪服务或 OAST 平台的域名",
+		check:       checkKnownDomain,
+	},
+	{
+		Name:        "external-image",
+		Severity:    SeverityMedium,
+		Description: "图片关系的 TargetMode 为 External",
+		check:       relsRule(relImage),
+	},
+	{
+		Name:        "external-data",
+		Severity:    SeverityMedium,
+		Description: "外部工作簿链接、Web 查询数据连接、WEBSERVICE 公式",
+		check:       checkExternalData,
+	},
+	{
+		Name:        "technique-label",
+		Severity:    SeverityMedium,
+		Description: "外部地址的路径或查询参数中包含追踪技术名称，例如 v=docx-template",
+		check:       checkTechniqueLabel,
+	},
+	{
+		Name:        "ip-host",
+		Severity:    SeverityLow,
+		Description: "外部地址的主机为 IP 地址",
+		check:       checkIPHost,
+	},
+	{
+		Name:        "token-url",
+		Severity:    SeverityLow,
+		Description: "外部地址的路径、子域名或查询参数中包含 token 格式的字符串",
+		check:       checkTokenUrl,
+	},
+	{
+		Name:        "fixed-id",
+		Severity:    SeverityLow,
+		Description: "关系 Id 不连续，例如 rId9999",
+		check:       checkFixedId,
+	},
+	{
+		Name:        "tiny-image",
+		Severity:    SeverityLow,
+		Description: "图片尺寸为 1 EMU，或使用模板中的名称",
+		check:       checkTinyImage,
+	},
+}
+
+// Scan 使用内置规则检测 office、opendocument 文件
+func Scan(filename string) ([]Finding, error) {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	p := &officePackage{parts: make(map[string]string)}
+	for _, f := range r.File {
+		ext := path.Ext(f.Name)
+		if ext != ".xml" && ext != ".rels" && ext != ".vml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		p.parts[f.Name] = string(data)
+		p.names = append(p.names, f.Name)
+	}
+	sort.Strings(p.names)
+
+	var findings []Finding
+	for _, rule := range Rules {
+		for _, finding := range rule.check(p) {
+			finding.Rule = rule.Name
+			finding.Severity = rule.Severity
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
+
+// relsRule 检查指定类型的外部关系
+func relsRule(relTypes ...string) func(p *officePackage) []Finding {
+	return func(p *officePackage) (findings []Finding) {
+		p.eachRel(func(part string, rel *etree.Element) {
+			if rel.SelectAttrValue("TargetMode", "") != "External" {
+				return
+			}
+			for _, relType := range relTypes {
+				if rel.SelectAttrValue("Type", "") == relType {
+					findings = append(findings, Finding{Part: part, Detail: rel.SelectAttrValue("Target", "")})
+				}
+			}
+		})
+		return findings
+	}
+}
+
+func checkExternalData(p *officePackage) []Finding {
+	findings := relsRule(relExternalPath)(p)
+	for _, name := range p.names {
+		content := p.parts[name]
+		if path.Base(name) == "connections.xml" {
+			for _, u := range urlPattern.FindAllString(html.UnescapeString(content), -1) {
+				findings = append(findings, Finding{Part: name, Detail: u})
+			}
+		}
+		if strings.Contains(content, "WEBSERVICE(") {
+			findings = append(findings, Finding{Part: name, Detail: "WEBSERVICE"})
+		}
+	}
+	return findings
+}
+
+func checkKnownDomain(p *officePackage) (findings []Finding) {
+	p.eachUrl(func(part string, u *url.URL) {
+		host := strings.ToLower(u.Hostname())
+		for _, domain := range KnownDomains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				findings = append(findings, Finding{Part: part, Detail: u.String()})
+				return
+			}
+		}
+	})
+	return findings
+}
+
+func checkIPHost(p *officePackage) (findings []Finding) {
+	p.eachUrl(func(part string, u *url.URL) {
+		if net.ParseIP(u.Hostname()) != nil {
+			findings = append(findings, Finding{Part: part, Detail: u.String()})
+		}
+	})
+	return findings
+}
+
+func checkTokenUrl(p *officePackage) (findings []Finding) {
+	p.eachUrl(func(part string, u *url.URL) {
+		elems := strings.Split(strings.Trim(u.Path, "/"), "/")
+		elems = append(elems, strings.Split(u.Hostname(), ".")...)
+		for _, value := range u.Query() {
+			elems = append(elems, value...)
+		}
+		for _, elem := range elems {
+			if tokenString(elem) {
+				findings = append(findings, Finding{Part: part, Detail: elem})
+				return
+			}
+		}
+	})
+	return findings
+}
+
+func checkTechniqueLabel(p *officePackage) (findings []Finding) {
+	p.eachUrl(func(part string, u *url.URL) {
+		elems := strings.Split(strings.Trim(u.Path, "/"), "/")
+		for _, value := range u.Query() {
+			elems = append(elems, value...)
+		}
+		for _, elem := range elems {
+			if _, ok := ms_office.LookupTechnique(strings.ToLower(elem)); ok {
+				findings = append(findings, Finding{Part: part, Detail: elem})
+				return
+			}
+		}
+	})
+	return findings
+}
+
+func checkFixedId(p *officePackage) (findings []Finding) {
+	p.eachRel(func(part string, rel *etree.Element) {
+		id := rel.SelectAttrValue("Id", "")
+		if n, err := strconv.Atoi(strings.TrimPrefix(id, "rId")); err == nil && n >= 1000 {
+			findings = append(findings, Finding{Part: part, Detail: id})
+		}
+	})
+	return findings
+}
+
+func checkTinyImage(p *officePackage) (findings []Finding) {
+	for _, name := range p.names {
+		for _, match := range tinyPattern.FindAllString(p.parts[name], -1) {
+			findings = append(findings, Finding{Part: name, Detail: match})
+		}
+		for _, match := range templatePattern.FindAllString(p.parts[name], -1) {
+			findings = append(findings, Finding{Part: name, Detail: match})
+		}
+	}
+	return findings
+}
+
+// tokenString 判断是否符合 token 格式：16 位 base32 字符（a-z、2-7），或签名后的 <kid>-<token>-<mac>
+func tokenString(s string) bool {
+	return token.Valid(s) || token.Signed(s)
+}
+
+// eachRel 遍历全部关系
+func (p *officePackage) eachRel(fn func(part string, rel *etree.Element)) {
+	for _, name := range p.names {
+		if path.Ext(name) != ".rels" {
+			continue
+		}
+		document := etree.NewDocument()
+		if err := document.ReadFromString(p.parts[name]); err != nil {
+			continue
+		}
+		relationships := document.SelectElement("Relationships")
+		if relationships == nil {
+			continue
+		}
+		for _, rel := range relationships.SelectElements("Relationship") {
+			fn(name, rel)
+		}
+	}
+}
+
+// eachUrl 遍历属性和 WEBSERVICE 公式中的外部地址，不包括 XML 命名空间和正文中的文本
+func (p *officePackage) eachUrl(fn func(part string, u *url.URL)) {
+	for _, name := range p.names {
+		for _, match := range attrUrlPattern.FindAllStringSubmatch(html.UnescapeString(p.parts[name]), -1) {
+			u, err := url.Parse(match[1] + match[2])
+			if err != nil || namespaceHost(u.Hostname()) {
+				continue
+			}
+			fn(name, u)
+		}
+	}
+}
+
+// namespaceHost 判断是否为 XML 命名空间使用的域名
+func namespaceHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range namespaceHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
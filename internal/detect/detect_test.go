@@ -0,0 +1,175 @@
+package detect
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fmt"
+	"tracer/internal/lure"
+	ms_office "tracer/internal/ms-office"
+)
+
+// rules 获取触发的规则名称
+func rules(findings []Finding) map[string]bool {
+	m := make(map[string]bool)
+	for _, finding := range findings {
+		m[finding.Rule] = true
+	}
+	return m
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		lure      string
+		technique string
+		profile   ms_office.Profile
+		traceUrl  string
+		want      []string
+		not       []string
+	}{
+		{"credentials", "docx-template", ms_office.ProfileDefault, "http://10.0.0.1/t", []string{"remote-template", "fixed-id", "ip-host", "token-url"}, []string{"external-image", "known-domain"}},
+		{"credentials", "docx-template", ms_office.ProfileStealth, "https://abc.canarytokens.com/t", []string{"remote-template", "known-domain"}, []string{"fixed-id", "ip-host"}},
+		{"credentials", "docx-image", ms_office.ProfileDefault, "http://cdn.example.com/t", []string{"external-image", "tiny-image"}, []string{"remote-template"}},
+		{"credentials", "docx-image", ms_office.ProfileStealth, "http://cdn.example.com/t", []string{"external-image"}, []string{"tiny-image", "fixed-id"}},
+		{"credentials", "docx-frame", ms_office.ProfileDefault, "http://cdn.example.com/t", []string{"external-frame"}, nil},
+		{"network", "pptx-image", ms_office.ProfileDefault, "http://cdn.example.com/t", []string{"external-image", "tiny-image", "fixed-id"}, nil},
+		{"payroll", "xlsx-webservice", ms_office.ProfileDefault, "http://cdn.example.com/t", []string{"external-data"}, []string{"external-image"}},
+		{"payroll", "xlsx-connection", ms_office.ProfileDefault, "http://cdn.example.com/t", []string{"external-data"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.technique+"/"+string(tt.profile), func(t *testing.T) {
+			filename := filepath.Join(dir, tt.technique+string(tt.profile)+".zip")
+			_, err := lure.GenerateTracer(tt.lure, filename, tt.traceUrl, lure.Options{Profile: tt.profile}, tt.technique)
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings, err := Scan(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := rules(findings)
+			for _, rule := range tt.want {
+				if !got[rule] {
+					t.Errorf("missing %s: %+v", rule, findings)
+				}
+			}
+			for _, rule := range tt.not {
+				if got[rule] {
+					t.Errorf("unexpected %s: %+v", rule, findings)
+				}
+			}
+		})
+	}
+}
+
+func TestScanTechniqueLabel(t *testing.T) {
+	dir := t.TempDir()
+	for _, tag := range []bool{false, true} {
+		filename := filepath.Join(dir, fmt.Sprintf("%v.docx", tag))
+		_, err := lure.GenerateTracer("credentials", filename, "http://cdn.example.com/t", lure.Options{TagTechnique: tag}, "docx-template")
+		if err != nil {
+			t.Fatal(err)
+		}
+		findings, err := Scan(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rules(findings)["technique-label"]; got != tag {
+			t.Errorf("tag = %v, technique-label = %v: %+v", tag, got, findings)
+		}
+	}
+}
+
+func TestScanClean(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "clean.docx")
+	if err := lure.Generate("board-minutes", filename, lure.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := Scan(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("findings = %+v", findings)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	results, err := SelfTest(SelfTestOptions{
+		TraceUrls:  []string{"http://cdn.example.com/t"},
+		Techniques: []string{"docx-template", "docx-image"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("results = %d, want 4", len(results))
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i-1].Score() > results[i].Score() {
+			t.Errorf("results not sorted by score")
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = WriteReport(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "remote-template(high)") {
+		t.Errorf("report = %s", buf.String())
+	}
+	t.Log("\n" + buf.String())
+
+	// 添加追踪技术名称时只对比 default，被 technique-label 发现
+	tagged, err := SelfTest(SelfTestOptions{
+		TraceUrls:    []string{"http://cdn.example.com/t"},
+		Techniques:   []string{"docx-template"},
+		TagTechnique: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 3 {
+		t.Fatalf("tagged results = %d, want 3", len(tagged))
+	}
+	for _, result := range tagged {
+		flagged := strings.Join(result.Flagged(), ",")
+		if result.Tagged != strings.Contains(flagged, "technique-label") || result.Tagged && result.Profile != ms_office.ProfileDefault {
+			t.Errorf("result = %+v", result)
+		}
+	}
+
+	// 固定的 token 和随机种子，再次自检结果相同
+	again, err := SelfTest(SelfTestOptions{
+		TraceUrls:  []string{"http://cdn.example.com/t"},
+		Techniques: []string{"docx-template", "docx-image"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, again) {
+		t.Errorf("results = %+v, again = %+v", results, again)
+	}
+}
+
+func TestTokenString(t *testing.T) {
+	tests := map[string]bool{
+		"abcd2345efgh6723":                     true,
+		"mfrggzdfmztwqzlk":                     true, // 不包含数字
+		"k1-abcd2345efgh6723-mfrggzdfmztwqzlk": true,
+		"abcd2345efgh0189":                     false, // 0、1、8、9 不是 base32 字符
+		"abc234abc234":                         false,
+		"template":                             false,
+		"2026":                                 false,
+		"k1-abcd2345efgh6723":                  false,
+	}
+	for s, want := range tests {
+		if got := tokenString(s); got != want {
+			t.Errorf("tokenString(%s) = %v, want %v", s, got, want)
+		}
+	}
+}
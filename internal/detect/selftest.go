@@ -0,0 +1,163 @@
+package detect
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"tracer/internal/lure"
+	ms_office "tracer/internal/ms-office"
+)
+
+// sampleLures 格式 => 自检使用的诱饵文档模板
+var sampleLures = map[string]string{
+	"docx": "credentials",
+	"xlsx": "payroll",
+	"pptx": "network",
+}
+
+// 自检使用固定的 token 和随机种子，相同的选项生成相同的报告
+const (
+	selfTestToken = "mfrggzdfmztwq2lk"
+	selfTestSeed  = 1
+)
+
+// SelfTestOptions 自检选项
+type SelfTestOptions struct {
+	TraceUrls  []string            // 追踪地址，可以对比不同的地址格式
+	Profiles   []ms_office.Profile // 生成方式，为空时使用 default 和 stealth
+	Techniques []string            // 追踪技术，为空时使用全部 office 追踪技术
+	Locale     lure.Locale         // 诱饵文档语言
+
+	// TagTechnique 同时生成追踪地址添加追踪技术名称（查询参数 v）的文件，对比是否添加的结果
+	// ProfileStealth 不能添加追踪技术名称，只对比其他生成方式
+	TagTechnique bool
+}
+
+// Result 一次自检的结果
+type Result struct {
+	Technique string            `json:"technique"`
+	Profile   ms_office.Profile `json:"profile"`
+	Tagged    bool              `json:"tagged"` // 追踪地址添加了追踪技术名称
+	TraceUrl  string            `json:"traceUrl"`
+	Findings  []Finding         `json:"findings"`
+}
+
+// Flagged 获取触发的规则，按照严重程度排序
+func (r *Result) Flagged() []string {
+	seen := make(map[string]bool)
+	var rules []string
+	for _, rule := range Rules {
+		for _, finding := range r.Findings {
+			if finding.Rule == rule.Name && !seen[rule.Name] {
+				seen[rule.Name] = true
+				rules = append(rules, rule.Name+"("+string(rule.Severity)+")")
+			}
+		}
+	}
+	return rules
+}
+
+// Score 触发规则的分数，严重程度越高分数越高，用于排序
+func (r *Result) Score() int {
+	score := 0
+	for _, rule := range Rules {
+		for _, finding := range r.Findings {
+			if finding.Rule == rule.Name {
+				score += severityScores[rule.Severity]
+				break
+			}
+		}
+	}
+	return score
+}
+
+var severityScores = map[Severity]int{SeverityHigh: 100, SeverityMedium: 10, SeverityLow: 1}
+
+// SelfTest 使用内置诱饵文档生成可追踪文件，并使用检测规则检查
+// 每个追踪技术、生成方式、追踪地址的组合生成一个文件，结果按照分数从低到高排序
+func SelfTest(opts SelfTestOptions) (results []Result, err error) {
+	var (
+		tempDir string
+	)
+
+	if len(opts.Profiles) == 0 {
+		opts.Profiles = []ms_office.Profile{ms_office.ProfileDefault, ms_office.ProfileStealth}
+	}
+	techniques := opts.Techniques
+	if len(techniques) == 0 {
+		for _, technique := range ms_office.Techniques("") {
+			techniques = append(techniques, technique.Name)
+		}
+	}
+
+	tempDir, err = os.MkdirTemp("", "selftest-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	for _, name := range techniques {
+		technique, ok := ms_office.LookupTechnique(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown technique: %s", name)
+		}
+		sample, ok := sampleLures[technique.Format]
+		if !ok {
+			return nil, fmt.Errorf("%s: no sample for %s", name, technique.Format)
+		}
+
+		for _, profile := range opts.Profiles {
+			tags := []bool{false}
+			if opts.TagTechnique && profile != ms_office.ProfileStealth {
+				tags = append(tags, true)
+			}
+
+			for _, tag := range tags {
+				for _, traceUrl := range opts.TraceUrls {
+					filename := filepath.Join(tempDir, fmt.Sprintf("%d.%s", len(results), technique.Format))
+					lureOpts := lure.Options{Locale: opts.Locale, Seed: selfTestSeed, Profile: profile, Token: selfTestToken, TagTechnique: tag}
+					_, err = lure.GenerateTracer(sample, filename, traceUrl, lureOpts, name)
+					if err != nil {
+						return nil, fmt.Errorf("%s: %w", name, err)
+					}
+
+					findings, err := Scan(filename)
+					if err != nil {
+						return nil, err
+					}
+					results = append(results, Result{Technique: name, Profile: profile, Tagged: tag, TraceUrl: traceUrl, Findings: findings})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score() < results[j].Score()
+	})
+	return results, nil
+}
+
+// WriteReport 输出自检结果表格
+func WriteReport(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "technique\tprofile\ttraceUrl\tflagged")
+	for _, result := range results {
+		flagged := strings.Join(result.Flagged(), ", ")
+		if flagged == "" {
+			flagged = "-"
+		}
+		profile := string(result.Profile)
+		if result.Tagged {
+			profile += "+tag"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Technique, profile, result.TraceUrl, flagged)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,129 @@
+package email
+
+import (
+	"html"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tracer/internal/ms-office"
+	"tracer/internal/token"
+	"tracer/pkg/utils"
+)
+
+// Message 邮件内容
+type Message struct {
+	From       string    // 发件人地址
+	FromName   string    // 发件人名称
+	To         string    // 收件人地址
+	ToName     string    // 收件人名称
+	Subject    string    // 主题
+	Date       time.Time // 发送时间，为空时使用当前时间
+	Body       string    // 纯文本正文，${link} 替换为追踪链接，空行分隔段落
+	Attachment string    // 附件路径，为空时不添加附件
+	Techniques []string  // 附件使用的追踪技术（ms-office），为空时附件不追踪
+
+	Signer token.Signer // 签名追踪地址中的 token，为空时不签名
+}
+
+// PasswordReset 生成密码重置邮件
+func PasswordReset(from, to string) *Message {
+	return &Message{
+		From:     from,
+		FromName: "IT 服务台",
+		To:       to,
+		Subject:  "【重要】您的域账号密码即将过期",
+		Body: "您好：\n\n" +
+			"您的域账号密码将在 3 天后过期，过期后将无法登录邮箱、VPN 及办公系统。\n\n" +
+			"请在 24 小时内访问以下链接重置密码：\n${link}\n\n" +
+			"临时密码已随附件发送，请妥善保管，不要转发。\n\n" +
+			"IT 服务台",
+	}
+}
+
+// traceBody 生成纯文本和 HTML 正文，HTML 正文末尾添加远程图片
+func traceBody(msg *Message, traceUrl string) (text, htmlBody string) {
+	link := traceQuery(traceUrl, "link")
+	text = strings.Replace(msg.Body, "${link}", link, -1)
+
+	var b strings.Builder
+	b.WriteString(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=utf-8"></head><body>`)
+	for _, p := range strings.Split(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n\n") {
+		p = html.EscapeString(p)
+		p = strings.Replace(p, "${link}", `<a href="`+html.EscapeString(link)+`">`+html.EscapeString(link)+`</a>`, -1)
+		b.WriteString("<p>" + strings.ReplaceAll(p, "\n", "<br>") + "</p>")
+	}
+	b.WriteString(`<img src="` + html.EscapeString(traceQuery(traceUrl, "img")) + `" width="1" height="1" alt="" style="border:0">`)
+	b.WriteString("</body></html>")
+	return text, b.String()
+}
+
+// traceAttachment 读取附件，指定追踪技术时先生成可追踪文件
+func traceAttachment(msg *Message, traceUrl string) ([]byte, error) {
+	if len(msg.Techniques) == 0 {
+		return os.ReadFile(msg.Attachment)
+	}
+
+	tempDir, err := os.MkdirTemp("", "tracer-email-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	dstFile := filepath.Join(tempDir, filepath.Base(msg.Attachment))
+	err = ms_office.GenTracer(msg.Attachment, dstFile, traceQuery(traceUrl, "attachment"), msg.Techniques...)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(dstFile)
+}
+
+// traceUrls 生成 token 及追踪地址，signer 不为 nil 时使用签名后的 token
+func traceUrls(signer token.Signer, traceUrl string) (tok, url string) {
+	tok = token.New()
+	return tok, token.URL(signer, utils.UNCToUrl(traceUrl), tok)
+}
+
+// traceQuery 添加查询参数 v，标识追踪方式
+func traceQuery(traceUrl, v string) string {
+	if strings.Contains(traceUrl, "?") {
+		return traceUrl + "&v=" + v
+	}
+	return traceUrl + "?v=" + v
+}
+
+// messageDate 发送时间，为空时使用当前时间
+func messageDate(msg *Message) time.Time {
+	if msg.Date.IsZero() {
+		return time.Now()
+	}
+	return msg.Date
+}
+
+// attachmentTypes 常用附件的 MIME 类型，系统中可能没有 mime.types
+var attachmentTypes = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".docm": "application/vnd.ms-word.document.macroEnabled.12",
+	".xlsm": "application/vnd.ms-excel.sheet.macroEnabled.12",
+	".pptm": "application/vnd.ms-powerpoint.presentation.macroEnabled.12",
+	".pdf":  "application/pdf",
+	".zip":  "application/zip",
+}
+
+// attachmentType 根据扩展名获取附件的 MIME 类型
+func attachmentType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if v, ok := attachmentTypes[ext]; ok {
+		return v
+	}
+	if v := mime.TypeByExtension(ext); v != "" {
+		return v
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,40 @@
+package email
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tracer/internal/lure"
+)
+
+func testMessage(t *testing.T) *Message {
+	t.Helper()
+	msg := PasswordReset("helpdesk@example.com", "alice@example.com")
+	msg.ToName = "Alice"
+	msg.Date = time.Date(2024, 3, 1, 9, 30, 0, 0, time.FixedZone("CST", 8*3600))
+	msg.Attachment = filepath.Join(t.TempDir(), "临时密码.docx")
+	if err := lure.Generate("credentials", msg.Attachment, lure.Options{Seed: 1, Date: msg.Date}); err != nil {
+		t.Fatal(err)
+	}
+	msg.Techniques = []string{"docx-template"}
+	return msg
+}
+
+func TestTraceBody(t *testing.T) {
+	msg := &Message{Body: "a < b\n\n${link}"}
+	text, htmlBody := traceBody(msg, "http://example.com/t/abc?x=1")
+	if text != "a < b\n\nhttp://example.com/t/abc?x=1&v=link" {
+		t.Errorf("text = %q", text)
+	}
+	for _, v := range []string{
+		"<p>a &lt; b</p>",
+		`<a href="http://example.com/t/abc?x=1&amp;v=link">`,
+		`<img src="http://example.com/t/abc?x=1&amp;v=img" width="1" height="1"`,
+	} {
+		if !strings.Contains(htmlBody, v) {
+			t.Errorf("html missing %q: %s", v, htmlBody)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// base64 编码后每行的长度
+const emlLineLength = 76
+
+// GenTracerEML 生成可追踪的邮件（.eml），返回生成的 token
+// HTML 正文末尾添加远程图片，邮件客户端加载外部图片时请求追踪地址
+// traceUrl: 追踪地址，token 添加到路径末尾
+func GenTracerEML(dstFile string, msg *Message, traceUrl string) (tok string, err error) {
+	var (
+		attachment []byte
+	)
+
+	// 1、生成正文及附件
+	tok, traceUrl = traceUrls(msg.Signer, traceUrl)
+	text, htmlBody := traceBody(msg, traceUrl)
+	if msg.Attachment != "" {
+		attachment, err = traceAttachment(msg, traceUrl)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// 2、正文：multipart/alternative
+	var alternative bytes.Buffer
+	w := multipart.NewWriter(&alternative)
+	for _, part := range [][2]string{{"text/plain; charset=utf-8", text}, {"text/html; charset=utf-8", htmlBody}} {
+		err = writeQuotedPrintable(w, part[0], part[1])
+		if err != nil {
+			return "", err
+		}
+	}
+	_ = w.Close()
+	contentType := `multipart/alternative; boundary="` + w.Boundary() + `"`
+	body := alternative.Bytes()
+
+	// 3、附件：外层为 multipart/mixed
+	if msg.Attachment != "" {
+		var mixed bytes.Buffer
+		w = multipart.NewWriter(&mixed)
+		part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return "", err
+		}
+		_, _ = part.Write(body)
+
+		err = writeAttachment(w, filepath.Base(msg.Attachment), attachment)
+		if err != nil {
+			return "", err
+		}
+		_ = w.Close()
+		contentType = `multipart/mixed; boundary="` + w.Boundary() + `"`
+		body = mixed.Bytes()
+	}
+
+	// 4、邮件头
+	from := &mail.Address{Name: msg.FromName, Address: msg.From}
+	to := &mail.Address{Name: msg.ToName, Address: msg.To}
+	header := []string{
+		"From: " + from.String(),
+		"To: " + to.String(),
+		"Subject: " + mime.BEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + messageDate(msg).Format("Mon, 02 Jan 2006 15:04:05 -0700"),
+		"Message-ID: " + messageId(msg.From),
+		"MIME-Version: 1.0",
+		"Content-Type: " + contentType,
+	}
+	data := append([]byte(strings.Join(header, "\r\n")+"\r\n\r\n"), body...)
+
+	// 5、生成邮件
+	err = os.WriteFile(dstFile, data, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// writeQuotedPrintable 添加 quoted-printable 编码的正文
+func writeQuotedPrintable(w *multipart.Writer, contentType, content string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	_, err = qp.Write([]byte(content))
+	if err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeAttachment 添加 base64 编码的附件
+func writeAttachment(w *multipart.Writer, name string, data []byte) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(attachmentType(name), map[string]string{"name": name})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := emlLineLength
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		_, err = part.Write([]byte(encoded[:n] + "\r\n"))
+		if err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// messageId 生成 Message-ID，域名与发件人相同
+func messageId(from string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+	return "<" + strings.ToUpper(hex.EncodeToString(b)) + "@" + domain + ">"
+}
@@ -0,0 +1,123 @@
+package email
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+	"tracer/internal/token"
+)
+
+func TestGenTracerEML(t *testing.T) {
+	msg := testMessage(t)
+	dstFile := filepath.Join(t.TempDir(), "reset.eml")
+	tok, err := GenTracerEML(dstFile, msg, "http://10.0.0.1/t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceUrl := "http://10.0.0.1/t/" + tok
+
+	f, err := os.Open(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	m, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1、邮件头
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %s, %v", subject, err)
+	}
+	if to, err := m.Header.AddressList("To"); err != nil || to[0].Address != "alice@example.com" || to[0].Name != "Alice" {
+		t.Errorf("To = %v, %v", to, err)
+	}
+	if date, err := m.Header.Date(); err != nil || !date.Equal(msg.Date) {
+		t.Errorf("Date = %v, %v", date, err)
+	}
+
+	// 2、multipart/mixed：正文和附件
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %s, %v", mediaType, err)
+	}
+	mixed := multipart.NewReader(m.Body, params["boundary"])
+
+	part, err := mixed.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, _ = mime.ParseMediaType(part.Header.Get("Content-Type"))
+	alternative := multipart.NewReader(part, params["boundary"])
+	var bodies []string
+	for {
+		p, err := alternative.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// quoted-printable 由 multipart 自动解码
+		b, _ := io.ReadAll(p)
+		bodies = append(bodies, string(b))
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[0], traceUrl+"?v=link") {
+		t.Fatalf("bodies = %q", bodies)
+	}
+	if !strings.Contains(bodies[1], `<img src="`+traceUrl+`?v=img"`) {
+		t.Errorf("html = %s", bodies[1])
+	}
+
+	part, err = mixed.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.FileName() != "临时密码.docx" {
+		t.Errorf("FileName = %s", part.FileName())
+	}
+	b, _ := io.ReadAll(part)
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(b), "\r\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rels := testutil.ReadZip(t, data)["word/_rels/settings.xml.rels"]
+	if !strings.Contains(rels, traceUrl+"?v=attachment") {
+		t.Errorf("settings.xml.rels = %s", rels)
+	}
+}
+
+func TestGenTracerEMLWithoutAttachment(t *testing.T) {
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := PasswordReset("helpdesk@example.com", "alice@example.com")
+	msg.Signer = keyring
+	dstFile := filepath.Join(t.TempDir(), "reset.eml")
+	tok, err := GenTracerEML(dstFile, msg, "http://10.0.0.1/t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 设置 Signer 时追踪链接中为签名后的 token
+	b, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Content-Type: multipart/alternative;") || !strings.Contains(string(b), keyring.Sign(tok)) {
+		t.Errorf("eml = %s", b)
+	}
+}
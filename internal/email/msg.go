@@ -0,0 +1,247 @@
+package email
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"time"
+	"unicode/utf16"
+
+	"tracer/pkg/utils"
+)
+
+// MAPI 属性标签：高 16 位为属性 id，低 16 位为属性类型
+const (
+	prMessageClass          = 0x001a001f
+	prSubject               = 0x0037001f
+	prClientSubmitTime      = 0x00390040
+	prSubjectPrefix         = 0x003d001f
+	prSentRepresentingName  = 0x0042001f
+	prSentRepresentingAddr  = 0x0064001f
+	prSentRepresentingEmail = 0x0065001f
+	prSenderName            = 0x0c1a001f
+	prSenderAddrType        = 0x0c1e001f
+	prSenderEmailAddress    = 0x0c1f001f
+	prRecipientType         = 0x0c150003
+	prDisplayTo             = 0x0e04001f
+	prMessageDeliveryTime   = 0x0e060040
+	prMessageFlags          = 0x0e070003
+	prNormalizedSubject     = 0x0e1d001f
+	prAttachSize            = 0x0e200003
+	prAttachNum             = 0x0e210003
+	prObjectType            = 0x0ffe0003
+	prBody                  = 0x1000001f
+	prHTML                  = 0x10130102
+	prInternetMessageId     = 0x1035001f
+	prRowId                 = 0x30000003
+	prDisplayName           = 0x3001001f
+	prAddrType              = 0x3002001f
+	prEmailAddress          = 0x3003001f
+	prCreationTime          = 0x30070040
+	prLastModificationTime  = 0x30080040
+	prStoreSupportMask      = 0x340d0003
+	prAttachDataBin         = 0x37010102
+	prAttachExtension       = 0x3703001f
+	prAttachFilename        = 0x3704001f
+	prAttachMethod          = 0x37050003
+	prAttachLongFilename    = 0x3707001f
+	prRenderingPosition     = 0x370b0003
+	prAttachMimeTag         = 0x370e001f
+	prDisplayType           = 0x39000003
+	prSmtpAddress           = 0x39fe001f
+	prInternetCodepage      = 0x3fde0003
+)
+
+const (
+	msgPropertyFlags          = 0x00000006 // PROPATTR_READABLE | PROPATTR_WRITABLE
+	msgFlagHasAttach          = 0x00000010
+	msgStoreUnicodeOk         = 0x00040000
+	msgObjectMailUser         = 6
+	msgObjectAttach           = 7
+	msgRecipientTo            = 1
+	msgAttachByValue          = 1
+	msgCodepageUTF8           = 65001
+	msgTopLevelHeaderSize     = 32
+	msgRecipAttachHeaderSize  = 8
+	msgPropertiesStream       = "__properties_version1.0"
+	msgNameIdStorage          = "__nameid_version1.0"
+	msgRecipStorage           = "__recip_version1.0_#00000000"
+	msgAttachStorage          = "__attach_version1.0_#00000000"
+	msgFileTimeEpochDiff      = 116444736000000000
+	msgFileTimeTicksPerSecond = 10000000
+)
+
+// msgCLSID Outlook 邮件的根存储 CLSID {00020D0B-0000-0000-C000-000000000046}
+var msgCLSID = [16]byte{0x0b, 0x0d, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+// msgProperty MAPI 属性，值为 uint32、bool、time.Time、string 或 []byte
+type msgProperty struct {
+	Tag   uint32
+	Value interface{}
+}
+
+// GenTracerMSG 生成可追踪的 Outlook 邮件（.msg），返回生成的 token
+// 使用 MS-OXMSG 格式，HTML 正文（PR_HTML）末尾添加远程图片
+// traceUrl: 追踪地址，token 添加到路径末尾
+func GenTracerMSG(dstFile string, msg *Message, traceUrl string) (tok string, err error) {
+	var (
+		attachment []byte
+	)
+
+	// 1、生成正文及附件
+	tok, traceUrl = traceUrls(msg.Signer, traceUrl)
+	text, htmlBody := traceBody(msg, traceUrl)
+	if msg.Attachment != "" {
+		attachment, err = traceAttachment(msg, traceUrl)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// 2、邮件属性
+	date := messageDate(msg)
+	flags := uint32(0)
+	if msg.Attachment != "" {
+		flags |= msgFlagHasAttach
+	}
+	fromName := msg.FromName
+	if fromName == "" {
+		fromName = msg.From
+	}
+	toName := msg.ToName
+	if toName == "" {
+		toName = msg.To
+	}
+
+	cf := &utils.CompoundFile{Root: &utils.CFBEntry{Name: "Root Entry", Type: utils.CFBRoot, CLSID: msgCLSID}}
+	header := make([]byte, msgTopLevelHeaderSize)
+	binary.LittleEndian.PutUint32(header[8:], 1)  // next recipient id
+	binary.LittleEndian.PutUint32(header[16:], 1) // recipient count
+	if msg.Attachment != "" {
+		binary.LittleEndian.PutUint32(header[12:], 1) // next attachment id
+		binary.LittleEndian.PutUint32(header[20:], 1) // attachment count
+	}
+	setMSGProperties(cf, "", header, []msgProperty{
+		{prMessageClass, "IPM.Note"},
+		{prSubject, msg.Subject},
+		{prSubjectPrefix, ""},
+		{prNormalizedSubject, msg.Subject},
+		{prClientSubmitTime, date},
+		{prMessageDeliveryTime, date},
+		{prCreationTime, date},
+		{prLastModificationTime, date},
+		{prSenderName, fromName},
+		{prSenderEmailAddress, msg.From},
+		{prSenderAddrType, "SMTP"},
+		{prSentRepresentingName, fromName},
+		{prSentRepresentingEmail, msg.From},
+		{prSentRepresentingAddr, "SMTP"},
+		{prDisplayTo, toName},
+		{prMessageFlags, flags},
+		{prInternetMessageId, messageId(msg.From)},
+		{prStoreSupportMask, uint32(msgStoreUnicodeOk)},
+		{prInternetCodepage, uint32(msgCodepageUTF8)},
+		{prBody, text},
+		{prHTML, []byte(htmlBody)},
+	})
+
+	// 3、命名属性映射，没有命名属性时也需要存在
+	for _, name := range []string{"__substg1.0_00020102", "__substg1.0_00030102", "__substg1.0_00040102"} {
+		cf.SetStream(msgNameIdStorage+"/"+name, []byte{})
+	}
+
+	// 4、收件人
+	setMSGProperties(cf, msgRecipStorage, make([]byte, msgRecipAttachHeaderSize), []msgProperty{
+		{prRowId, uint32(0)},
+		{prObjectType, uint32(msgObjectMailUser)},
+		{prDisplayType, uint32(0)},
+		{prRecipientType, uint32(msgRecipientTo)},
+		{prDisplayName, toName},
+		{prAddrType, "SMTP"},
+		{prEmailAddress, msg.To},
+		{prSmtpAddress, msg.To},
+	})
+
+	// 5、附件
+	if msg.Attachment != "" {
+		name := filepath.Base(msg.Attachment)
+		setMSGProperties(cf, msgAttachStorage, make([]byte, msgRecipAttachHeaderSize), []msgProperty{
+			{prAttachNum, uint32(0)},
+			{prObjectType, uint32(msgObjectAttach)},
+			{prAttachMethod, uint32(msgAttachByValue)},
+			{prRenderingPosition, uint32(0xffffffff)},
+			{prAttachSize, uint32(len(attachment))},
+			{prDisplayName, name},
+			{prAttachFilename, name},
+			{prAttachLongFilename, name},
+			{prAttachExtension, filepath.Ext(name)},
+			{prAttachMimeTag, attachmentType(name)},
+			{prAttachDataBin, attachment},
+		})
+	}
+
+	// 6、生成邮件
+	err = utils.WriteCFB(cf, dstFile)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// setMSGProperties 写入属性流（__properties_version1.0），变长属性写入单独的流
+// storage 为空时写入根存储
+func setMSGProperties(cf *utils.CompoundFile, storage string, header []byte, props []msgProperty) {
+	prefix := ""
+	if storage != "" {
+		prefix = storage + "/"
+	}
+
+	le := binary.LittleEndian
+	b := header
+	for _, prop := range props {
+		value := make([]byte, 8)
+		switch v := prop.Value.(type) {
+		case uint32:
+			le.PutUint32(value, v)
+		case bool:
+			if v {
+				value[0] = 1
+			}
+		case time.Time:
+			le.PutUint64(value, fileTime(v))
+		case string:
+			// 字符串不以 0 结尾，长度包含结尾的 2 字节
+			data := encodeUTF16(v)
+			le.PutUint32(value, uint32(len(data)+2))
+			cf.SetStream(prefix+msgStreamName(prop.Tag), data)
+		case []byte:
+			le.PutUint32(value, uint32(len(v)))
+			cf.SetStream(prefix+msgStreamName(prop.Tag), v)
+		}
+
+		b = le.AppendUint32(b, prop.Tag)
+		b = le.AppendUint32(b, msgPropertyFlags)
+		b = append(b, value...)
+	}
+	cf.SetStream(prefix+msgPropertiesStream, b)
+}
+
+// msgStreamName 变长属性的流名称，例如 __substg1.0_0037001F
+func msgStreamName(tag uint32) string {
+	return fmt.Sprintf("__substg1.0_%08X", tag)
+}
+
+// encodeUTF16 编码为 UTF-16LE
+func encodeUTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 0, len(u)*2)
+	for _, v := range u {
+		b = binary.LittleEndian.AppendUint16(b, v)
+	}
+	return b
+}
+
+// fileTime 转换为 Windows FILETIME
+func fileTime(t time.Time) uint64 {
+	return uint64(t.Unix())*msgFileTimeTicksPerSecond + uint64(t.Nanosecond()/100) + msgFileTimeEpochDiff
+}
@@ -0,0 +1,89 @@
+package email
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"tracer/internal/testutil"
+	"tracer/pkg/utils"
+)
+
+// testMSGString 读取字符串属性流
+func testMSGString(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// testMSGProperties 解析属性流，返回属性标签及 8 字节的值
+func testMSGProperties(b []byte, headerSize int) map[uint32][]byte {
+	props := make(map[uint32][]byte)
+	for i := headerSize; i+16 <= len(b); i += 16 {
+		props[binary.LittleEndian.Uint32(b[i:])] = b[i+8 : i+16]
+	}
+	return props
+}
+
+func TestGenTracerMSG(t *testing.T) {
+	msg := testMessage(t)
+	dstFile := filepath.Join(t.TempDir(), "reset.msg")
+	tok, err := GenTracerMSG(dstFile, msg, "http://10.0.0.1/t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceUrl := "http://10.0.0.1/t/" + tok
+
+	cf, err := utils.ReadCFB(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1、邮件属性
+	header := cf.Stream(msgPropertiesStream)
+	if len(header) < msgTopLevelHeaderSize || binary.LittleEndian.Uint32(header[16:]) != 1 || binary.LittleEndian.Uint32(header[20:]) != 1 {
+		t.Fatalf("header = %x", header)
+	}
+	props := testMSGProperties(header, msgTopLevelHeaderSize)
+	if v, ok := props[prMessageFlags]; !ok || binary.LittleEndian.Uint32(v)&msgFlagHasAttach == 0 {
+		t.Errorf("PR_MESSAGE_FLAGS = %x", v)
+	}
+	if v := props[prClientSubmitTime]; binary.LittleEndian.Uint64(v) != fileTime(msg.Date) {
+		t.Errorf("PR_CLIENT_SUBMIT_TIME = %x", v)
+	}
+	subject := cf.Stream(msgStreamName(prSubject))
+	if testMSGString(subject) != msg.Subject || binary.LittleEndian.Uint32(props[prSubject]) != uint32(len(subject)+2) {
+		t.Errorf("PR_SUBJECT = %s", testMSGString(subject))
+	}
+	if s := testMSGString(cf.Stream(msgStreamName(prMessageClass))); s != "IPM.Note" {
+		t.Errorf("PR_MESSAGE_CLASS = %s", s)
+	}
+	if s := string(cf.Stream(msgStreamName(prHTML))); !strings.Contains(s, `<img src="`+traceUrl+`?v=img"`) {
+		t.Errorf("PR_HTML = %s", s)
+	}
+	if s := testMSGString(cf.Stream(msgStreamName(prBody))); !strings.Contains(s, traceUrl+"?v=link") {
+		t.Errorf("PR_BODY = %s", s)
+	}
+	if cf.Entry(msgNameIdStorage+"/__substg1.0_00020102") == nil {
+		t.Error("missing named property mapping storage")
+	}
+
+	// 2、收件人
+	if s := testMSGString(cf.Stream(msgRecipStorage + "/" + msgStreamName(prSmtpAddress))); s != "alice@example.com" {
+		t.Errorf("PR_SMTP_ADDRESS = %s", s)
+	}
+
+	// 3、附件
+	if s := testMSGString(cf.Stream(msgAttachStorage + "/" + msgStreamName(prAttachLongFilename))); s != "临时密码.docx" {
+		t.Errorf("PR_ATTACH_LONG_FILENAME = %s", s)
+	}
+	data := cf.Stream(msgAttachStorage + "/" + msgStreamName(prAttachDataBin))
+	rels := testutil.ReadZip(t, data)["word/_rels/settings.xml.rels"]
+	if !strings.Contains(rels, traceUrl+"?v=attachment") {
+		t.Errorf("settings.xml.rels = %s", rels)
+	}
+}
@@ -0,0 +1,144 @@
+package lure
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Locale 内容语言
+type Locale string
+
+const (
+	LocaleZH Locale = "zh" // 简体中文
+	LocaleEN Locale = "en" // 英文
+)
+
+// person 随机生成的人员
+type person struct {
+	Name     string // 显示名称
+	Username string // 账号，例如 zhangwei、jsmith
+	Title    string // 职位
+	Level    int    // 职位级别，titles 中的下标
+	Dept     string // 部门
+}
+
+// 姓名及拼音
+var (
+	zhSurnames = [][2]string{
+		{"王", "wang"}, {"李", "li"}, {"张", "zhang"}, {"刘", "liu"}, {"陈", "chen"},
+		{"杨", "yang"}, {"赵", "zhao"}, {"黄", "huang"}, {"周", "zhou"}, {"吴", "wu"},
+		{"徐", "xu"}, {"孙", "sun"}, {"胡", "hu"}, {"朱", "zhu"}, {"高", "gao"},
+		{"林", "lin"}, {"何", "he"}, {"郭", "guo"}, {"马", "ma"}, {"罗", "luo"},
+	}
+	zhGivenNames = [][2]string{
+		{"伟", "wei"}, {"芳", "fang"}, {"娜", "na"}, {"秀英", "xiuying"}, {"敏", "min"},
+		{"静", "jing"}, {"丽", "li"}, {"强", "qiang"}, {"磊", "lei"}, {"军", "jun"},
+		{"洋", "yang"}, {"勇", "yong"}, {"艳", "yan"}, {"杰", "jie"}, {"娟", "juan"},
+		{"涛", "tao"}, {"明", "ming"}, {"超", "chao"}, {"霞", "xia"}, {"平", "ping"},
+		{"鹏", "peng"}, {"宇", "yu"}, {"浩", "hao"}, {"晨", "chen"}, {"欣怡", "xinyi"},
+		{"子涵", "zihan"}, {"梓萱", "zixuan"}, {"俊杰", "junjie"}, {"思远", "siyuan"}, {"雨桐", "yutong"},
+	}
+	enFirstNames = []string{
+		"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth",
+		"William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Charles", "Karen",
+	}
+	enLastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+		"Wilson", "Anderson", "Taylor", "Thomas", "Moore", "Jackson", "Martin", "Lee", "Thompson", "White",
+	}
+)
+
+// 部门、职位、公司
+var (
+	departments = map[Locale][]string{
+		LocaleZH: {"财务部", "人力资源部", "研发中心", "市场部", "销售部", "运维部", "法务部", "行政部"},
+		LocaleEN: {"Finance", "Human Resources", "R&D", "Marketing", "Sales", "IT Operations", "Legal", "Administration"},
+	}
+	titles = map[Locale][]string{
+		LocaleZH: {"总监", "经理", "主管", "高级工程师", "工程师", "专员", "助理"},
+		LocaleEN: {"Director", "Manager", "Supervisor", "Senior Engineer", "Engineer", "Specialist", "Assistant"},
+	}
+	companies = map[Locale][]string{
+		LocaleZH: {"华信科技有限公司", "远景数据股份有限公司", "恒达实业集团", "启明网络技术有限公司", "中联智能制造有限公司"},
+		LocaleEN: {"Northwind Holdings", "Contoso Ltd", "Fabrikam Inc", "Bluewater Logistics", "Summit Analytics"},
+	}
+	// 与 titles 对应的月薪范围
+	salaryRanges = [][2]int{{45000, 80000}, {28000, 45000}, {20000, 30000}, {22000, 35000}, {14000, 24000}, {9000, 15000}, {6000, 9000}}
+	// 密码中常用的单词
+	passwordWords = []string{"Welcome", "Spring", "Summer", "Autumn", "Winter", "Admin", "Company", "Passw0rd", "Qwer", "P@ss"}
+)
+
+// pick 随机选择一个元素
+func pick[T any](r *rand.Rand, list []T) T {
+	return list[r.Intn(len(list))]
+}
+
+// between 返回 [min, max] 之间的随机整数
+func between(r *rand.Rand, min, max int) int {
+	return min + r.Intn(max-min+1)
+}
+
+// newPerson 随机生成人员，同一批次中账号不重复
+func newPerson(r *rand.Rand, locale Locale, used map[string]bool) person {
+	for {
+		var p person
+		if locale == LocaleZH {
+			surname, given := pick(r, zhSurnames), pick(r, zhGivenNames)
+			p.Name = surname[0] + given[0]
+			p.Username = surname[1] + given[1]
+		} else {
+			first, last := pick(r, enFirstNames), pick(r, enLastNames)
+			p.Name = first + " " + last
+			p.Username = strings.ToLower(first[:1] + last)
+		}
+		if used[p.Username] {
+			p.Username += fmt.Sprint(between(r, 1, 99))
+		}
+		if used[p.Username] {
+			continue
+		}
+		used[p.Username] = true
+
+		p.Level = r.Intn(len(titles[locale]))
+		p.Title = titles[locale][p.Level]
+		p.Dept = pick(r, departments[locale])
+		return p
+	}
+}
+
+// newPassword 生成看起来像人工设置的密码，例如 Summer2026!、Admin@123
+func newPassword(r *rand.Rand, year int) string {
+	word := pick(r, passwordWords)
+	switch r.Intn(3) {
+	case 0:
+		return fmt.Sprintf("%s%d%c", word, year, pick(r, []rune("!@#$")))
+	case 1:
+		return fmt.Sprintf("%s@%d", word, between(r, 100, 999))
+	default:
+		const chars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+		b := make([]byte, 12)
+		for i := range b {
+			b[i] = chars[r.Intn(len(chars))]
+		}
+		return string(b[:4]) + "-" + string(b[4:8]) + "-" + string(b[8:])
+	}
+}
+
+// newIP 生成内网地址
+func newIP(r *rand.Rand, subnet string) string {
+	return fmt.Sprintf("%s.%d", subnet, between(r, 2, 250))
+}
+
+// newSubnet 生成内网 /24 网段前缀，例如 10.20.30
+func newSubnet(r *rand.Rand) string {
+	return fmt.Sprintf("10.%d.%d", between(r, 1, 60), between(r, 1, 250))
+}
+
+// text 根据语言选择文本或数值
+func text[T any](locale Locale, zh, en T) T {
+	if locale == LocaleZH {
+		return zh
+	}
+	return en
+}
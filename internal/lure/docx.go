@@ -0,0 +1,122 @@
+package lure
+
+import (
+	"strings"
+)
+
+const docxDocumentTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body>${body}<w:sectPr><w:pgSz w:w="${width}" w:h="${height}"/><w:pgMar w:top="1440" w:right="1800" w:bottom="1440" w:left="1800" w:header="851" w:footer="992" w:gutter="0"/><w:cols w:space="425"/></w:sectPr></w:body></w:document>`
+
+const docxStylesTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:asciiTheme="minorHAnsi" w:eastAsiaTheme="minorEastAsia" w:hAnsiTheme="minorHAnsi" w:cstheme="minorBidi"/><w:kern w:val="2"/><w:sz w:val="${size}"/><w:szCs w:val="24"/><w:lang w:val="en-US" w:eastAsia="${lang}" w:bidi="ar-SA"/></w:rPr></w:rPrDefault><w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="259" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:spacing w:before="240" w:after="60"/><w:jc w:val="center"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:rFonts w:asciiTheme="majorHAnsi" w:eastAsiaTheme="majorEastAsia" w:hAnsiTheme="majorHAnsi"/><w:b/><w:sz w:val="32"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:default="1" w:styleId="TableNormal"><w:name w:val="Normal Table"/><w:tblPr><w:tblInd w:w="0" w:type="dxa"/><w:tblCellMar><w:top w:w="0" w:type="dxa"/><w:left w:w="108" w:type="dxa"/><w:bottom w:w="0" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:basedOn w:val="TableNormal"/><w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:tblPr><w:tblBorders><w:top w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:left w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:bottom w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:right w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideH w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="auto"/></w:tblBorders></w:tblPr></w:style></w:styles>`
+
+const docxSettingsTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:zoom w:percent="100"/><w:bordersDoNotSurroundHeader/><w:bordersDoNotSurroundFooter/><w:defaultTabStop w:val="420"/><w:drawingGridVerticalSpacing w:val="156"/><w:characterSpacingControl w:val="compressPunctuation"/><w:compat><w:compatSetting w:name="compatibilityMode" w:uri="http://schemas.microsoft.com/office/word" w:val="15"/></w:compat><w:themeFontLang w:val="en-US" w:eastAsia="${lang}"/><w:decimalSymbol w:val="."/><w:listSeparator w:val=","/></w:settings>`
+
+const docxRelsTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+// block 文档内容块：段落或表格
+type block struct {
+	Style string     // 段落样式：Title、Heading1，为空时为正文
+	Text  string     // 段落文本
+	Bold  bool       // 段落加粗
+	Right bool       // 段落右对齐
+	Table [][]string // 表格，第一行为表头
+}
+
+// buildDOCX 生成 docx 文件
+func buildDOCX(g *generator, title string, blocks []block) ooxmlPackage {
+	var body strings.Builder
+	for _, b := range blocks {
+		if b.Table != nil {
+			body.WriteString(docxTable(b.Table))
+			continue
+		}
+		body.WriteString(docxParagraph(b))
+	}
+
+	// 中文使用 A4，英文使用 Letter
+	width, height := "12240", "15840"
+	if g.locale == LocaleZH {
+		width, height = "11906", "16838"
+	}
+
+	p := newPackage(g, "word/document.xml", "Microsoft Office Word", title, []override{
+		{"/word/document.xml", "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"},
+		{"/word/styles.xml", "application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"},
+		{"/word/settings.xml", "application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"},
+	})
+	p["word/document.xml"] = strings.NewReplacer("${body}", body.String(), "${width}", width, "${height}", height).Replace(docxDocumentTemp)
+	p["word/styles.xml"] = strings.NewReplacer("${size}", text(g.locale, "21", "22"), "${lang}", lang(g.locale)).Replace(docxStylesTemp)
+	p["word/settings.xml"] = strings.Replace(docxSettingsTemp, "${lang}", lang(g.locale), 1)
+	p["word/_rels/document.xml.rels"] = docxRelsTemp
+	return p
+}
+
+// docxParagraph 生成段落，文本中的换行转换为 w:br
+func docxParagraph(b block) string {
+	var s strings.Builder
+	s.WriteString("<w:p>")
+	if b.Style != "" || b.Right {
+		s.WriteString("<w:pPr>")
+		if b.Style != "" {
+			s.WriteString(`<w:pStyle w:val="` + b.Style + `"/>`)
+		}
+		if b.Right {
+			s.WriteString(`<w:jc w:val="right"/>`)
+		}
+		s.WriteString("</w:pPr>")
+	}
+	if b.Text != "" {
+		s.WriteString(docxRun(b.Text, b.Bold))
+	}
+	s.WriteString("</w:p>")
+	return s.String()
+}
+
+// docxRun 生成文本
+func docxRun(text string, bold bool) string {
+	var s strings.Builder
+	s.WriteString("<w:r>")
+	if bold {
+		s.WriteString("<w:rPr><w:b/></w:rPr>")
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			s.WriteString("<w:br/>")
+		}
+		s.WriteString(`<w:t xml:space="preserve">` + escape(line) + "</w:t>")
+	}
+	s.WriteString("</w:r>")
+	return s.String()
+}
+
+// docxTable 生成表格，第一行为加粗的表头，表格之后添加空段落
+func docxTable(rows [][]string) string {
+	var s strings.Builder
+	s.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/><w:tblLook w:val="04A0" w:firstRow="1" w:lastRow="0" w:firstColumn="1" w:lastColumn="0" w:noHBand="0" w:noVBand="1"/></w:tblPr><w:tblGrid>`)
+	if len(rows) > 0 {
+		for range rows[0] {
+			s.WriteString(`<w:gridCol/>`)
+		}
+	}
+	s.WriteString("</w:tblGrid>")
+	for i, row := range rows {
+		s.WriteString("<w:tr>")
+		if i == 0 {
+			s.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		for _, cell := range row {
+			s.WriteString(`<w:tc><w:tcPr><w:tcW w:w="0" w:type="auto"/></w:tcPr><w:p>` + docxRun(cell, i == 0) + "</w:p></w:tc>")
+		}
+		s.WriteString("</w:tr>")
+	}
+	s.WriteString("</w:tbl><w:p/>")
+	return s.String()
+}
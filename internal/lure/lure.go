@@ -0,0 +1,187 @@
+package lure
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ms_office "tracer/internal/ms-office"
+	"tracer/internal/token"
+	"tracer/pkg/utils"
+)
+
+var ErrLure = errors.New("unknown lure")
+
+// Options 生成选项
+type Options struct {
+	Locale  Locale    // 内容语言，默认为简体中文
+	Seed    int64     // 随机种子，为 0 时随机生成，相同的种子生成相同的内容
+	Company string    // 公司名称，为空时随机选择
+	Domain  string    // 内网域名，默认为 corp.local
+	Date    time.Time // 文档日期，为空时使用当前时间
+
+	Profile ms_office.Profile // 追踪信息的生成方式，默认为 ms_office.ProfileDefault
+	Token   string            // 追踪使用的 token，为空时随机生成
+	Signer  token.Signer      // 签名追踪地址中的 token，为空时不签名
+
+	TagTechnique bool // 追踪地址添加查询参数 v 为追踪技术名称，见 ms_office.Options，不能与 ms_office.ProfileStealth 一起使用
+}
+
+// Lure 诱饵文档模板
+type Lure struct {
+	Name        string            // 名称，例如 payroll
+	Format      string            // 文件格式：docx、xlsx、pptx
+	Description string            // 说明
+	FileName    map[Locale]string // 建议的文件名，${year} 替换为文档年份
+	build       func(g *generator) ooxmlPackage
+}
+
+// generator 生成一个文档时的随机状态
+type generator struct {
+	r       *rand.Rand
+	locale  Locale
+	date    time.Time
+	company string
+	domain  string
+	author  person
+	used    map[string]bool // 已使用的账号
+}
+
+var lures = make(map[string]*Lure)
+
+func registerLure(lure *Lure) {
+	lures[lure.Name] = lure
+}
+
+// LookupLure 根据名称查找模板
+func LookupLure(name string) (*Lure, bool) {
+	lure, ok := lures[name]
+	return lure, ok
+}
+
+// Lures 获取全部模板，按照名称排序
+func Lures() (list []*Lure) {
+	for _, lure := range lures {
+		list = append(list, lure)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// DefaultFileName 获取建议的文件名，例如 2026年10月工资表.xlsx
+func (l *Lure) DefaultFileName(opts Options) string {
+	opts = opts.normalize()
+	name := strings.ReplaceAll(l.FileName[opts.Locale], "${year}", strconv.Itoa(opts.Date.Year()))
+	name = strings.ReplaceAll(name, "${month}", strconv.Itoa(int(opts.Date.Month())))
+	return name + "." + l.Format
+}
+
+// Generate 根据模板生成诱饵文档，不包含追踪信息
+func Generate(name, dstFile string, opts Options) error {
+	lure, ok := LookupLure(name)
+	if !ok {
+		return ErrLure
+	}
+	return lure.build(newGenerator(opts)).write(dstFile)
+}
+
+// GenerateTracer 根据模板生成诱饵文档并添加追踪信息，返回生成的 token
+// traceUrl: 追踪地址，token 添加到路径末尾
+// techniques: 追踪技术名称，为空时使用模板格式的默认追踪技术
+func GenerateTracer(name, dstFile, traceUrl string, opts Options, techniques ...string) (tok string, err error) {
+	var (
+		tempDir string
+	)
+
+	lure, ok := LookupLure(name)
+	if !ok {
+		return "", ErrLure
+	}
+	if len(techniques) == 0 {
+		techniques = []string{defaultTechniques[lure.Format]}
+	}
+
+	// 1、生成诱饵文档
+	tempDir, err = os.MkdirTemp("", "lure-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+	srcFile := filepath.Join(tempDir, "lure."+lure.Format)
+	err = lure.build(newGenerator(opts)).write(srcFile)
+	if err != nil {
+		return "", err
+	}
+
+	// 2、添加追踪信息，未指定 token 时每个文件使用不同的 token
+	tok = opts.Token
+	if tok == "" {
+		tok = token.New()
+	}
+	traceOpts := ms_office.Options{Profile: opts.Profile, TagTechnique: opts.TagTechnique}
+	err = ms_office.GenTracerOptions(srcFile, dstFile, token.URL(opts.Signer, utils.UNCToUrl(traceUrl), tok), traceOpts, techniques...)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// DefaultTechnique 模板格式的默认追踪技术
+func (l *Lure) DefaultTechnique() string {
+	return defaultTechniques[l.Format]
+}
+
+// defaultTechniques 格式 => 默认追踪技术，选择不需要用户确认的方式
+var defaultTechniques = map[string]string{
+	"docx": "docx-template",
+	"xlsx": "xlsx-image",
+	"pptx": "pptx-image",
+}
+
+// normalize 填充默认值
+func (opts Options) normalize() Options {
+	if opts.Locale != LocaleEN {
+		opts.Locale = LocaleZH
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.Domain == "" {
+		opts.Domain = "corp.local"
+	}
+	if opts.Date.IsZero() {
+		opts.Date = time.Now()
+	}
+	return opts
+}
+
+func newGenerator(opts Options) *generator {
+	opts = opts.normalize()
+	g := &generator{
+		r:       rand.New(rand.NewSource(opts.Seed)),
+		locale:  opts.Locale,
+		company: opts.Company,
+		domain:  opts.Domain,
+		used:    make(map[string]bool),
+	}
+	// 文档时间为工作日上午的随机时间
+	date := opts.Date
+	for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		date = date.AddDate(0, 0, -1)
+	}
+	g.date = time.Date(date.Year(), date.Month(), date.Day(), between(g.r, 9, 11), g.r.Intn(60), g.r.Intn(60), 0, date.Location())
+	if g.company == "" {
+		g.company = pick(g.r, companies[g.locale])
+	}
+	g.author = newPerson(g.r, g.locale, g.used)
+	return g
+}
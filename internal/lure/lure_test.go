@@ -0,0 +1,169 @@
+package lure
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ms_office "tracer/internal/ms-office"
+	"tracer/internal/testutil"
+	"tracer/internal/token"
+	"tracer/pkg/utils"
+)
+
+var testOptions = Options{Seed: 42, Date: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	wants := map[string]string{
+		"payroll":       "实发工资",
+		"credentials":   "堡垒机",
+		"network":       "核心交换机",
+		"board-minutes": "董事会会议纪要",
+	}
+	for _, lure := range Lures() {
+		t.Run(lure.Name, func(t *testing.T) {
+			filename := filepath.Join(dir, lure.DefaultFileName(testOptions))
+			err := Generate(lure.Name, filename, testOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := testutil.ReadZipFile(t, filename)
+
+			var content strings.Builder
+			for _, data := range files {
+				content.WriteString(data)
+			}
+			if want := wants[lure.Name]; !strings.Contains(content.String(), want) {
+				t.Errorf("content does not contain %q", want)
+			}
+
+			tempDir, err := utils.ExtractZip(filename, "lure-test-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tempDir)
+			format, err := ms_office.DetectFormat(tempDir)
+			if err != nil || format != lure.Format {
+				t.Errorf("DetectFormat() = %s, %v, want %s", format, err, lure.Format)
+			}
+		})
+	}
+}
+
+func TestGenerateLocale(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "payroll.xlsx")
+	opts := testOptions
+	opts.Locale = LocaleEN
+	err := Generate("payroll", filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := testutil.ReadZipFile(t, filename)
+	if !strings.Contains(files["xl/sharedStrings.xml"], "Net Pay") || strings.Contains(files["xl/sharedStrings.xml"], "实发工资") {
+		t.Errorf("sharedStrings.xml = %s", files["xl/sharedStrings.xml"])
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	dir := t.TempDir()
+	read := func(name string, opts Options) string {
+		filename := filepath.Join(dir, name)
+		err := Generate("credentials", filename, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// 相同的种子生成相同的文件
+	a, b := read("a.docx", testOptions), read("b.docx", testOptions)
+	if a != b {
+		t.Error("same seed generated different files")
+	}
+	opts := testOptions
+	opts.Seed = 43
+	if c := read("c.docx", opts); c == a {
+		t.Error("different seed generated same file")
+	}
+}
+
+func TestGenerateTracer(t *testing.T) {
+	dir := t.TempDir()
+	for _, lure := range Lures() {
+		t.Run(lure.Name, func(t *testing.T) {
+			filename := filepath.Join(dir, lure.Name+"."+lure.Format)
+			tok, err := GenerateTracer(lure.Name, filename, "http://127.0.0.1/t", testOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !token.Valid(tok) {
+				t.Errorf("token = %q", tok)
+			}
+
+			found := false
+			for _, data := range testutil.ReadZipFile(t, filename) {
+				if strings.Contains(data, "http://127.0.0.1/t/"+tok) {
+					found = true
+				}
+			}
+			if !found {
+				t.Error("trace url not found")
+			}
+		})
+	}
+}
+
+func TestGenerateTracerSigned(t *testing.T) {
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions
+	opts.Signer = keyring
+
+	filename := filepath.Join(t.TempDir(), "payroll.xlsx")
+	tok, err := GenerateTracer("payroll", filename, "http://127.0.0.1/t", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, data := range testutil.ReadZipFile(t, filename) {
+		if strings.Contains(data, "http://127.0.0.1/t/"+keyring.Sign(tok)) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("signed trace url not found")
+	}
+}
+
+func TestGenerateUnknown(t *testing.T) {
+	if err := Generate("unknown", filepath.Join(t.TempDir(), "a.docx"), testOptions); err != ErrLure {
+		t.Errorf("Generate() = %v, want ErrLure", err)
+	}
+	if _, err := GenerateTracer("payroll", filepath.Join(t.TempDir(), "a.xlsx"), "http://127.0.0.1/t", testOptions, "docx-template"); err == nil {
+		t.Error("GenerateTracer() with docx technique = nil")
+	}
+}
+
+func TestIncomeTax(t *testing.T) {
+	tests := []struct {
+		income, want float64
+	}{
+		{4000, 0},
+		{8000, 90},
+		{20000, 1590},
+	}
+	for _, tt := range tests {
+		if got := incomeTax(LocaleZH, tt.income); got != tt.want {
+			t.Errorf("incomeTax(%v) = %v, want %v", tt.income, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package lure
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tracer/pkg/utils"
+)
+
+const contentTypesTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/>${overrides}<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/><Override PartName="/docProps/app.xml" ContentType="application/vnd.openxmlformats-officedocument.extended-properties+xml"/></Types>`
+
+const rootRelsTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties" Target="docProps/app.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="${main}"/></Relationships>`
+
+const coreTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:dcmitype="http://purl.org/dc/dcmitype/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><dc:title>${title}</dc:title><dc:creator>${creator}</dc:creator><cp:lastModifiedBy>${modifier}</cp:lastModifiedBy><cp:revision>${revision}</cp:revision><dcterms:created xsi:type="dcterms:W3CDTF">${created}</dcterms:created><dcterms:modified xsi:type="dcterms:W3CDTF">${modified}</dcterms:modified></cp:coreProperties>`
+
+const appTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes"><Application>${application}</Application><DocSecurity>0</DocSecurity><ScaleCrop>false</ScaleCrop><Company>${company}</Company><LinksUpToDate>false</LinksUpToDate><SharedDoc>false</SharedDoc><HyperlinksChanged>false</HyperlinksChanged><AppVersion>16.0000</AppVersion></Properties>`
+
+// override [Content_Types].xml 中的 Override 节点
+type override struct {
+	PartName    string
+	ContentType string
+}
+
+// ooxmlPackage 生成的 office 文件，部件路径 => 内容
+type ooxmlPackage map[string]string
+
+// newPackage 生成包含内容类型、包关系、文档属性的 office 文件
+// main: 主文档部件，例如 word/document.xml
+// application: 应用程序名称，例如 Microsoft Office Word
+func newPackage(g *generator, main, application, title string, overrides []override) ooxmlPackage {
+	var b strings.Builder
+	for _, o := range overrides {
+		b.WriteString(`<Override PartName="` + o.PartName + `" ContentType="` + o.ContentType + `"/>`)
+	}
+
+	// 创建时间早于文档日期，修改时间为文档日期
+	created := g.date.Add(-time.Duration(between(g.r, 1, 30*24)) * time.Hour)
+	modifier := g.author
+	if g.r.Intn(2) == 0 {
+		modifier = newPerson(g.r, g.locale, g.used)
+	}
+
+	return ooxmlPackage{
+		"[Content_Types].xml": strings.Replace(contentTypesTemp, "${overrides}", b.String(), 1),
+		"_rels/.rels":         strings.Replace(rootRelsTemp, "${main}", main, 1),
+		"docProps/core.xml": strings.NewReplacer(
+			"${title}", escape(title),
+			"${creator}", escape(g.author.Name),
+			"${modifier}", escape(modifier.Name),
+			"${revision}", strconv.Itoa(between(g.r, 2, 12)),
+			"${created}", created.UTC().Format("2006-01-02T15:04:05Z"),
+			"${modified}", g.date.UTC().Format("2006-01-02T15:04:05Z"),
+		).Replace(coreTemp),
+		"docProps/app.xml": strings.NewReplacer(
+			"${application}", application,
+			"${company}", escape(g.company),
+		).Replace(appTemp),
+	}
+}
+
+// write 写入临时目录后压缩，zip 成员的修改时间与 Office 一致
+func (p ooxmlPackage) write(dstFile string) (err error) {
+	tempDir, err := os.MkdirTemp("", "lure-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	for name, content := range p {
+		filename := filepath.Join(tempDir, filepath.FromSlash(name))
+		err = utils.CreateDir(filepath.Dir(filename))
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filename, []byte(content), os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+	return utils.CompressZipTime(tempDir, dstFile, utils.OfficeZipTime)
+}
+
+// escape 转义 XML 文本
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// lang 语言标记，例如 zh-CN、en-US
+func lang(locale Locale) string {
+	return text(locale, "zh-CN", "en-US")
+}
@@ -0,0 +1,192 @@
+package lure
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 幻灯片大小 16:9，单位 EMU
+const (
+	slideWidth  = 12192000
+	slideHeight = 6858000
+)
+
+const pptxPresentationTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:presentation xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" saveSubsetFonts="1"><p:sldMasterIdLst><p:sldMasterId id="2147483648" r:id="rId1"/></p:sldMasterIdLst><p:sldIdLst>${slides}</p:sldIdLst><p:sldSz cx="12192000" cy="6858000"/><p:notesSz cx="6858000" cy="9144000"/><p:defaultTextStyle><a:defPPr><a:defRPr lang="${lang}"/></a:defPPr></p:defaultTextStyle></p:presentation>`
+
+const pptxMaster = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sldMaster xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:cSld><p:bg><p:bgRef idx="1001"><a:schemeClr val="bg1"/></p:bgRef></p:bg><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr></p:spTree></p:cSld><p:clrMap bg1="lt1" tx1="dk1" bg2="lt2" tx2="dk2" accent1="accent1" accent2="accent2" accent3="accent3" accent4="accent4" accent5="accent5" accent6="accent6" hlink="hlink" folHlink="folHlink"/><p:sldLayoutIdLst><p:sldLayoutId id="2147483649" r:id="rId1"/></p:sldLayoutIdLst><p:txStyles><p:titleStyle><a:lvl1pPr><a:defRPr sz="4400"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mj-lt"/><a:ea typeface="+mj-ea"/></a:defRPr></a:lvl1pPr></p:titleStyle><p:bodyStyle><a:lvl1pPr><a:defRPr sz="2800"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mn-lt"/><a:ea typeface="+mn-ea"/></a:defRPr></a:lvl1pPr></p:bodyStyle><p:otherStyle><a:lvl1pPr><a:defRPr sz="1800"><a:solidFill><a:schemeClr val="tx1"/></a:solidFill><a:latin typeface="+mn-lt"/><a:ea typeface="+mn-ea"/></a:defRPr></a:lvl1pPr></p:otherStyle></p:txStyles></p:sldMaster>`
+
+const pptxMasterRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme" Target="../theme/theme1.xml"/><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/></Relationships>`
+
+const pptxLayoutTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sldLayout xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" type="blank" preserve="1"><p:cSld name="${name}"><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr></p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sldLayout>`
+
+const pptxLayoutRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster" Target="../slideMasters/slideMaster1.xml"/></Relationships>`
+
+const pptxSlideRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/></Relationships>`
+
+const pptxThemeTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" name="Office Theme"><a:themeElements><a:clrScheme name="Office"><a:dk1><a:sysClr val="windowText" lastClr="000000"/></a:dk1><a:lt1><a:sysClr val="window" lastClr="FFFFFF"/></a:lt1><a:dk2><a:srgbClr val="44546A"/></a:dk2><a:lt2><a:srgbClr val="E7E6E6"/></a:lt2><a:accent1><a:srgbClr val="4472C4"/></a:accent1><a:accent2><a:srgbClr val="ED7D31"/></a:accent2><a:accent3><a:srgbClr val="A5A5A5"/></a:accent3><a:accent4><a:srgbClr val="FFC000"/></a:accent4><a:accent5><a:srgbClr val="5B9BD5"/></a:accent5><a:accent6><a:srgbClr val="70AD47"/></a:accent6><a:hlink><a:srgbClr val="0563C1"/></a:hlink><a:folHlink><a:srgbClr val="954F72"/></a:folHlink></a:clrScheme><a:fontScheme name="Office"><a:majorFont><a:latin typeface="Calibri Light"/><a:ea typeface="${font}"/><a:cs typeface=""/></a:majorFont><a:minorFont><a:latin typeface="Calibri"/><a:ea typeface="${font}"/><a:cs typeface=""/></a:minorFont></a:fontScheme><a:fmtScheme name="Office"><a:fillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"><a:tint val="50000"/></a:schemeClr></a:solidFill><a:solidFill><a:schemeClr val="phClr"><a:shade val="80000"/></a:schemeClr></a:solidFill></a:fillStyleLst><a:lnStyleLst><a:ln w="6350"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="12700"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln><a:ln w="19050"><a:solidFill><a:schemeClr val="phClr"/></a:solidFill></a:ln></a:lnStyleLst><a:effectStyleLst><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle><a:effectStyle><a:effectLst/></a:effectStyle></a:effectStyleLst><a:bgFillStyleLst><a:solidFill><a:schemeClr val="phClr"/></a:solidFill><a:solidFill><a:schemeClr val="phClr"><a:tint val="95000"/></a:schemeClr></a:solidFill><a:solidFill><a:schemeClr val="phClr"><a:shade val="90000"/></a:schemeClr></a:solidFill></a:bgFillStyleLst></a:fmtScheme></a:themeElements></a:theme>`
+
+const pptxPresProps = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:presentationPr xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"/>`
+
+const pptxViewProps = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:viewPr xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:normalViewPr horzBarState="maximized"><p:restoredLeft sz="15000"/><p:restoredTop sz="94660"/></p:normalViewPr><p:gridSpacing cx="72008" cy="72008"/></p:viewPr>`
+
+const pptxTableStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<a:tblStyleLst xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" def="{5C22544A-7EE6-4342-B048-85BDC9FD1C3A}"/>`
+
+// 形状类型
+const (
+	shapeRect      = "rect"      // 矩形
+	shapeRoundRect = "roundRect" // 圆角矩形
+	shapeText      = "text"      // 文本框，无边框和填充
+	shapeLine      = "line"      // 直线连接符
+)
+
+// shape 幻灯片中的形状，位置和大小的单位为 EMU
+type shape struct {
+	Kind         string
+	X, Y, W, H   int
+	FlipH, FlipV bool   // 直线方向
+	Text         string // 文本，换行分隔段落
+	Size         int    // 字号，单位 1/100 磅，为 0 时使用 1800
+	Bold         bool
+	Fill         string // 填充颜色，例如 4472C4，为空时使用默认颜色
+	Color        string // 文字颜色，为空时使用默认颜色
+}
+
+// buildPPTX 生成 pptx 文件，每个元素为一张幻灯片
+func buildPPTX(g *generator, title string, slides [][]shape) ooxmlPackage {
+	overrides := []override{
+		{"/ppt/presentation.xml", "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"},
+		{"/ppt/slideMasters/slideMaster1.xml", "application/vnd.openxmlformats-officedocument.presentationml.slideMaster+xml"},
+		{"/ppt/slideLayouts/slideLayout1.xml", "application/vnd.openxmlformats-officedocument.presentationml.slideLayout+xml"},
+		{"/ppt/theme/theme1.xml", "application/vnd.openxmlformats-officedocument.theme+xml"},
+		{"/ppt/presProps.xml", "application/vnd.openxmlformats-officedocument.presentationml.presProps+xml"},
+		{"/ppt/viewProps.xml", "application/vnd.openxmlformats-officedocument.presentationml.viewProps+xml"},
+		{"/ppt/tableStyles.xml", "application/vnd.openxmlformats-officedocument.presentationml.tableStyles+xml"},
+	}
+
+	// 1、幻灯片，关系 Id 从 rId2 开始，rId1 为母版
+	var (
+		ids  strings.Builder
+		rels strings.Builder
+	)
+	rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideMaster" Target="slideMasters/slideMaster1.xml"/>`)
+	for i := range slides {
+		n := strconv.Itoa(i + 1)
+		rId := "rId" + strconv.Itoa(i+2)
+		ids.WriteString(`<p:sldId id="` + strconv.Itoa(256+i) + `" r:id="` + rId + `"/>`)
+		rels.WriteString(`<Relationship Id="` + rId + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide` + n + `.xml"/>`)
+		overrides = append(overrides, override{"/ppt/slides/slide" + n + ".xml", "application/vnd.openxmlformats-officedocument.presentationml.slide+xml"})
+	}
+	for i, rel := range [][2]string{{"theme", "theme/theme1.xml"}, {"presProps", "presProps.xml"}, {"viewProps", "viewProps.xml"}, {"tableStyles", "tableStyles.xml"}} {
+		rels.WriteString(`<Relationship Id="rId` + strconv.Itoa(len(slides)+2+i) + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/` + rel[0] + `" Target="` + rel[1] + `"/>`)
+	}
+	rels.WriteString("</Relationships>")
+
+	// 2、母版、版式、主题
+	p := newPackage(g, "ppt/presentation.xml", "Microsoft Office PowerPoint", title, overrides)
+	p["ppt/presentation.xml"] = strings.NewReplacer("${slides}", ids.String(), "${lang}", lang(g.locale)).Replace(pptxPresentationTemp)
+	p["ppt/_rels/presentation.xml.rels"] = rels.String()
+	p["ppt/slideMasters/slideMaster1.xml"] = pptxMaster
+	p["ppt/slideMasters/_rels/slideMaster1.xml.rels"] = pptxMasterRels
+	p["ppt/slideLayouts/slideLayout1.xml"] = strings.Replace(pptxLayoutTemp, "${name}", text(g.locale, "空白", "Blank"), 1)
+	p["ppt/slideLayouts/_rels/slideLayout1.xml.rels"] = pptxLayoutRels
+	p["ppt/theme/theme1.xml"] = strings.Replace(pptxThemeTemp, "${font}", text(g.locale, "等线", ""), -1)
+	p["ppt/presProps.xml"] = pptxPresProps
+	p["ppt/viewProps.xml"] = pptxViewProps
+	p["ppt/tableStyles.xml"] = pptxTableStyles
+
+	// 3、幻灯片内容
+	for i, shapes := range slides {
+		n := strconv.Itoa(i + 1)
+		p["ppt/slides/slide"+n+".xml"] = pptxSlide(g.locale, shapes)
+		p["ppt/slides/_rels/slide"+n+".xml.rels"] = pptxSlideRels
+	}
+	return p
+}
+
+// pptxSlide 生成幻灯片，形状名称与 PowerPoint 插入形状时的默认名称一致
+func pptxSlide(locale Locale, shapes []shape) string {
+	var s strings.Builder
+	s.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main"><p:cSld><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr><p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr>`)
+	for i, sh := range shapes {
+		id := strconv.Itoa(i + 2)
+		name := strconv.Itoa(i + 1)
+		xfrm := `<a:off x="` + strconv.Itoa(sh.X) + `" y="` + strconv.Itoa(sh.Y) + `"/><a:ext cx="` + strconv.Itoa(sh.W) + `" cy="` + strconv.Itoa(sh.H) + `"/>`
+
+		if sh.Kind == shapeLine {
+			flip := ""
+			if sh.FlipH {
+				flip += ` flipH="1"`
+			}
+			if sh.FlipV {
+				flip += ` flipV="1"`
+			}
+			s.WriteString(`<p:cxnSp><p:nvCxnSpPr><p:cNvPr id="` + id + `" name="` + text(locale, "直接连接符 ", "Straight Connector ") + name + `"/><p:cNvCxnSpPr/><p:nvPr/></p:nvCxnSpPr><p:spPr><a:xfrm` + flip + `>` + xfrm + `</a:xfrm><a:prstGeom prst="line"><a:avLst/></a:prstGeom><a:ln w="19050"><a:solidFill><a:srgbClr val="7F7F7F"/></a:solidFill></a:ln></p:spPr></p:cxnSp>`)
+			continue
+		}
+
+		var (
+			kind  string
+			nvPr  string
+			geom  = sh.Kind
+			style string
+			color = "FFFFFF"
+		)
+		switch sh.Kind {
+		case shapeText:
+			kind = text(locale, "文本框 ", "TextBox ")
+			nvPr = `<p:cNvSpPr txBox="1"/>`
+			geom = "rect"
+			style = `<a:noFill/>`
+			color = "404040"
+		case shapeRoundRect:
+			kind = text(locale, "矩形: 圆角 ", "Rectangle: Rounded Corners ")
+			nvPr = `<p:cNvSpPr/>`
+		default:
+			kind = text(locale, "矩形 ", "Rectangle ")
+			nvPr = `<p:cNvSpPr/>`
+		}
+		if sh.Kind != shapeText {
+			fill := sh.Fill
+			if fill == "" {
+				fill = "4472C4"
+			}
+			style = `<a:solidFill><a:srgbClr val="` + fill + `"/></a:solidFill><a:ln w="12700"><a:solidFill><a:srgbClr val="2F528F"/></a:solidFill></a:ln>`
+		}
+
+		if sh.Color != "" {
+			color = sh.Color
+		}
+		size := sh.Size
+		if size == 0 {
+			size = 1800
+		}
+		algn := "ctr"
+		if sh.Kind == shapeText {
+			algn = "l"
+		}
+
+		s.WriteString(`<p:sp><p:nvSpPr><p:cNvPr id="` + id + `" name="` + kind + name + `"/>` + nvPr + `<p:nvPr/></p:nvSpPr><p:spPr><a:xfrm>` + xfrm + `</a:xfrm><a:prstGeom prst="` + geom + `"><a:avLst/></a:prstGeom>` + style + `</p:spPr><p:txBody><a:bodyPr wrap="square" rtlCol="0" anchor="ctr"/><a:lstStyle/>`)
+		for _, line := range strings.Split(sh.Text, "\n") {
+			b := ""
+			if sh.Bold {
+				b = ` b="1"`
+			}
+			s.WriteString(`<a:p><a:pPr algn="` + algn + `"/><a:r><a:rPr lang="` + lang(locale) + `" altLang="en-US" sz="` + strconv.Itoa(size) + `"` + b + ` dirty="0"><a:solidFill><a:srgbClr val="` + color + `"/></a:solidFill></a:rPr><a:t>` + escape(line) + `</a:t></a:r></a:p>`)
+		}
+		s.WriteString(`</p:txBody></p:sp>`)
+	}
+	s.WriteString(`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`)
+	return s.String()
+}
@@ -0,0 +1,288 @@
+package lure
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerLure(&Lure{
+		Name:        "payroll",
+		Format:      "xlsx",
+		Description: "月度工资表：姓名、部门、职位、工资明细、银行卡号",
+		FileName:    map[Locale]string{LocaleZH: "${year}年${month}月工资表", LocaleEN: "Payroll ${year}-${month}"},
+		build:       payroll,
+	})
+	registerLure(&Lure{
+		Name:        "credentials",
+		Format:      "docx",
+		Description: "运维账号密码汇总：VPN、堡垒机、vCenter、域控、数据库等",
+		FileName:    map[Locale]string{LocaleZH: "运维系统账号密码汇总", LocaleEN: "IT Systems Credentials"},
+		build:       credentials,
+	})
+	registerLure(&Lure{
+		Name:        "network",
+		Format:      "pptx",
+		Description: "网络拓扑规划：拓扑图、VLAN 与网段分配",
+		FileName:    map[Locale]string{LocaleZH: "${year}年网络拓扑规划", LocaleEN: "Network Topology ${year}"},
+		build:       network,
+	})
+	registerLure(&Lure{
+		Name:        "board-minutes",
+		Format:      "docx",
+		Description: "董事会会议纪要：出席人员、议题、决议",
+		FileName:    map[Locale]string{LocaleZH: "第${month}次董事会会议纪要", LocaleEN: "Board Meeting Minutes ${year}-${month}"},
+		build:       boardMinutes,
+	})
+}
+
+// payroll 工资表
+func payroll(g *generator) ooxmlPackage {
+	l := g.locale
+	month := g.date
+	title := text(l, fmt.Sprintf("%d年%d月工资表", month.Year(), month.Month()), fmt.Sprintf("Payroll %s", month.Format("January 2006")))
+	s := sheet{
+		Name:   text(l, fmt.Sprintf("%d年%d月", month.Year(), month.Month()), month.Format("Jan 2006")),
+		Widths: []float64{10, 12, 14, 14, 12, 12, 14, 12, 12, 24},
+		Rows: [][]cell{{
+			{Text: text(l, "工号", "Employee ID")},
+			{Text: text(l, "姓名", "Name")},
+			{Text: text(l, "部门", "Department")},
+			{Text: text(l, "职位", "Title")},
+			{Text: text(l, "基本工资", "Base Salary")},
+			{Text: text(l, "绩效奖金", "Bonus")},
+			{Text: text(l, "社保公积金", "Benefits")},
+			{Text: text(l, "个人所得税", "Income Tax")},
+			{Text: text(l, "实发工资", "Net Pay")},
+			{Text: text(l, "银行卡号", "Bank Account")},
+		}},
+	}
+
+	// 按照部门、职位级别排序
+	people := make([]person, between(g.r, 24, 40))
+	for i := range people {
+		people[i] = newPerson(g.r, l, g.used)
+	}
+	sort.SliceStable(people, func(i, j int) bool {
+		if people[i].Dept != people[j].Dept {
+			return people[i].Dept < people[j].Dept
+		}
+		return people[i].Level < people[j].Level
+	})
+
+	id := between(g.r, 1000, 3000)
+	for _, p := range people {
+		id += between(g.r, 1, 20)
+		salary := salaryRanges[p.Level]
+		base := float64(between(g.r, salary[0], salary[1]) / 100 * 100)
+		bonus := math.Round(base*float64(g.r.Intn(30))/100/10) * 10
+		benefits := math.Round(base*text(l, 0.225, 0.0765)*100) / 100
+		tax := incomeTax(l, base+bonus-benefits)
+		s.Rows = append(s.Rows, []cell{
+			{Text: fmt.Sprintf("%s%05d", text(l, "HR", "E"), id)},
+			{Text: p.Name},
+			{Text: p.Dept},
+			{Text: p.Title},
+			{Number: base},
+			{Number: bonus},
+			{Number: benefits},
+			{Number: tax},
+			{Number: math.Round((base+bonus-benefits-tax)*100) / 100},
+			{Text: bankAccount(g, l)},
+		})
+	}
+	return buildXLSX(g, title, s)
+}
+
+// incomeTax 个人所得税
+// 中文使用月度累进税率，起征点 5000；英文使用固定比例
+func incomeTax(locale Locale, income float64) float64 {
+	if locale != LocaleZH {
+		return math.Round(income*0.22*100) / 100
+	}
+
+	brackets := []struct {
+		limit, rate, deduction float64
+	}{
+		{3000, 0.03, 0}, {12000, 0.10, 210}, {25000, 0.20, 1410}, {35000, 0.25, 2660},
+		{55000, 0.30, 4410}, {80000, 0.35, 7160}, {math.MaxFloat64, 0.45, 15160},
+	}
+	taxable := income - 5000
+	if taxable <= 0 {
+		return 0
+	}
+	for _, b := range brackets {
+		if taxable <= b.limit {
+			return math.Round((taxable*b.rate-b.deduction)*100) / 100
+		}
+	}
+	return 0
+}
+
+// bankAccount 银行卡号，中文为 19 位借记卡号，英文为 10 位账号
+func bankAccount(g *generator, locale Locale) string {
+	if locale != LocaleZH {
+		return fmt.Sprintf("%010d", g.r.Int63n(1e10))
+	}
+	number := pick(g.r, []string{"622202", "621700", "622848", "621483", "622588"}) + fmt.Sprintf("%013d", g.r.Int63n(1e13))
+	return number[:4] + " " + number[4:8] + " " + number[8:12] + " " + number[12:16] + " " + number[16:]
+}
+
+// credentials 账号密码汇总
+func credentials(g *generator) ooxmlPackage {
+	l := g.locale
+	subnet := newSubnet(g.r)
+	year := g.date.Year()
+	short := strings.ToUpper(strings.SplitN(g.domain, ".", 2)[0])
+	title := text(l, "运维系统账号密码汇总", "IT Systems Credentials")
+
+	owners := make([]person, 4)
+	for i := range owners {
+		owners[i] = newPerson(g.r, l, g.used)
+	}
+	owner := func() string {
+		return pick(g.r, owners).Name
+	}
+
+	rows := [][]string{
+		{text(l, "系统", "System"), text(l, "地址", "Address"), text(l, "账号", "Account"), text(l, "密码", "Password"), text(l, "负责人", "Owner"), text(l, "备注", "Notes")},
+		{"VPN", "https://vpn." + g.domain, g.author.Username, newPassword(g.r, year), g.author.Name, text(l, "需要 OTP，临时账号", "OTP required, temporary account")},
+		{text(l, "堡垒机", "Jump Server"), newIP(g.r, subnet) + ":22", "root", newPassword(g.r, year), owner(), text(l, "所有服务器经堡垒机登录", "All servers via jump host")},
+		{"vCenter", "https://vcenter." + g.domain + "/ui", "administrator@vsphere.local", newPassword(g.r, year), owner(), "ESXi 7.0"},
+		{text(l, "AD 域管理员", "AD Domain Admin"), "dc01." + g.domain, short + `\administrator`, newPassword(g.r, year), owner(), text(l, "勿修改，多个服务使用", "Do not change, shared by services")},
+		{text(l, "MySQL 主库", "MySQL Primary"), newIP(g.r, subnet) + ":3306", "root", newPassword(g.r, year), owner(), text(l, "从库只读账号 readonly", "Replica read-only user: readonly")},
+		{"GitLab", "https://git." + g.domain, "root", newPassword(g.r, year), owner(), text(l, "开启 2FA 前使用", "Until 2FA is enabled")},
+		{"Jenkins", "http://" + newIP(g.r, subnet) + ":8080", "admin", newPassword(g.r, year), owner(), ""},
+		{text(l, "防火墙", "Firewall"), "https://" + subnet + ".1:4443", "admin", newPassword(g.r, year), owner(), text(l, "修改策略前请备份配置", "Back up config before changes")},
+		{"NAS", `\\nas.` + g.domain + `\share`, "nasadmin", newPassword(g.r, year), owner(), ""},
+		{text(l, "办公 Wi-Fi", "Office Wi-Fi"), "SSID: " + short + "-Office", "-", newPassword(g.r, year), owner(), text(l, "访客网络 "+short+"-Guest", "Guest network "+short+"-Guest")},
+	}
+
+	return buildDOCX(g, title, []block{
+		{Style: "Title", Text: title},
+		{Text: text(l, "仅限运维部内部使用，请勿外传。更新日期：", "Internal use only. Do not distribute. Updated: ") + g.date.Format("2006-01-02"), Bold: true},
+		{Text: text(l, "所有密码每季度更换一次，更换后请同步更新本文档。", "Passwords are rotated quarterly. Please update this document after each rotation.")},
+		{Table: rows},
+		{Style: "Heading1", Text: text(l, "说明", "Notes")},
+		{Text: text(l,
+			fmt.Sprintf("1、内网地址段 %s.0/24，网关 %s.1。\n2、如遇账号锁定请联系 %s。", subnet, subnet, g.author.Name),
+			fmt.Sprintf("1. Internal network %s.0/24, gateway %s.1.\n2. Contact %s if an account is locked.", subnet, subnet, g.author.Name),
+		)},
+	})
+}
+
+// network 网络拓扑规划
+func network(g *generator) ooxmlPackage {
+	l := g.locale
+	title := text(l, fmt.Sprintf("%d年网络拓扑规划", g.date.Year()), fmt.Sprintf("Network Topology %d", g.date.Year()))
+
+	// 1、标题页
+	cover := []shape{
+		{Kind: shapeText, X: 1524000, Y: 2130000, W: 9144000, H: 1470000, Text: title, Size: 4400, Bold: true},
+		{Kind: shapeText, X: 1524000, Y: 3700000, W: 9144000, H: 900000, Text: g.company + "\n" + g.author.Dept + "  " + g.author.Name + "  " + g.date.Format("2006-01-02"), Size: 2000},
+	}
+
+	// 2、拓扑图：互联网、防火墙、核心交换机，下接 DMZ、服务器区、办公区
+	const w, h = 2400000, 720000
+	cx := (slideWidth - w) / 2
+	areas := []string{text(l, "DMZ 区", "DMZ"), text(l, "服务器区", "Server Zone"), text(l, "办公区", "Office Zone")}
+	diagram := []shape{
+		{Kind: shapeText, X: 457200, Y: 274638, W: 10972800, H: 800000, Text: text(l, "网络拓扑", "Topology"), Size: 3200, Bold: true},
+		{Kind: shapeRoundRect, X: cx, Y: 1200000, W: w, H: h, Text: text(l, "互联网", "Internet"), Fill: "A5A5A5"},
+		{Kind: shapeRect, X: cx, Y: 2300000, W: w, H: h, Text: text(l, "防火墙", "Firewall"), Fill: "ED7D31"},
+		{Kind: shapeRect, X: cx, Y: 3400000, W: w, H: h, Text: text(l, "核心交换机", "Core Switch")},
+		{Kind: shapeLine, X: cx + w/2, Y: 1200000 + h, W: 0, H: 2300000 - 1200000 - h},
+		{Kind: shapeLine, X: cx + w/2, Y: 2300000 + h, W: 0, H: 3400000 - 2300000 - h},
+	}
+	vlans := make([][3]string, len(areas))
+	for i, area := range areas {
+		x := 1000000 + i*(slideWidth-2000000-w)/(len(areas)-1)
+		diagram = append(diagram, shape{Kind: shapeRect, X: x, Y: 5100000, W: w, H: h, Text: area, Fill: "70AD47"})
+		// 连接符从核心交换机底部连接到各区域顶部，左侧区域需要水平翻转
+		line := shape{Kind: shapeLine, Y: 3400000 + h, H: 5100000 - 3400000 - h}
+		if x+w/2 < cx+w/2 {
+			line.X, line.W, line.FlipH = x+w/2, cx+w/2-(x+w/2), true
+		} else {
+			line.X, line.W = cx+w/2, x+w/2-(cx+w/2)
+		}
+		diagram = append(diagram, line)
+		vlans[i] = [3]string{fmt.Sprint(10 * (i + 1)), newSubnet(g.r), area}
+	}
+
+	// 3、VLAN 与网段分配
+	plan := []shape{
+		{Kind: shapeText, X: 457200, Y: 274638, W: 10972800, H: 800000, Text: text(l, "VLAN 与网段分配", "VLAN and Subnet Plan"), Size: 3200, Bold: true},
+	}
+	header := []string{"VLAN", text(l, "网段", "Subnet"), text(l, "网关", "Gateway"), text(l, "用途", "Usage")}
+	colW := 2600000
+	for i, name := range header {
+		plan = append(plan, shape{Kind: shapeRect, X: 457200 + i*colW, Y: 1400000, W: colW, H: 500000, Text: name, Size: 1600, Bold: true})
+	}
+	for i, vlan := range vlans {
+		values := []string{vlan[0], vlan[1] + ".0/24", vlan[1] + ".1", vlan[2]}
+		for j, value := range values {
+			plan = append(plan, shape{Kind: shapeRect, X: 457200 + j*colW, Y: 1900000 + i*500000, W: colW, H: 500000, Text: value, Size: 1400, Fill: "D9E1F2", Color: "404040"})
+		}
+	}
+	plan = append(plan, shape{Kind: shapeText, X: 457200, Y: 1900000 + len(vlans)*500000 + 300000, W: 10400000, H: 900000, Size: 1400, Text: text(l,
+		fmt.Sprintf("管理网段 %s.0/24，交换机管理账号 admin，详见《运维系统账号密码汇总》。", newSubnet(g.r)),
+		fmt.Sprintf("Management subnet %s.0/24, switch admin account: admin. See \"IT Systems Credentials\".", newSubnet(g.r)),
+	)})
+	return buildPPTX(g, title, [][]shape{cover, diagram, plan})
+}
+
+// boardMinutes 董事会会议纪要
+func boardMinutes(g *generator) ooxmlPackage {
+	l := g.locale
+	title := text(l, fmt.Sprintf("%s第%d次董事会会议纪要", g.company, g.date.Month()), fmt.Sprintf("%s Board Meeting Minutes", g.company))
+
+	directors := make([]string, between(g.r, 5, 7))
+	for i := range directors {
+		directors[i] = newPerson(g.r, l, g.used).Name
+	}
+	secretary := newPerson(g.r, l, g.used).Name
+	sep := text(l, "、", ", ")
+	amount := between(g.r, 20, 90) * 100
+	target := pick(g.r, companies[l])
+	for target == g.company {
+		target = pick(g.r, companies[l])
+	}
+
+	return buildDOCX(g, title, []block{
+		{Style: "Title", Text: title},
+		{Text: text(l, "【保密】本纪要仅供董事会成员及高级管理人员查阅", "CONFIDENTIAL - For board members and senior management only"), Bold: true},
+		{Table: [][]string{
+			{text(l, "项目", "Item"), text(l, "内容", "Details")},
+			{text(l, "会议时间", "Date"), g.date.Format(text(l, "2006年1月2日 15:04", "January 2, 2006 15:04"))},
+			{text(l, "会议地点", "Location"), text(l, "公司总部 3 楼第一会议室", "Headquarters, Conference Room 3A")},
+			{text(l, "出席董事", "Directors Present"), strings.Join(directors, sep)},
+			{text(l, "主持人", "Chair"), directors[0]},
+			{text(l, "记录人", "Secretary"), secretary},
+		}},
+		{Style: "Heading1", Text: text(l, "一、审议第三季度财务报告", "1. Q3 Financial Report")},
+		{Text: text(l,
+			fmt.Sprintf("财务负责人汇报了第三季度经营情况，营业收入同比增长 %d%%，净利润率 %d%%。董事会一致通过该报告。", between(g.r, 3, 25), between(g.r, 5, 18)),
+			fmt.Sprintf("The CFO presented Q3 results: revenue up %d%% year over year, net margin %d%%. The board approved the report unanimously.", between(g.r, 3, 25), between(g.r, 5, 18)),
+		)},
+		{Style: "Heading1", Text: text(l, "二、审议收购事项", "2. Proposed Acquisition")},
+		{Text: text(l,
+			fmt.Sprintf("董事会讨论了收购%s %d%% 股权的方案，交易对价不超过人民币 %d 万元。该事项尚未公开披露，请各位董事严格保密。", target, between(g.r, 51, 100), amount),
+			fmt.Sprintf("The board discussed acquiring %d%% of %s for no more than $%d thousand. This matter has not been disclosed; all directors must keep it strictly confidential.", between(g.r, 51, 100), target, amount),
+		)},
+		{Style: "Heading1", Text: text(l, "三、审议高级管理人员薪酬调整", "3. Executive Compensation")},
+		{Text: text(l,
+			fmt.Sprintf("同意高级管理人员年度薪酬整体上调 %d%%，具体方案由薪酬委员会制定。", between(g.r, 3, 12)),
+			fmt.Sprintf("Approved an overall %d%% increase in executive compensation; details to be set by the compensation committee.", between(g.r, 3, 12)),
+		)},
+		{Style: "Heading1", Text: text(l, "决议", "Resolutions")},
+		{Text: text(l,
+			fmt.Sprintf("以上议案经出席董事 %d 票同意、0 票反对、0 票弃权通过。", len(directors)),
+			fmt.Sprintf("The above proposals were approved with %d votes for, 0 against and 0 abstentions.", len(directors)),
+		)},
+		{},
+		{Text: text(l, "记录人：", "Recorded by: ") + secretary, Right: true},
+		{Text: g.date.Format(text(l, "2006年1月2日", "January 2, 2006")), Right: true},
+	})
+}
@@ -0,0 +1,120 @@
+package lure
+
+import (
+	"strconv"
+	"strings"
+)
+
+const xlsxWorkbookTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><fileVersion appName="xl" lastEdited="7" lowestEdited="7" rupBuild="27328"/><workbookPr defaultThemeVersion="166925"/><bookViews><workbookView xWindow="0" yWindow="0" windowWidth="28800" windowHeight="12300"/></bookViews><sheets><sheet name="${sheet}" sheetId="1" r:id="rId1"/></sheets>${names}<calcPr calcId="191029"/></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings" Target="sharedStrings.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+// 样式：0 默认，1 加粗表头，2 千分位两位小数
+const xlsxStylesTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="${font}"/><family val="2"/><charset val="${charset}"/><scheme val="minor"/></font><font><b/><sz val="11"/><name val="${font}"/><family val="2"/><charset val="${charset}"/><scheme val="minor"/></font></fonts><fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/><bgColor indexed="64"/></patternFill></fill></fills><borders count="2"><border><left/><right/><top/><bottom/><diagonal/></border><border><left style="thin"><color auto="1"/></left><right style="thin"><color auto="1"/></right><top style="thin"><color auto="1"/></top><bottom style="thin"><color auto="1"/></bottom><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="2" borderId="1" xfId="0" applyFont="1" applyFill="1" applyBorder="1"/><xf numFmtId="4" fontId="0" fillId="0" borderId="1" xfId="0" applyNumberFormat="1" applyBorder="1"/></cellXfs><cellStyles count="1"><cellStyle name="${normal}" xfId="0" builtinId="0"/></cellStyles><dxfs count="0"/><tableStyles count="0" defaultTableStyle="TableStyleMedium2" defaultPivotStyle="PivotStyleLight16"/></styleSheet>`
+
+// cell 单元格，Text 为空时使用 Number
+type cell struct {
+	Text   string
+	Number float64
+}
+
+// sheet 工作表，第一行为表头
+type sheet struct {
+	Name   string
+	Rows   [][]cell
+	Widths []float64 // 列宽
+}
+
+// buildXLSX 生成只有一个工作表的 xlsx 文件
+// 表头加粗并冻结，添加自动筛选
+func buildXLSX(g *generator, title string, s sheet) ooxmlPackage {
+	var (
+		strs  []string
+		index = make(map[string]int)
+		data  strings.Builder
+		cols  strings.Builder
+	)
+
+	// 1、共享字符串
+	shared := func(str string) int {
+		if i, ok := index[str]; ok {
+			return i
+		}
+		index[str] = len(strs)
+		strs = append(strs, str)
+		return index[str]
+	}
+
+	// 2、单元格
+	count := 0
+	for i, row := range s.Rows {
+		data.WriteString(`<row r="` + strconv.Itoa(i+1) + `">`)
+		for j, c := range row {
+			ref := xlsxColumn(j) + strconv.Itoa(i+1)
+			style := "2"
+			if i == 0 {
+				style = "1"
+			}
+			if c.Text != "" {
+				count++
+				data.WriteString(`<c r="` + ref + `" s="` + style + `" t="s"><v>` + strconv.Itoa(shared(c.Text)) + `</v></c>`)
+			} else {
+				data.WriteString(`<c r="` + ref + `" s="` + style + `"><v>` + strconv.FormatFloat(c.Number, 'f', -1, 64) + `</v></c>`)
+			}
+		}
+		data.WriteString("</row>")
+	}
+	for i, width := range s.Widths {
+		if i == 0 {
+			cols.WriteString("<cols>")
+		}
+		n := strconv.Itoa(i + 1)
+		cols.WriteString(`<col min="` + n + `" max="` + n + `" width="` + strconv.FormatFloat(width, 'f', -1, 64) + `" customWidth="1"/>`)
+	}
+
+	var sst strings.Builder
+	for _, str := range strs {
+		sst.WriteString(`<si><t>` + escape(str) + `</t></si>`)
+	}
+
+	last := "A1"
+	names := ""
+	if len(s.Rows) > 0 {
+		last = xlsxColumn(len(s.Rows[0])-1) + strconv.Itoa(len(s.Rows))
+		names = `<definedNames><definedName name="_xlnm._FilterDatabase" localSheetId="0" hidden="1">'` + escape(s.Name) + `'!$A$1:$` + xlsxColumn(len(s.Rows[0])-1) + `$` + strconv.Itoa(len(s.Rows)) + `</definedName></definedNames>`
+	}
+	if cols.Len() > 0 {
+		cols.WriteString("</cols>")
+	}
+
+	p := newPackage(g, "xl/workbook.xml", "Microsoft Excel", title, []override{
+		{"/xl/workbook.xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"},
+		{"/xl/worksheets/sheet1.xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"},
+		{"/xl/styles.xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"},
+		{"/xl/sharedStrings.xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.sharedStrings+xml"},
+	})
+	p["xl/workbook.xml"] = strings.NewReplacer("${sheet}", escape(s.Name), "${names}", names).Replace(xlsxWorkbookTemp)
+	p["xl/_rels/workbook.xml.rels"] = xlsxWorkbookRels
+	p["xl/styles.xml"] = strings.NewReplacer(
+		"${font}", text(g.locale, "等线", "Calibri"),
+		"${charset}", text(g.locale, "134", "0"),
+		"${normal}", text(g.locale, "常规", "Normal"),
+	).Replace(xlsxStylesTemp)
+	p["xl/sharedStrings.xml"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="` + strconv.Itoa(count) + `" uniqueCount="` + strconv.Itoa(len(strs)) + `">` + sst.String() + `</sst>`
+	p["xl/worksheets/sheet1.xml"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><dimension ref="A1:` + last + `"/><sheetViews><sheetView tabSelected="1" workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/><selection pane="bottomLeft" activeCell="A2" sqref="A2"/></sheetView></sheetViews><sheetFormatPr defaultRowHeight="14.25"/>` + cols.String() + `<sheetData>` + data.String() + `</sheetData><autoFilter ref="A1:` + last + `"/><pageMargins left="0.7" right="0.7" top="0.75" bottom="0.75" header="0.3" footer="0.3"/></worksheet>`
+	return p
+}
+
+// xlsxColumn 列名，例如 0 => A，26 => AA
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
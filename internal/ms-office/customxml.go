@@ -0,0 +1,39 @@
+package ms_office
+
+import (
+	"github.com/beevik/etree"
+)
+
+const docxCustomXmlType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXml"
+
+// traceDOCXCustomXml 在 document.xml.rels 中添加外部 customXml 数据存储部件，打开文档时加载到数据存储
+// document.xml.rels 中的 rId9999 已用于正文远程图片，外部的 customXml 关系只由追踪添加，作为追踪信息的标识
+func traceDOCXCustomXml(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 1、读取 document.xml.rels 文件
+	relsFile := partRels(tempDir, docxMainPart(tempDir))
+	document, err = readRels(relsFile)
+	if err != nil {
+		return err
+	}
+
+	// 2、已存在追踪信息时只替换追踪地址，文档原有的 customXml 部件均为内部关系
+	relationships := document.SelectElement("Relationships")
+	for _, element := range findRels(relationships, docxCustomXmlType) {
+		if element.SelectAttrValue("TargetMode", "") == "External" {
+			element.CreateAttr("Target", traceUrl)
+			return writeRels(document, relsFile)
+		}
+	}
+
+	node := relationships.CreateElement("Relationship")
+	node.CreateAttr("Id", nextRelId(relationships))
+	node.CreateAttr("Type", docxCustomXmlType)
+	node.CreateAttr("Target", traceUrl)
+	node.CreateAttr("TargetMode", "External")
+
+	return writeRels(document, relsFile)
+}
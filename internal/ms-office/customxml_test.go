@@ -0,0 +1,41 @@
+package ms_office
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+)
+
+func TestTraceDOCXCustomXml(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	// 文档原有的内部 customXml 部件不修改
+	files := testDOCX()
+	files["customXml/item1.xml"] = `<b:Sources xmlns:b="http://schemas.openxmlformats.org/officeDocument/2006/bibliography"/>`
+	files["word/_rels/document.xml.rels"] = strings.Replace(testDocumentRels, "</Relationships>",
+		`<Relationship Id="rId2" Type="`+docxCustomXmlType+`" Target="../customXml/item1.xml"/></Relationships>`, 1)
+
+	srcFile := testutil.WriteZip(t, "source.zip", files)
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	if err := GenTracer(srcFile, dstFile, "http://localhost:9090/old", "docx-customxml"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重复生成时只替换追踪地址
+	if err := GenTracer(dstFile, dstFile+"2", traceUrl, "docx-customxml"); err != nil {
+		t.Fatal(err)
+	}
+
+	rels := testutil.ReadZipFile(t, dstFile+"2")["word/_rels/document.xml.rels"]
+	if strings.Count(rels, docxCustomXmlType) != 2 || strings.Count(rels, `TargetMode="External"`) != 1 {
+		t.Errorf("rels = %s", rels)
+	}
+	if !strings.Contains(rels, `Target="../customXml/item1.xml"`) || !strings.Contains(rels, `Target="`+traceUrl+`"`) {
+		t.Errorf("rels = %s", rels)
+	}
+	if strings.Contains(rels, "/old") {
+		t.Errorf("old traceUrl not replaced: %s", rels)
+	}
+}
@@ -0,0 +1,23 @@
+package ms_office
+
+import (
+	"tracer/internal/token"
+)
+
+// DNSTraceUrl 生成 DNS 追踪地址，token 编码为追踪域名的子域名，signer 不为 nil 时使用签名后的 token
+// 客户端请求远程资源前必须解析域名，出站 HTTP 被拦截时依然可以追踪
+func DNSTraceUrl(signer token.Signer, domain, tok string) string {
+	return "http://" + token.Subdomain(signer, tok, domain) + "/"
+}
+
+// GenTracerDNS 使用 DNS 追踪地址生成可追踪文件，返回生成的 token
+// domain: 追踪域名，需要将 NS 记录指向 collector 的 DNS 服务
+// signer: 签名 token，为 nil 时不签名
+func GenTracerDNS(srcFile, dstFile, domain string, signer token.Signer, names ...string) (tok string, err error) {
+	tok = token.New()
+	err = GenTracer(srcFile, dstFile, DNSTraceUrl(signer, domain, tok), names...)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
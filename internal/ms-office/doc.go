@@ -0,0 +1,165 @@
+package ms_office
+
+import (
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+
+	"tracer/pkg/utils"
+)
+
+const (
+	docIdent           = 0xa5ec
+	docFlagEncrypted   = 0x0100
+	docFlagWhichTblStm = 0x0200
+
+	// SttbfAssoc 在 FibRgFcLcb 中的位置（第 32 组 fc/lcb）
+	docSttbfAssocIndex = 32
+	// SttbfAssoc 中模板路径的位置
+	docAssocDot   = 1
+	docAssocCount = 18
+)
+
+var ErrEncrypted = errors.New("encrypted file is not supported")
+var errDoc = errors.New("invalid word document")
+
+// GenTracerDOC 生成可追踪文档（Word 97-2003）
+// 修改 Table 流中 SttbfAssoc 的模板路径（ibstAssocDot），效果等同 docx 的 attachedTemplate
+func GenTracerDOC(srcFile, dstFile, traceUrl string) (err error) {
+	var (
+		cf *utils.CompoundFile
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、读取复合文档
+	cf, err = utils.ReadCFB(srcFile)
+	if err != nil {
+		return err
+	}
+
+	// 2、修改模板路径
+	err = traceDOCTemplate(cf, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、生成新的 doc 文件
+	return utils.WriteCFB(cf, dstFile)
+}
+
+func traceDOCTemplate(cf *utils.CompoundFile, traceUrl string) error {
+	le := binary.LittleEndian
+
+	// 1、读取 FIB
+	wd := cf.Stream("WordDocument")
+	if len(wd) < 34 || le.Uint16(wd) != docIdent {
+		return errDoc
+	}
+
+	flags := le.Uint16(wd[0x0a:])
+	if flags&docFlagEncrypted != 0 {
+		return ErrEncrypted
+	}
+
+	tableName := "0Table"
+	if flags&docFlagWhichTblStm != 0 {
+		tableName = "1Table"
+	}
+	table := cf.Stream(tableName)
+	if table == nil {
+		return errDoc
+	}
+
+	// FibBase(32) csw FibRgW97 cslw FibRgLw97 cbRgFcLcb FibRgFcLcb
+	offset := 32
+	csw := int(le.Uint16(wd[offset:]))
+	offset += 2 + csw*2
+	if offset+2 > len(wd) {
+		return errDoc
+	}
+	cslw := int(le.Uint16(wd[offset:]))
+	offset += 2 + cslw*4
+	if offset+2 > len(wd) {
+		return errDoc
+	}
+	cbRgFcLcb := int(le.Uint16(wd[offset:]))
+	offset += 2
+	if cbRgFcLcb <= docSttbfAssocIndex || offset+cbRgFcLcb*8 > len(wd) {
+		return errDoc
+	}
+
+	fcOffset := offset + docSttbfAssocIndex*8
+	fc := int(le.Uint32(wd[fcOffset:]))
+	lcb := int(le.Uint32(wd[fcOffset+4:]))
+
+	// 2、读取 SttbfAssoc，替换模板路径
+	var assoc []string
+	if lcb > 0 {
+		if fc+lcb > len(table) {
+			return errDoc
+		}
+		assoc = parseDOCSttb(table[fc : fc+lcb])
+	}
+	for len(assoc) < docAssocCount {
+		assoc = append(assoc, "")
+	}
+	assoc[docAssocDot] = traceUrl
+
+	// 3、写入 Table 流，空间足够时原地覆盖，否则追加到末尾
+	sttb := buildDOCSttb(assoc)
+	if lcb > 0 && len(sttb) <= lcb {
+		copy(table[fc:], sttb)
+	} else {
+		fc = len(table)
+		table = append(table, sttb...)
+		cf.SetStream(tableName, table)
+	}
+	le.PutUint32(wd[fcOffset:], uint32(fc))
+	le.PutUint32(wd[fcOffset+4:], uint32(len(sttb)))
+	return nil
+}
+
+// parseDOCSttb 解析 STTB（扩展字符，无附加数据）
+func parseDOCSttb(b []byte) (list []string) {
+	le := binary.LittleEndian
+	if len(b) < 6 || le.Uint16(b) != 0xffff {
+		return nil
+	}
+
+	count := int(le.Uint16(b[2:]))
+	cbExtra := int(le.Uint16(b[4:]))
+	offset := 6
+	for i := 0; i < count && offset+2 <= len(b); i++ {
+		cch := int(le.Uint16(b[offset:]))
+		offset += 2
+		if offset+cch*2 > len(b) {
+			break
+		}
+		u := make([]uint16, cch)
+		for j := range u {
+			u[j] = le.Uint16(b[offset+j*2:])
+		}
+		list = append(list, string(utf16.Decode(u)))
+		offset += cch*2 + cbExtra
+	}
+	return list
+}
+
+// buildDOCSttb 生成 STTB
+func buildDOCSttb(list []string) []byte {
+	le := binary.LittleEndian
+
+	b := []byte{0xff, 0xff}
+	b = le.AppendUint16(b, uint16(len(list)))
+	b = le.AppendUint16(b, 0)
+	for _, s := range list {
+		u := utf16.Encode([]rune(s))
+		b = le.AppendUint16(b, uint16(len(u)))
+		for _, v := range u {
+			b = le.AppendUint16(b, v)
+		}
+	}
+	return b
+}
@@ -0,0 +1,113 @@
+package ms_office
+
+import (
+	"errors"
+	"os"
+	"path"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const docxFontTableType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/fontTable"
+const docxFontType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/font"
+const docxFontTableContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.fontTable+xml"
+const docxFontTableTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:fonts xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"></w:fonts>`
+
+// docxFontName 嵌入字体的名称，使用 Windows 自带但文档中很少使用的字体，不影响正文显示
+const docxFontName = "Segoe UI Semilight"
+
+// docxFontKey 嵌入字体的混淆密钥，远程字体不会被解密，使用固定值
+const docxFontKey = "{5B3F2C71-8D4E-4A26-9C1B-7E0F3D6A2B84}"
+
+// ErrEmbeddedFont 文档已经嵌入了同名字体，无法添加远程字体
+var ErrEmbeddedFont = errors.New("document already embeds the font " + docxFontName)
+
+// traceDOCXFont 在 fontTable.xml 中添加远程嵌入字体（w:embedRegular）
+func traceDOCXFont(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 1、不存在 fontTable.xml 文件时创建
+	main := docxMainPart(tempDir)
+	part, ok := relPart(tempDir, main, docxFontTableType)
+	if !ok {
+		part = path.Join(path.Dir(main), "fontTable.xml")
+	}
+	xmlFile := partFile(tempDir, part)
+	if _, err = os.Stat(xmlFile); err != nil {
+		err = os.WriteFile(xmlFile, []byte(docxFontTableTemp), os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		_, err = addRels(partRels(tempDir, main), docxFontTableType, path.Base(part))
+		if err != nil {
+			return err
+		}
+
+		err = addContentType(tempDir, "/"+part, docxFontTableContentType)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 2、读取 fontTable.xml 文件，查找同名字体
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	root := document.Root()
+	var font *etree.Element
+	for _, element := range root.SelectElements("w:font") {
+		if element.SelectAttrValue("w:name", "") == docxFontName {
+			font = element
+			break
+		}
+	}
+	if font != nil {
+		if embed := font.SelectElement("w:embedRegular"); embed != nil {
+			if embed.SelectAttrValue("r:id", "") != docxTraceId {
+				// 不修改文档原有的嵌入字体
+				return ErrEmbeddedFont
+			}
+
+			// 已存在追踪信息，只替换追踪地址
+			return setTraceRels(partRels(tempDir, part), docxTraceId, docxFontType, traceUrl)
+		}
+	}
+
+	// 3、添加/修改 fontTable.xml.rels 文件
+	err = setTraceRels(partRels(tempDir, part), docxTraceId, docxFontType, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 4、修改 fontTable.xml 文件，embedRegular 位于 w:font 的最后
+	if font == nil {
+		font = root.CreateElement("w:font")
+		font.CreateAttr("w:name", docxFontName)
+		font.CreateElement("w:panose1").CreateAttr("w:val", "020B0402040204020203")
+		font.CreateElement("w:charset").CreateAttr("w:val", "00")
+		font.CreateElement("w:family").CreateAttr("w:val", "swiss")
+		font.CreateElement("w:pitch").CreateAttr("w:val", "variable")
+	}
+	embed := font.CreateElement("w:embedRegular")
+	embed.CreateAttr("r:id", docxTraceId)
+	embed.CreateAttr("w:fontKey", docxFontKey)
+
+	if root.SelectAttr("xmlns:r") == nil {
+		root.CreateAttr("xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships")
+	}
+
+	// 更新 fontTable.xml 文件
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+	return nil
+}
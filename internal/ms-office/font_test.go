@@ -0,0 +1,68 @@
+package ms_office
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+)
+
+func TestTraceDOCXFont(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	// 已有字体表的文档，保留原有字体
+	withFonts := testDOCX()
+	withFonts["word/fontTable.xml"] = `<w:fonts xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:font w:name="Calibri"><w:charset w:val="00"/></w:font></w:fonts>`
+	withFonts["word/_rels/document.xml.rels"] = strings.Replace(testDocumentRels, "</Relationships>",
+		`<Relationship Id="rId2" Type="`+docxFontTableType+`" Target="fontTable.xml"/></Relationships>`, 1)
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		profile Profile
+	}{
+		{"create font table", testDOCX(), ProfileDefault},
+		{"existing font table", withFonts, ProfileDefault},
+		{"stealth", withFonts, ProfileStealth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcFile := testutil.WriteZip(t, "source.zip", tt.files)
+			dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+			if err := GenTracerProfile(srcFile, dstFile, traceUrl, tt.profile, "docx-font"); err != nil {
+				t.Fatal(err)
+			}
+
+			files := testutil.ReadZipFile(t, dstFile)
+			fonts := files["word/fontTable.xml"]
+			rels := files["word/_rels/fontTable.xml.rels"]
+			if strings.Count(fonts, "w:embedRegular") != 1 || !strings.Contains(fonts, docxFontName) {
+				t.Errorf("fontTable.xml = %s", fonts)
+			}
+			if !strings.Contains(rels, traceUrl) || !strings.Contains(rels, docxFontType) {
+				t.Errorf("rels = %s", rels)
+			}
+			if tt.files["word/fontTable.xml"] != "" && !strings.Contains(fonts, `w:name="Calibri"`) {
+				t.Error("existing font removed")
+			}
+			if strings.Count(files["word/_rels/document.xml.rels"], docxFontTableType) != 1 {
+				t.Error("fontTable relationship count != 1")
+			}
+			if tt.profile == ProfileStealth && strings.Contains(fonts+rels, docxTraceId) {
+				t.Errorf("stealth: %s still uses %s", rels, docxTraceId)
+			}
+		})
+	}
+
+	// 文档原有的同名嵌入字体不修改
+	files := testDOCX()
+	files["word/fontTable.xml"] = `<w:fonts xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:font w:name="` + docxFontName + `"><w:embedRegular r:id="rId1" w:fontKey="{00000000-0000-0000-0000-000000000000}"/></w:font></w:fonts>`
+	files["word/_rels/document.xml.rels"] = withFonts["word/_rels/document.xml.rels"]
+	srcFile := testutil.WriteZip(t, "source.zip", files)
+	err := GenTracer(srcFile, filepath.Join(t.TempDir(), "tracer.docx"), traceUrl, "docx-font")
+	if !errors.Is(err, ErrEmbeddedFont) {
+		t.Errorf("error = %v, want ErrEmbeddedFont", err)
+	}
+}
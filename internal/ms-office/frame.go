@@ -0,0 +1,123 @@
+package ms_office
+
+import (
+	"errors"
+	"os"
+	"path"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const docxFrameType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/frame"
+const docxWebSettingsType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/webSettings"
+const docxWebSettingsContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.webSettings+xml"
+const docxWebSettingsTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:webSettings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"></w:webSettings>`
+const docxFramesetTemp = `<w:frameset>
+    <w:framesetSplitbar>
+        <w:w w:val="60"/>
+        <w:color w:val="auto"/>
+        <w:noBorder/>
+    </w:framesetSplitbar>
+    <w:frameset>
+        <w:frame>
+            <w:name w:val="1"/>
+            <w:sourceFileName r:id="rId9999"/>
+            <w:linkedToFile/>
+        </w:frame>
+    </w:frameset>
+</w:frameset>`
+
+// ErrFrameset 文档已经是框架页，无法添加外部框架
+var ErrFrameset = errors.New("document already has a frameset")
+
+// traceDOCXFrame 在 webSettings.xml 中添加外部框架
+func traceDOCXFrame(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 1、不存在 webSettings.xml 文件时创建
+	main := docxMainPart(tempDir)
+	part, ok := relPart(tempDir, main, docxWebSettingsType)
+	if !ok {
+		part = path.Join(path.Dir(main), "webSettings.xml")
+	}
+	xmlFile := partFile(tempDir, part)
+	if _, err = os.Stat(xmlFile); err != nil {
+		err = os.WriteFile(xmlFile, []byte(docxWebSettingsTemp), os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		relsFile := partRels(tempDir, main)
+		document, err = readRels(relsFile)
+		if err != nil {
+			return err
+		}
+
+		relationships := document.SelectElement("Relationships")
+		if len(findRels(relationships, docxWebSettingsType)) == 0 {
+			node := relationships.CreateElement("Relationship")
+			node.CreateAttr("Id", nextRelId(relationships))
+			node.CreateAttr("Type", docxWebSettingsType)
+			node.CreateAttr("Target", path.Base(part))
+
+			err = writeRels(document, relsFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = addContentType(tempDir, "/"+part, docxWebSettingsContentType)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 2、读取 webSettings.xml 文件，先检查框架页，再修改 webSettings.xml.rels 文件，避免留下孤立的外部关系
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	root := document.Root()
+	for _, element := range root.FindElements("//w:sourceFileName") {
+		if element.SelectAttrValue("r:id", "") == docxTraceId {
+			// 已存在追踪信息，只替换追踪地址
+			return setTraceRels(partRels(tempDir, part), docxTraceId, docxFrameType, traceUrl)
+		}
+	}
+
+	// 已存在 frameset 时不再修改，避免破坏原有框架页
+	if root.SelectElement("w:frameset") != nil {
+		return ErrFrameset
+	}
+
+	err = setTraceRels(partRels(tempDir, part), docxTraceId, docxFrameType, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、修改 webSettings.xml 文件
+	n := etree.NewDocument()
+	err = n.ReadFromString(docxFramesetTemp)
+	if err != nil {
+		return err
+	}
+
+	// frameset 必须是 webSettings 的第一个子节点
+	root.InsertChildAt(0, n.Root())
+	if root.SelectAttr("xmlns:r") == nil {
+		root.CreateAttr("xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships")
+	}
+
+	// 更新 webSettings.xml 文件
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+	return nil
+}
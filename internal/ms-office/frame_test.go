@@ -0,0 +1,42 @@
+package ms_office
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+	"tracer/pkg/utils"
+)
+
+func TestTraceDOCXFrameExisting(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	// 已经是框架页的文档，不能添加外部框架，也不能留下外部关系
+	files := testDOCX()
+	files["word/webSettings.xml"] = `<w:webSettings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:frameset><w:frame><w:name w:val="1"/></w:frame></w:frameset></w:webSettings>`
+	files["word/_rels/document.xml.rels"] = strings.Replace(testDocumentRels, "</Relationships>",
+		`<Relationship Id="rId2" Type="`+docxWebSettingsType+`" Target="webSettings.xml"/></Relationships>`, 1)
+
+	srcFile := testutil.WriteZip(t, "source.zip", files)
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	err := GenTracerProfile(srcFile, dstFile, traceUrl, ProfileDefault, "docx-frame")
+	if !errors.Is(err, ErrFrameset) {
+		t.Fatalf("error = %v, want ErrFrameset", err)
+	}
+
+	tempDir, err := utils.ExtractZip(srcFile, "file-trace-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err = traceDOCXFrame(tempDir, traceUrl); !errors.Is(err, ErrFrameset) {
+		t.Fatalf("error = %v, want ErrFrameset", err)
+	}
+	if _, err = os.Stat(filepath.Join(tempDir, "word", "_rels", "webSettings.xml.rels")); err == nil {
+		t.Error("webSettings.xml.rels should not be created")
+	}
+}
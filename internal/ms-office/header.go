@@ -0,0 +1,265 @@
+package ms_office
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tracer/internal/assets"
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const docxImageType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+const docxHeaderType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/header"
+const docxFooterType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer"
+const docxHeaderContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"
+
+// GenTracerDOCXHeader 生成可追踪文档（页眉/页脚）
+// 远程图片写入 word/header*.xml、word/footer*.xml，每一页都会渲染
+// 文档不存在页眉/页脚时，自动创建页眉并添加到 sectPr 中
+func GenTracerDOCXHeader(srcFile, dstFile, traceUrl string) (err error) {
+	var (
+		tempDir string
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、解压 docx 文件
+	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
+	if err != nil {
+		return err
+	}
+
+	// 2、添加页眉/页脚追踪信息
+	err = traceDOCXHeader(tempDir, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、压缩文件夹，生成新的 docx 文件
+	err = utils.CompressZip(tempDir, dstFile)
+	if err != nil {
+		return err
+	}
+
+	// 4、删除临时目录
+	err = os.RemoveAll(tempDir)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func traceDOCXHeader(tempDir, traceUrl string) (err error) {
+	var (
+		parts    []string
+		document *etree.Document
+	)
+
+	// 1、读取 document.xml.rels 文件，查找页眉/页脚
+	main := docxMainPart(tempDir)
+	relsFile := partRels(tempDir, main)
+	document, err = readRels(relsFile)
+	if err != nil {
+		return err
+	}
+
+	relationships := document.SelectElement("Relationships")
+	elements := append(findRels(relationships, docxHeaderType), findRels(relationships, docxFooterType)...)
+	for _, element := range elements {
+		parts = append(parts, resolveTarget(main, element.SelectAttrValue("Target", "")))
+	}
+
+	// 2、不存在页眉/页脚，创建页眉
+	if len(parts) == 0 {
+		var part string
+		part, err = createDOCXHeader(tempDir, main, document, relsFile)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, part)
+	}
+
+	// 3、添加/修改页眉/页脚中的远程图片
+	for _, part := range parts {
+		err = traceDOCXPart(tempDir, part, traceUrl)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createDOCXHeader 创建页眉部件，并添加到 document.xml.rels、[Content_Types].xml、sectPr 中
+// main: 文档主部件，例如 word/document.xml
+func createDOCXHeader(tempDir, main string, rels *etree.Document, relsFile string) (part string, err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 查找可用的页眉文件名
+	name := ""
+	for i := 1; ; i++ {
+		name = "header" + strconv.Itoa(i) + ".xml"
+		part = path.Join(path.Dir(main), name)
+		if _, err = os.Stat(partFile(tempDir, part)); err != nil {
+			break
+		}
+	}
+
+	err = os.WriteFile(partFile(tempDir, part), []byte(assets.MSHeaderTpl), os.ModePerm)
+	if err != nil {
+		return part, err
+	}
+
+	// 添加关系
+	relationships := rels.SelectElement("Relationships")
+	headerId := nextRelId(relationships)
+	node := relationships.CreateElement("Relationship")
+	node.CreateAttr("Id", headerId)
+	node.CreateAttr("Type", docxHeaderType)
+	node.CreateAttr("Target", name)
+
+	err = writeRels(rels, relsFile)
+	if err != nil {
+		return part, err
+	}
+
+	// 添加 header1.xml 到 [Content_Types].xml 文件
+	err = addContentType(tempDir, "/"+part, docxHeaderContentType)
+	if err != nil {
+		return part, err
+	}
+
+	// 添加 headerReference 到 sectPr 中
+	xmlFile := partFile(tempDir, main)
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return part, err
+	}
+
+	body := document.FindElement("w:document/w:body")
+	if body == nil {
+		return part, os.ErrNotExist
+	}
+
+	sectPr := body.SelectElement("w:sectPr")
+	if sectPr == nil {
+		sectPr = body.CreateElement("w:sectPr")
+	}
+
+	// 多节文档中，除最后一节外每一节的 sectPr 位于该节最后一个段落的 pPr 中，每一节都需要引用页眉
+	// headerReference 必须位于 sectPr 的最前面
+	for _, element := range append(body.FindElements("w:p/w:pPr/w:sectPr"), sectPr) {
+		reference := etree.NewElement("w:headerReference")
+		reference.CreateAttr("w:type", "default")
+		reference.CreateAttr("r:id", headerId)
+		element.InsertChildAt(0, reference)
+	}
+
+	// document.xml 根节点可能未声明 r 命名空间
+	root := document.Root()
+	if root.SelectAttr("xmlns:r") == nil {
+		root.CreateAttr("xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships")
+	}
+
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return part, err
+	}
+	return part, nil
+}
+
+// traceDOCXBody 在正文末尾添加远程图片
+func traceDOCXBody(tempDir, traceUrl string) (err error) {
+	return traceDOCXPart(tempDir, docxMainPart(tempDir), traceUrl)
+}
+
+// traceDOCXPart 在正文、页眉/页脚部件中添加远程图片
+// part: 包内路径，例如 word/header1.xml
+func traceDOCXPart(tempDir, part, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 1、添加/修改 header1.xml.rels 文件
+	relsFile := partRels(tempDir, part)
+	err = setTraceRels(relsFile, docxTraceId, docxImageType, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 2、修改 header1.xml 文件
+	xmlFile := partFile(tempDir, part)
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	root := document.Root()
+	for _, blip := range root.FindElements("//a:blip") {
+		if blip.SelectAttrValue("r:link", "") == docxTraceId {
+			// 已存在追踪信息
+			return nil
+		}
+	}
+
+	// 正文需要添加到 w:body 中，并位于 sectPr 之前
+	parent := root
+	if body := root.SelectElement("w:body"); body != nil {
+		parent = body
+	}
+
+	// wp:docPr 的 id 在正文、页眉/页脚中必须唯一
+	id, err := maxDocPrId(tempDir, part)
+	if err != nil {
+		return err
+	}
+	nodeId := strconv.Itoa(id + 1)
+	tpl := strings.Replace(assets.MSParagraphTpl, "${id}", nodeId, -1)
+	tpl = strings.Replace(tpl, "${docxTraceId}", docxTraceId, -1)
+
+	n := etree.NewDocument()
+	err = n.ReadFromString(tpl)
+	if err != nil {
+		return err
+	}
+
+	if sectPr := parent.SelectElement("w:sectPr"); sectPr != nil {
+		parent.InsertChildAt(sectPr.Index(), n.Root())
+	} else {
+		parent.AddChild(n.Root())
+	}
+
+	// 更新 header1.xml 文件
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// maxDocPrId 获取部件所在目录中全部 XML 部件的最大 wp:docPr id
+func maxDocPrId(tempDir, part string) (int, error) {
+	matches, err := filepath.Glob(filepath.Join(partFile(tempDir, path.Dir(part)), "*.xml"))
+	if err != nil {
+		return 0, err
+	}
+
+	max := 0
+	for _, match := range matches {
+		document, err := utils.ReadXml(match)
+		if err != nil {
+			return 0, err
+		}
+		if id := maxShapeId(document.Root(), "docPr"); id > max {
+			max = id
+		}
+	}
+	return max, nil
+}
@@ -0,0 +1,97 @@
+package ms_office
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+)
+
+func TestGenTracerDOCXHeader(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	withFooter := testDOCX()
+	withFooter["word/footer1.xml"] = `<w:ftr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:p/></w:ftr>`
+	withFooter["word/_rels/document.xml.rels"] = strings.Replace(testDocumentRels, "</Relationships>",
+		`<Relationship Id="rId2" Type="`+docxFooterType+`" Target="footer1.xml"/></Relationships>`, 1)
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		part  string
+	}{
+		{"create header", testDOCX(), "word/header1.xml"},
+		{"existing footer", withFooter, "word/footer1.xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcFile := testutil.WriteZip(t, "source.zip", tt.files)
+			dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+			if err := GenTracerDOCXHeader(srcFile, dstFile, traceUrl); err != nil {
+				t.Fatal(err)
+			}
+
+			// 重复生成，不应重复添加
+			if err := GenTracerDOCXHeader(dstFile, dstFile+"2", traceUrl); err != nil {
+				t.Fatal(err)
+			}
+
+			files := testutil.ReadZipFile(t, dstFile+"2")
+			part, ok := files[tt.part]
+			if !ok {
+				t.Fatalf("missing %s", tt.part)
+			}
+			if n := strings.Count(part, `r:link="`+docxTraceId+`"`); n != 1 {
+				t.Errorf("r:link count = %d, want 1", n)
+			}
+
+			dir, name := filepath.Split(tt.part)
+			rels := files[dir+"_rels/"+name+".rels"]
+			if !strings.Contains(rels, traceUrl) || !strings.Contains(rels, `TargetMode="External"`) {
+				t.Errorf("rels = %s", rels)
+			}
+
+			if tt.part == "word/header1.xml" {
+				if !strings.Contains(files["word/document.xml"], "w:headerReference") {
+					t.Error("missing headerReference")
+				}
+				if !strings.Contains(files["[Content_Types].xml"], "/word/header1.xml") {
+					t.Error("missing content type")
+				}
+				if strings.Count(files["word/_rels/document.xml.rels"], docxHeaderType) != 1 {
+					t.Error("header relationship count != 1")
+				}
+			}
+		})
+	}
+}
+
+func TestGenTracerDOCXHeaderSections(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	// 两节文档，第一节的 sectPr 位于段落的 pPr 中；正文已有 id 为 7 的图片
+	files := testDOCX()
+	files["word/document.xml"] = strings.Replace(testDocument, "<w:p>",
+		`<w:p><w:pPr><w:sectPr><w:pgSz w:w="16838" w:h="11906"/></w:sectPr></w:pPr></w:p>`+
+			`<w:p><w:r><w:drawing><wp:inline xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"><wp:docPr id="7" name="Picture 7"/></wp:inline></w:drawing></w:r></w:p><w:p>`, 1)
+
+	srcFile := testutil.WriteZip(t, "source.zip", files)
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	if err := GenTracerProfile(srcFile, dstFile, traceUrl, ProfileDefault, "docx-header", "docx-image"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := testutil.ReadZipFile(t, dstFile)
+	if n := strings.Count(got["word/document.xml"], "w:headerReference"); n != 2 {
+		t.Errorf("headerReference count = %d, want 2", n)
+	}
+
+	// docPr id 在正文、页眉中唯一
+	if !strings.Contains(got["word/header1.xml"], `<wp:docPr id="8"`) {
+		t.Errorf("header1.xml = %s", got["word/header1.xml"])
+	}
+	if !strings.Contains(got["word/document.xml"], `<wp:docPr id="9"`) {
+		t.Errorf("document.xml = %s", got["word/document.xml"])
+	}
+}
@@ -0,0 +1,256 @@
+package ms_office
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"tracer/pkg/utils"
+)
+
+var le = binary.LittleEndian
+
+// writeTestCFB 生成测试用的复合文档
+func writeTestCFB(t *testing.T, streams map[string][]byte) string {
+	t.Helper()
+
+	cf := &utils.CompoundFile{Root: &utils.CFBEntry{Name: "Root Entry", Type: utils.CFBRoot}}
+	for path, data := range streams {
+		cf.SetStream(path, data)
+	}
+
+	filename := filepath.Join(t.TempDir(), "source.cfb")
+	if err := utils.WriteCFB(cf, filename); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// testWordDocument 最小的 FIB：FibBase、FibRgW97、FibRgLw97、FibRgFcLcb97
+func testWordDocument() []byte {
+	b := make([]byte, 32)
+	le.PutUint16(b, docIdent)
+	le.PutUint16(b[2:], 0x00c1)
+	le.PutUint16(b[0x0a:], docFlagWhichTblStm)
+	b = le.AppendUint16(b, 14)
+	b = append(b, make([]byte, 14*2)...)
+	b = le.AppendUint16(b, 22)
+	b = append(b, make([]byte, 22*4)...)
+	b = le.AppendUint16(b, 0x5d)
+	b = append(b, make([]byte, 0x5d*8)...)
+	return append(b, make([]byte, 512)...)
+}
+
+func TestGenTracerDOC(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+	srcFile := writeTestCFB(t, map[string][]byte{
+		"WordDocument": testWordDocument(),
+		"1Table":       bytes.Repeat([]byte{0}, 100),
+	})
+	dstFile := filepath.Join(t.TempDir(), "tracer.doc")
+	if err := GenTracerDOC(srcFile, dstFile, traceUrl); err != nil {
+		t.Fatal(err)
+	}
+	// 长度相同时原地覆盖
+	traceUrl = "http://localhost:9090/trac2"
+	if err := GenTracerDOC(dstFile, dstFile+"2", traceUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := utils.ReadCFB(dstFile + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, table := cf.Stream("WordDocument"), cf.Stream("1Table")
+	fc := int(le.Uint32(wd[0x19a:]))
+	lcb := int(le.Uint32(wd[0x19a+4:]))
+	if fc != 100 || fc+lcb > len(table) {
+		t.Fatalf("fcSttbfAssoc = %d, lcbSttbfAssoc = %d, table = %d", fc, lcb, len(table))
+	}
+
+	assoc := parseDOCSttb(table[fc : fc+lcb])
+	if len(assoc) != docAssocCount || assoc[docAssocDot] != traceUrl {
+		t.Errorf("SttbfAssoc = %q", assoc)
+	}
+}
+
+// testXLSRecord 生成 BIFF 记录
+func testXLSRecord(typ uint16, data []byte) []byte {
+	b := le.AppendUint16(nil, typ)
+	b = le.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+func TestGenTracerXLS(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	bof := make([]byte, 16)
+	le.PutUint16(bof, 0x0600)
+	le.PutUint16(bof[2:], 0x0005)
+
+	// 全局子流
+	var globals []byte
+	globals = append(globals, testXLSRecord(xlsBOF, bof)...)
+	boundSheet := len(globals) + 4
+	globals = append(globals, testXLSRecord(xlsBoundSheet, append(make([]byte, 6), 6, 0, 'S', 'h', 'e', 'e', 't', '1'))...)
+	globals = append(globals, testXLSRecord(xlsCountry, []byte{0x56, 0, 0x56, 0})...)
+	globals = append(globals, testXLSRecord(xlsEOF, nil)...)
+
+	// 工作表子流
+	sheetBof := append([]byte{}, bof...)
+	le.PutUint16(sheetBof[2:], 0x0010)
+	sheetStart := len(globals)
+	sheet := testXLSRecord(xlsBOF, sheetBof)
+	index := len(sheet)
+	sheet = append(sheet, testXLSRecord(xlsIndex, make([]byte, 20))...)
+	dbCell := sheetStart + len(sheet)
+	sheet = append(sheet, testXLSRecord(0x00d7, make([]byte, 4))...)
+	sheet = append(sheet, testXLSRecord(xlsEOF, nil)...)
+	le.PutUint32(sheet[index+4+16:], uint32(dbCell))
+
+	stream := append(globals, sheet...)
+	le.PutUint32(stream[boundSheet:], uint32(sheetStart))
+
+	srcFile := writeTestCFB(t, map[string][]byte{"Workbook": stream})
+	dstFile := filepath.Join(t.TempDir(), "tracer.xls")
+	if err := GenTracerXLS(srcFile, dstFile, traceUrl); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenTracerXLS(dstFile, dstFile+"2", traceUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := utils.ReadCFB(dstFile + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream = cf.Stream("Workbook")
+	records, err := parseXLSRecords(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []uint16
+	offsets := make(map[uint16][]int)
+	position := 0
+	for _, record := range records {
+		types = append(types, record.Type)
+		offsets[record.Type] = append(offsets[record.Type], position)
+		position += 4 + len(record.Data)
+	}
+	want := []uint16{xlsBOF, xlsBoundSheet, xlsCountry, xlsSupBook, xlsExternSheet, xlsEOF, xlsBOF, xlsIndex, 0x00d7, xlsEOF}
+	if len(types) != len(want) {
+		t.Fatalf("records = %x, want %x", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("records = %x, want %x", types, want)
+		}
+	}
+
+	if got := int(le.Uint32(records[1].Data)); got != offsets[xlsBOF][1] {
+		t.Errorf("lbPlyPos = %d, want %d", got, offsets[xlsBOF][1])
+	}
+	if got := int(le.Uint32(records[7].Data[16:])); got != offsets[0x00d7][0] {
+		t.Errorf("rgibRw = %d, want %d", got, offsets[0x00d7][0])
+	}
+	if !bytes.Contains(records[3].Data, encodePPTString("localhost:9090")[:28]) {
+		t.Errorf("SUPBOOK = %q", records[3].Data)
+	}
+}
+
+// testPPTRecord 生成 PowerPoint 记录
+func testPPTRecord(ver, instance, typ uint16, data []byte) []byte {
+	return append(appendPPTHeader(nil, ver, instance, typ, len(data)), data...)
+}
+
+func TestGenTracerPPT(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	// DocumentContainer > SlideListWithTextContainer > SlidePersistAtom
+	slidePersist := make([]byte, 20)
+	le.PutUint32(slidePersist, 2)
+	document := testPPTRecord(0x0f, 0, pptDocument,
+		testPPTRecord(0x0f, 0, pptSlideListWithText, testPPTRecord(0, 0, pptSlidePersistAtom, slidePersist)))
+
+	// SlideContainer > PPDrawing > DgContainer > FDG、SpgrContainer
+	fdg := make([]byte, 8)
+	le.PutUint32(fdg, 1)
+	le.PutUint32(fdg[4:], 1025)
+	group := testPPTRecord(0x0f, 0, pptSpContainer, testPPTRecord(2, 0, pptFSP, make([]byte, 8)))
+	dg := append(testPPTRecord(0, 1, pptFDG, fdg), testPPTRecord(0x0f, 0, pptSpgrContainer, group)...)
+	slide := testPPTRecord(0x0f, 0, pptSlide, testPPTRecord(0x0f, 0, pptDrawing, testPPTRecord(0x0f, 0, pptDgContainer, dg)))
+
+	stream := append(append([]byte{}, document...), slide...)
+
+	persist := len(stream)
+	dir := le.AppendUint32(nil, 1|2<<20)
+	dir = le.AppendUint32(dir, 0)
+	dir = le.AppendUint32(dir, uint32(len(document)))
+	stream = append(stream, testPPTRecord(0, 0, pptPersistDirectory, dir)...)
+
+	edit := len(stream)
+	userEdit := make([]byte, 28)
+	le.PutUint32(userEdit, 256)
+	le.PutUint32(userEdit[12:], uint32(persist))
+	le.PutUint32(userEdit[16:], 1)
+	le.PutUint32(userEdit[20:], 2)
+	stream = append(stream, testPPTRecord(0, 0, pptUserEditAtom, userEdit)...)
+
+	user := make([]byte, 20)
+	le.PutUint32(user, 0x14)
+	le.PutUint32(user[4:], pptHeaderToken)
+	le.PutUint32(user[8:], uint32(edit))
+	currentUser := testPPTRecord(0, 0, pptCurrentUserAtom, user)
+
+	srcFile := writeTestCFB(t, map[string][]byte{"PowerPoint Document": stream, "Current User": currentUser})
+	dstFile := filepath.Join(t.TempDir(), "tracer.ppt")
+	if err := GenTracerPPT(srcFile, dstFile, traceUrl); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenTracerPPT(dstFile, dstFile+"2", traceUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := utils.ReadCFB(dstFile + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream = cf.Stream("PowerPoint Document")
+	lastEdit := int(le.Uint32(cf.Stream("Current User")[16:]))
+	if lastEdit == edit {
+		t.Fatal("Current User not updated")
+	}
+
+	persistMap, err := readPPTPersist(stream, lastEdit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persistMap[1] != 0 {
+		t.Errorf("document offset = %d", persistMap[1])
+	}
+
+	record, err := readPPTRecord(stream, persistMap[2])
+	if err != nil || record.Type != pptSlide {
+		t.Fatalf("slide = %+v, %v", record, err)
+	}
+	if n := bytes.Count(stream[record.Offset:record.Offset+8+record.Len], encodePPTString(traceUrl)); n != 1 {
+		t.Errorf("pibName count = %d, want 1", n)
+	}
+
+	// 检查记录长度是否一致
+	drawing := pptChildren(stream, record)[0]
+	dgRecord := pptChildren(stream, drawing)[0]
+	children := pptChildren(stream, dgRecord)
+	if len(children) != 2 {
+		t.Fatalf("DgContainer children = %d", len(children))
+	}
+	shapes := pptChildren(stream, children[1])
+	if len(shapes) != 2 || shapes[1].Type != pptSpContainer {
+		t.Fatalf("SpgrContainer children = %+v", shapes)
+	}
+	if csp, spid := le.Uint32(stream[children[0].Offset+8:]), le.Uint32(stream[children[0].Offset+12:]); csp != 2 || spid != 1026 {
+		t.Errorf("FDG csp = %d, spidCur = %d", csp, spid)
+	}
+}
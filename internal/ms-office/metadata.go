@@ -0,0 +1,260 @@
+package ms_office
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	corePropertiesType         = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	extendedPropertiesType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	customPropertiesType       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties"
+	corePropertiesContentType  = "application/vnd.openxmlformats-package.core-properties+xml"
+	extendedPropertiesContType = "application/vnd.openxmlformats-officedocument.extended-properties+xml"
+	metadataTimeLayout         = "2006-01-02T15:04:05Z"
+)
+
+const corePropertiesTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:dcmitype="http://purl.org/dc/dcmitype/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"></cp:coreProperties>`
+
+const extendedPropertiesTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes"></Properties>`
+
+// 清除时删除的属性，可能包含作者、组织、内部路径等信息
+var (
+	scrubCoreProperties     = []string{"dc:creator", "cp:lastModifiedBy", "cp:lastPrinted", "cp:revision"}
+	scrubExtendedProperties = []string{"Company", "Manager", "HyperlinkBase"}
+)
+
+// Metadata 文档属性（docProps/core.xml、docProps/app.xml）
+// 字符串为空、时间为零值时保持原有的值
+type Metadata struct {
+	Scrub          bool      // 先删除作者、公司、修订号、自定义属性（docProps/custom.xml）等，再设置下面的属性
+	Creator        string    // 作者，dc:creator
+	LastModifiedBy string    // 最后修改者，cp:lastModifiedBy
+	Revision       string    // 修订号，cp:revision
+	Created        time.Time // 创建时间，dcterms:created
+	Modified       time.Time // 修改时间，dcterms:modified
+	Company        string    // 公司，Company
+	Template       string    // 模板名称，Template，例如 Normal.dotm
+	AppVersion     string    // 应用程序版本，AppVersion，例如 16.0000
+	ZipTime        time.Time // zip 成员的修改时间，为零值时使用 Office 的默认值 1980-01-01
+}
+
+// SetMetadata 修改 office 文件（docx、xlsx、pptx）的文档属性，并重新设置 zip 成员的修改时间
+// 用于生成追踪文件之后，使文件属性与部署环境一致
+func SetMetadata(srcFile, dstFile string, meta *Metadata) (err error) {
+	var (
+		tempDir string
+	)
+
+	// 1、解压文件
+	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	// 2、修改文档属性
+	err = applyMetadata(tempDir, meta)
+	if err != nil {
+		return err
+	}
+
+	// 3、压缩文件夹，设置成员的修改时间
+	zipTime := meta.ZipTime
+	if zipTime.IsZero() {
+		zipTime = utils.OfficeZipTime
+	}
+	return utils.CompressZipTime(tempDir, dstFile, zipTime)
+}
+
+// applyMetadata 修改解压目录中的文档属性，部件不存在且需要设置属性时创建
+func applyMetadata(tempDir string, meta *Metadata) (err error) {
+	if _, err = DetectFormat(tempDir); err != nil {
+		return err
+	}
+
+	// 1、自定义属性
+	if meta.Scrub {
+		err = removeRelPart(tempDir, customPropertiesType)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 2、core.xml
+	core := [][2]string{
+		{"dc:creator", meta.Creator},
+		{"cp:lastModifiedBy", meta.LastModifiedBy},
+		{"cp:revision", meta.Revision},
+		{"dcterms:created", metadataTime(meta.Created)},
+		{"dcterms:modified", metadataTime(meta.Modified)},
+	}
+	err = setProperties(tempDir, corePropertiesType, corePropertiesContentType, "docProps/core.xml", corePropertiesTemp,
+		scrubTags(meta.Scrub, scrubCoreProperties), core)
+	if err != nil {
+		return err
+	}
+
+	// 3、app.xml
+	app := [][2]string{
+		{"Template", meta.Template},
+		{"Company", meta.Company},
+		{"AppVersion", meta.AppVersion},
+	}
+	return setProperties(tempDir, extendedPropertiesType, extendedPropertiesContType, "docProps/app.xml", extendedPropertiesTemp,
+		scrubTags(meta.Scrub, scrubExtendedProperties), app)
+}
+
+// scrubTags 需要清除的属性
+func scrubTags(scrub bool, tags []string) []string {
+	if !scrub {
+		return nil
+	}
+	return tags
+}
+
+// metadataTime 格式化为 W3CDTF，零值时返回空
+func metadataTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(metadataTimeLayout)
+}
+
+// setProperties 修改属性部件，先删除 scrub 中的属性，再按顺序设置 values 中不为空的属性
+// 部件不存在时，只有需要设置属性时才创建
+func setProperties(tempDir, relType, contentType, defaultPart, temp string, scrub []string, values [][2]string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	part, ok := relPart(tempDir, "", relType)
+	if !ok {
+		create := false
+		for _, v := range values {
+			create = create || v[1] != ""
+		}
+		if !create {
+			return nil
+		}
+
+		// 1、创建部件，添加关系和内容类型
+		part = defaultPart
+		document = etree.NewDocument()
+		err = document.ReadFromString(temp)
+		if err != nil {
+			return err
+		}
+		_, err = addRels(partRels(tempDir, ""), relType, part)
+		if err != nil {
+			return err
+		}
+		err = addContentType(tempDir, "/"+part, contentType)
+		if err != nil {
+			return err
+		}
+		err = utils.CreateDir(filepath.Dir(partFile(tempDir, part)))
+		if err != nil {
+			return err
+		}
+	} else {
+		document, err = utils.ReadXml(partFile(tempDir, part))
+		if err != nil {
+			return err
+		}
+	}
+
+	root := document.Root()
+	if root == nil {
+		return ErrFormat
+	}
+
+	// 2、清除属性
+	for _, tag := range scrub {
+		for _, element := range root.SelectElements(tag) {
+			root.RemoveChild(element)
+		}
+	}
+
+	// 3、设置属性，时间需要指定类型
+	for _, v := range values {
+		if v[1] == "" {
+			continue
+		}
+		element := root.SelectElement(v[0])
+		if element == nil {
+			element = root.CreateElement(v[0])
+		}
+		element.SetText(v[1])
+
+		if element.Space == "dcterms" {
+			root.CreateAttr("xmlns:dcterms", "http://purl.org/dc/terms/")
+			root.CreateAttr("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+			element.CreateAttr("xsi:type", "dcterms:W3CDTF")
+		}
+	}
+	return utils.WriteXml(document, partFile(tempDir, part))
+}
+
+// removeRelPart 删除包关系中指定类型的部件，以及对应的关系和内容类型
+func removeRelPart(tempDir, relType string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	relsFile := partRels(tempDir, "")
+	document, err = readRels(relsFile)
+	if err != nil {
+		return err
+	}
+
+	relationships := document.SelectElement("Relationships")
+	elements := findRels(relationships, relType)
+	if len(elements) == 0 {
+		return nil
+	}
+	for _, element := range elements {
+		part := resolveTarget("", element.SelectAttrValue("Target", ""))
+		relationships.RemoveChild(element)
+
+		err = os.RemoveAll(partFile(tempDir, part))
+		if err != nil {
+			return err
+		}
+		err = removeContentType(tempDir, "/"+part)
+		if err != nil {
+			return err
+		}
+	}
+	return writeRels(document, relsFile)
+}
+
+// removeContentType 删除 [Content_Types].xml 中部件的 Override 节点
+func removeContentType(tempDir, partName string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	typesFile := filepath.Join(tempDir, "[Content_Types].xml")
+	document, err = utils.ReadXml(typesFile)
+	if err != nil {
+		return err
+	}
+
+	types := document.SelectElement("Types")
+	for _, element := range types.SelectElements("Override") {
+		if element.SelectAttrValue("PartName", "") == partName {
+			types.RemoveChild(element)
+		}
+	}
+	return utils.WriteXml(document, typesFile)
+}
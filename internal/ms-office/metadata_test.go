@@ -0,0 +1,150 @@
+package ms_office
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tracer/internal/testutil"
+
+	"github.com/beevik/etree"
+)
+
+const testCore = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+    <dc:title>Report</dc:title>
+    <dc:creator>red-team-01</dc:creator>
+    <cp:lastModifiedBy>red-team-02</cp:lastModifiedBy>
+    <cp:revision>42</cp:revision>
+    <dcterms:created xsi:type="dcterms:W3CDTF">2026-01-01T00:00:00Z</dcterms:created>
+</cp:coreProperties>`
+
+const testApp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties">
+    <Template>RedTeam.dotm</Template>
+    <Company>Red Team Ltd</Company>
+    <Manager>lead</Manager>
+    <AppVersion>16.0000</AppVersion>
+</Properties>`
+
+const testCustom = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties"/>`
+
+// testDOCXWithProps 带有文档属性的 docx
+func testDOCXWithProps() map[string]string {
+	files := testDOCX()
+	files["docProps/core.xml"] = testCore
+	files["docProps/app.xml"] = testApp
+	files["docProps/custom.xml"] = testCustom
+	files["_rels/.rels"] = strings.Replace(testRootRels, "</Relationships>",
+		`<Relationship Id="rId2" Type="`+corePropertiesType+`" Target="docProps/core.xml"/>`+
+			`<Relationship Id="rId3" Type="`+extendedPropertiesType+`" Target="docProps/app.xml"/>`+
+			`<Relationship Id="rId4" Type="`+customPropertiesType+`" Target="docProps/custom.xml"/></Relationships>`, 1)
+	files["[Content_Types].xml"] = strings.Replace(testContentTypes, "</Types>",
+		`<Override PartName="/docProps/custom.xml" ContentType="application/vnd.openxmlformats-officedocument.custom-properties+xml"/></Types>`, 1)
+	return files
+}
+
+// testProperties 读取属性部件中的属性
+func testProperties(t *testing.T, content string) map[string]string {
+	t.Helper()
+	document := etree.NewDocument()
+	if err := document.ReadFromString(content); err != nil {
+		t.Fatal(err)
+	}
+	props := make(map[string]string)
+	for _, element := range document.Root().ChildElements() {
+		props[element.FullTag()] = element.Text()
+	}
+	return props
+}
+
+func TestSetMetadata(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCXWithProps())
+	dstFile := filepath.Join(t.TempDir(), "meta.docx")
+	created := time.Date(2025, 11, 3, 9, 12, 0, 0, time.FixedZone("CST", 8*3600))
+	meta := &Metadata{
+		Scrub:          true,
+		Creator:        "张伟",
+		LastModifiedBy: "李娜",
+		Revision:       "3",
+		Created:        created,
+		Modified:       created.Add(48 * time.Hour),
+		Company:        "Example Corp",
+		Template:       "Normal.dotm",
+	}
+	if err := SetMetadata(srcFile, dstFile, meta); err != nil {
+		t.Fatal(err)
+	}
+	files := testutil.ReadZipFile(t, dstFile)
+
+	// 1、core.xml：清除后重新设置，时间转换为 UTC
+	core := testProperties(t, files["docProps/core.xml"])
+	want := map[string]string{
+		"dc:title":          "Report",
+		"dc:creator":        "张伟",
+		"cp:lastModifiedBy": "李娜",
+		"cp:revision":       "3",
+		"dcterms:created":   "2025-11-03T01:12:00Z",
+		"dcterms:modified":  "2025-11-05T01:12:00Z",
+	}
+	for tag, value := range want {
+		if core[tag] != value {
+			t.Errorf("core %s = %q, want %q", tag, core[tag], value)
+		}
+	}
+	if !strings.Contains(files["docProps/core.xml"], `<dcterms:modified xsi:type="dcterms:W3CDTF">`) {
+		t.Errorf("core.xml = %s", files["docProps/core.xml"])
+	}
+
+	// 2、app.xml：Manager 被清除，未设置的 AppVersion 保持不变
+	app := testProperties(t, files["docProps/app.xml"])
+	if app["Company"] != "Example Corp" || app["Template"] != "Normal.dotm" || app["AppVersion"] != "16.0000" {
+		t.Errorf("app = %v", app)
+	}
+	if _, ok := app["Manager"]; ok {
+		t.Errorf("Manager not scrubbed: %v", app)
+	}
+
+	// 3、自定义属性被删除
+	if _, ok := files["docProps/custom.xml"]; ok || strings.Contains(files["_rels/.rels"], customPropertiesType) ||
+		strings.Contains(files["[Content_Types].xml"], "/docProps/custom.xml") {
+		t.Error("custom properties not removed")
+	}
+
+	// 4、zip 成员使用 Office 默认时间，不添加扩展时间戳
+	reader := testutil.OpenZip(t, dstFile)
+	for _, f := range reader.File {
+		if !f.Modified.Equal(time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)) || len(f.Extra) != 0 {
+			t.Errorf("%s modified = %v, extra = %x", f.Name, f.Modified, f.Extra)
+		}
+	}
+}
+
+func TestSetMetadataCreate(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCX())
+	dstFile := filepath.Join(t.TempDir(), "meta.docx")
+	zipTime := time.Date(2025, 6, 30, 18, 20, 10, 0, time.UTC)
+	if err := SetMetadata(srcFile, dstFile, &Metadata{Creator: "admin", ZipTime: zipTime}); err != nil {
+		t.Fatal(err)
+	}
+	files := testutil.ReadZipFile(t, dstFile)
+
+	if core := testProperties(t, files["docProps/core.xml"]); core["dc:creator"] != "admin" {
+		t.Errorf("core = %v", core)
+	}
+	if !strings.Contains(files["_rels/.rels"], corePropertiesType) ||
+		!strings.Contains(files["[Content_Types].xml"], `PartName="/docProps/core.xml"`) {
+		t.Error("core properties part not registered")
+	}
+	// 没有需要设置的属性时不创建 app.xml
+	if _, ok := files["docProps/app.xml"]; ok {
+		t.Error("app.xml created")
+	}
+
+	reader := testutil.OpenZip(t, dstFile)
+	if f := reader.File[0]; !f.Modified.Equal(zipTime) {
+		t.Errorf("modified = %v, want %v", f.Modified, zipTime)
+	}
+}
@@ -28,18 +28,48 @@ func docxTraceInfo(traceUrl string) string {
 // GenTracerDOCX 生成可追踪文档
 func GenTracerDOCX(srcFile, dstFile, traceUrl string) (err error) {
 	var (
-		tempDir  string
-		document *etree.Document
+		tempDir string
 	)
 
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
 	// 1、解压 docx 文件
 	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
 	if err != nil {
 		return err
 	}
 
-	// 2、添加/修改 settings.xml.rels 文件
-	relsFile := filepath.Join(tempDir, "word", "_rels", "settings.xml.rels")
+	// 2、添加 attachedTemplate 追踪信息
+	err = traceDOCXTemplate(tempDir, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、压缩文件夹，生成新的 docx 文件
+	err = utils.CompressZip(tempDir, dstFile)
+	if err != nil {
+		return err
+	}
+
+	// 4、删除临时目录
+	err = os.RemoveAll(tempDir)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func traceDOCXTemplate(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 从主文档部件的关系中查找 settings.xml
+	part := docxSettingsPart(tempDir)
+
+	// 1、添加/修改 settings.xml.rels 文件
+	relsFile := partRels(tempDir, part)
 	if _, err = os.Stat(relsFile); err != nil {
 		// 创建文件
 		// 添加追踪信息
@@ -89,8 +119,8 @@ func GenTracerDOCX(srcFile, dstFile, traceUrl string) (err error) {
 		}
 	}
 
-	// 3、修改 settings.xml 文件
-	xmlFile := filepath.Join(tempDir, "word", "settings.xml")
+	// 2、修改 settings.xml 文件
+	xmlFile := partFile(tempDir, part)
 	document, err = utils.ReadXml(xmlFile)
 	if err != nil {
 		return err
@@ -122,18 +152,6 @@ func GenTracerDOCX(srcFile, dstFile, traceUrl string) (err error) {
 	if err != nil {
 		return err
 	}
-
-	// 4、压缩文件夹，生成新的 docx 文件
-	err = utils.CompressZip(tempDir, dstFile)
-	if err != nil {
-		return err
-	}
-
-	// 5、删除临时目录
-	err = os.RemoveAll(tempDir)
-	if err != nil {
-		return err
-	}
 	return nil
 }
 
@@ -151,18 +169,48 @@ func pptxTraceInfo(traceUrl string) string {
 // GenTracerPPTX 生成可追踪演示文稿
 func GenTracerPPTX(srcFile, dstFile, traceUrl string) (err error) {
 	var (
-		tempDir  string
-		document *etree.Document
+		tempDir string
 	)
 
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
 	// 1、解压 pptx 文件
 	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
 	if err != nil {
 		return err
 	}
 
-	// 2、添加/修改 slide1.xml.rels 文件
-	relsFile := filepath.Join(tempDir, "ppt", "slides", "_rels", "slide1.xml.rels")
+	// 2、添加远程图片追踪信息
+	err = tracePPTXImage(tempDir, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、压缩文件夹，生成新的 pptx 文件
+	err = utils.CompressZip(tempDir, dstFile)
+	if err != nil {
+		return err
+	}
+
+	// 4、删除临时目录
+	err = os.RemoveAll(tempDir)
+	if err != nil {
+		return err
+	}
+	return err
+}
+
+func tracePPTXImage(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 从 presentation.xml 中查找第一张幻灯片
+	slide := pptxFirstSlide(tempDir)
+
+	// 1、添加/修改 slide1.xml.rels 文件
+	relsFile := partRels(tempDir, slide)
 	if _, err = os.Stat(relsFile); err != nil {
 		// 创建文件
 		// 添加追踪信息
@@ -212,8 +260,8 @@ func GenTracerPPTX(srcFile, dstFile, traceUrl string) (err error) {
 		}
 	}
 
-	// 3、修改 slide1.xml 文件
-	xmlFile := filepath.Join(tempDir, "ppt", "slides", "slide1.xml")
+	// 2、修改 slide1.xml 文件
+	xmlFile := partFile(tempDir, slide)
 	document, err = utils.ReadXml(xmlFile)
 	if err != nil {
 		return err
@@ -232,11 +280,11 @@ func GenTracerPPTX(srcFile, dstFile, traceUrl string) (err error) {
 	if !exist {
 		tree := document.FindElement("//p:sld/p:cSld/p:spTree")
 		nodeId := strconv.Itoa(len(tree.ChildElements()) + 1)
-		assets.MSSlideTpl = strings.Replace(assets.MSSlideTpl, "${id}", nodeId, -1)
-		assets.MSSlideTpl = strings.Replace(assets.MSSlideTpl, "${pptxTraceId}", pptxTraceId, -1)
+		tpl := strings.Replace(assets.MSSlideTpl, "${id}", nodeId, -1)
+		tpl = strings.Replace(tpl, "${pptxTraceId}", pptxTraceId, -1)
 
 		n := etree.NewDocument()
-		err = n.ReadFromString(assets.MSSlideTpl)
+		err = n.ReadFromString(tpl)
 		if err != nil {
 			return err
 		}
@@ -248,19 +296,7 @@ func GenTracerPPTX(srcFile, dstFile, traceUrl string) (err error) {
 	if err != nil {
 		return err
 	}
-
-	// 4、压缩文件夹，生成新的 pptx 文件
-	err = utils.CompressZip(tempDir, dstFile)
-	if err != nil {
-		return err
-	}
-
-	// 5、删除临时目录
-	err = os.RemoveAll(tempDir)
-	if err != nil {
-		return err
-	}
-	return err
+	return nil
 }
 
 const xlsxTraceId = "rId9999"
@@ -282,9 +318,41 @@ func xlsxTraceInfo(traceUrl string) string {
 // GenTracerXLSX 生成可追踪表格
 func GenTracerXLSX(srcFile, dstFile, traceUrl string) (err error) {
 	var (
-		tempDir         string
+		tempDir string
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、解压 xlsx 文件
+	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
+	if err != nil {
+		return err
+	}
+
+	// 2、添加远程图片追踪信息
+	err = traceXLSXImage(tempDir, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、压缩文件夹，生成新的 xlsx 文件
+	err = utils.CompressZip(tempDir, dstFile)
+	if err != nil {
+		return err
+	}
+
+	// 4、删除临时目录
+	err = os.RemoveAll(tempDir)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func traceXLSXImage(tempDir, traceUrl string) (err error) {
+	var (
 		mediaDir        string
-		sheetDir        string
 		sheetRelsDir    string
 		drawingsDir     string
 		drawingsRelsDir string
@@ -297,13 +365,7 @@ func GenTracerXLSX(srcFile, dstFile, traceUrl string) (err error) {
 		xmlContent []byte
 	)
 
-	// 1、解压 xlsx 文件
-	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
-	if err != nil {
-		return err
-	}
-
-	// 2、添加图片
+	// 1、添加图片
 	mediaDir = filepath.Join(tempDir, "xl", "media")
 	err = utils.CreateDir(mediaDir)
 	if err != nil {
@@ -322,7 +384,7 @@ func GenTracerXLSX(srcFile, dstFile, traceUrl string) (err error) {
 		return err
 	}
 
-	// 3、添加/修改 drawing1.xml，drawing1.xml.rels 文件
+	// 2、添加/修改 drawing1.xml，drawing1.xml.rels 文件
 	drawingsDir = filepath.Join(tempDir, "xl", "drawings")
 	drawingsRelsDir = filepath.Join(tempDir, "xl", "drawings", "_rels")
 	err = utils.CreateDir(drawingsRelsDir)
@@ -447,10 +509,10 @@ func GenTracerXLSX(srcFile, dstFile, traceUrl string) (err error) {
 		}
 
 		if !strings.Contains(string(xmlContent), xlsxTraceId) {
-			assets.MSDrawingTpl = strings.Replace(assets.MSDrawingTpl, "${id}", strconv.Itoa(count+1), -1)
-			assets.MSDrawingTpl = ">" + assets.MSDrawingTpl + "</xdr:wsDr>"
+			tpl := strings.Replace(assets.MSDrawingTpl, "${id}", strconv.Itoa(count+1), -1)
+			tpl = ">" + tpl + "</xdr:wsDr>"
 
-			current := strings.Replace(string(xmlContent), "></xdr:wsDr>", assets.MSDrawingTpl, -1)
+			current := strings.Replace(string(xmlContent), "></xdr:wsDr>", tpl, -1)
 
 			err = os.WriteFile(xmlFile, []byte(current), os.ModePerm)
 			if err != nil {
@@ -459,16 +521,17 @@ func GenTracerXLSX(srcFile, dstFile, traceUrl string) (err error) {
 		}
 	}
 
-	// 4、添加/修改 sheet1.xml，sheet1.xml.rels 文件
-	sheetDir = filepath.Join(tempDir, "xl", "worksheets")
-	sheetRelsDir = filepath.Join(tempDir, "xl", "worksheets", "_rels")
+	// 3、添加/修改 sheet1.xml，sheet1.xml.rels 文件
+	// 从 workbook.xml 中查找第一个工作表
+	sheet := xlsxFirstSheet(tempDir)
+	xmlFile = partFile(tempDir, sheet)
+	relsFile = partRels(tempDir, sheet)
+	sheetRelsDir = filepath.Dir(relsFile)
 	err = utils.CreateDir(sheetRelsDir)
 	if err != nil {
 		return err
 	}
 
-	xmlFile = filepath.Join(sheetDir, "sheet1.xml")
-	relsFile = filepath.Join(sheetRelsDir, "sheet1.xml.rels")
 	if _, err = os.Stat(relsFile); err != nil {
 		// 创建文件
 		// 写入 drawing 信息
@@ -509,16 +572,5 @@ func GenTracerXLSX(srcFile, dstFile, traceUrl string) (err error) {
 		}
 	}
 
-	// 5、压缩文件夹，生成新的 xlsx 文件
-	err = utils.CompressZip(tempDir, dstFile)
-	if err != nil {
-		return err
-	}
-
-	// 6、删除临时目录
-	err = os.RemoveAll(tempDir)
-	if err != nil {
-		return err
-	}
 	return nil
 }
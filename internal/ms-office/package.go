@@ -0,0 +1,175 @@
+package ms_office
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const officeDocumentType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+const docxSettingsType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings"
+
+var ErrFormat = errors.New("unsupported office format")
+
+// formats 主文档部件类型 => 格式
+// 包括宏文件（docm、xlsm、pptm）、模板文件（dotx、xltx、potx）、放映文件（ppsx）
+var formats = map[string]string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml":   "docx",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml":   "docx",
+	"application/vnd.ms-word.document.macroEnabled.main+xml":                             "docx",
+	"application/vnd.ms-word.template.macroEnabledTemplate.main+xml":                     "docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml":         "xlsx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml":      "xlsx",
+	"application/vnd.ms-excel.sheet.macroEnabled.main+xml":                               "xlsx",
+	"application/vnd.ms-excel.template.macroEnabled.main+xml":                            "xlsx",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml": "pptx",
+	"application/vnd.openxmlformats-officedocument.presentationml.template.main+xml":     "pptx",
+	"application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml":    "pptx",
+	"application/vnd.ms-powerpoint.presentation.macroEnabled.main+xml":                   "pptx",
+	"application/vnd.ms-powerpoint.template.macroEnabled.main+xml":                       "pptx",
+	"application/vnd.ms-powerpoint.slideshow.macroEnabled.main+xml":                      "pptx",
+}
+
+// DetectFormat 根据主文档部件的类型获取格式：docx、xlsx、pptx
+// tempDir: 解压后的目录
+func DetectFormat(tempDir string) (string, error) {
+	main := mainPart(tempDir, "")
+	if main == "" {
+		return "", ErrFormat
+	}
+
+	document, err := utils.ReadXml(filepath.Join(tempDir, "[Content_Types].xml"))
+	if err != nil {
+		return "", err
+	}
+
+	types := document.SelectElement("Types")
+	if types == nil {
+		return "", ErrFormat
+	}
+	for _, element := range types.SelectElements("Override") {
+		if strings.EqualFold(element.SelectAttrValue("PartName", ""), "/"+main) {
+			if format, ok := formats[element.SelectAttrValue("ContentType", "")]; ok {
+				return format, nil
+			}
+		}
+	}
+	return "", ErrFormat
+}
+
+// mainPart 从 _rels/.rels 中获取主文档部件，例如 word/document.xml
+// fallback: 不存在时的默认值
+func mainPart(tempDir, fallback string) string {
+	if part, ok := relPart(tempDir, "", officeDocumentType); ok {
+		return part
+	}
+	return fallback
+}
+
+// partFile 部件在解压目录中的文件路径
+func partFile(tempDir, part string) string {
+	return filepath.Join(tempDir, filepath.FromSlash(part))
+}
+
+// partRels 部件对应的 .rels 文件路径，例如 word/document.xml => word/_rels/document.xml.rels
+// part 为空时表示包的 _rels/.rels
+func partRels(tempDir, part string) string {
+	dir, name := path.Split(part)
+	return filepath.Join(tempDir, filepath.FromSlash(dir), "_rels", name+".rels")
+}
+
+// resolveTarget 将关系中的 Target 转换为包内路径
+// 例如 word/document.xml 中的 header1.xml => word/header1.xml，/word/header1.xml => word/header1.xml
+func resolveTarget(part, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(target, "/")
+	}
+	return path.Join(path.Dir(part), target)
+}
+
+// relPart 获取部件中第一个指定类型的内部关系指向的部件
+func relPart(tempDir, part, relType string) (string, bool) {
+	document, err := readRels(partRels(tempDir, part))
+	if err != nil {
+		return "", false
+	}
+
+	for _, element := range findRels(document.SelectElement("Relationships"), relType) {
+		if element.SelectAttrValue("TargetMode", "") != "External" {
+			return resolveTarget(part, element.SelectAttrValue("Target", "")), true
+		}
+	}
+	return "", false
+}
+
+// relPartById 获取部件中指定 Id 的关系指向的部件
+func relPartById(tempDir, part, id string) (string, bool) {
+	document, err := readRels(partRels(tempDir, part))
+	if err != nil {
+		return "", false
+	}
+
+	for _, element := range document.SelectElement("Relationships").ChildElements() {
+		if element.SelectAttrValue("Id", "") == id {
+			return resolveTarget(part, element.SelectAttrValue("Target", "")), true
+		}
+	}
+	return "", false
+}
+
+// docxMainPart 获取文档主部件
+func docxMainPart(tempDir string) string {
+	return mainPart(tempDir, "word/document.xml")
+}
+
+// docxSettingsPart 获取文档设置部件
+func docxSettingsPart(tempDir string) string {
+	main := docxMainPart(tempDir)
+	if part, ok := relPart(tempDir, main, docxSettingsType); ok {
+		return part
+	}
+	return path.Join(path.Dir(main), "settings.xml")
+}
+
+// pptxFirstSlide 获取第一张幻灯片部件
+func pptxFirstSlide(tempDir string) string {
+	main := mainPart(tempDir, "ppt/presentation.xml")
+	return firstRelPart(tempDir, main, "p:sldIdLst/p:sldId", "ppt/slides/slide1.xml")
+}
+
+// xlsxWorkbookPart 获取工作簿部件
+func xlsxWorkbookPart(tempDir string) string {
+	return mainPart(tempDir, "xl/workbook.xml")
+}
+
+// xlsxFirstSheet 获取第一个工作表部件
+func xlsxFirstSheet(tempDir string) string {
+	return firstRelPart(tempDir, xlsxWorkbookPart(tempDir), "sheets/sheet", "xl/worksheets/sheet1.xml")
+}
+
+// firstRelPart 查找部件中第一个匹配 selector 的节点，并获取其 r:id 指向的部件
+func firstRelPart(tempDir, part, selector, fallback string) string {
+	var (
+		document *etree.Document
+		err      error
+	)
+
+	document, err = utils.ReadXml(partFile(tempDir, part))
+	if err != nil || document.Root() == nil {
+		return fallback
+	}
+
+	element := document.Root().FindElement(selector)
+	if element == nil {
+		return fallback
+	}
+	if target, ok := relPartById(tempDir, part, element.SelectAttrValue("r:id", "")); ok {
+		return target
+	}
+	return fallback
+}
@@ -0,0 +1,159 @@
+package ms_office
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+	"tracer/pkg/utils"
+)
+
+const testVBAProject = "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1\x00\x01\x02\xff"
+
+// testDOCM 主文档部件不是 word/document.xml 的启用宏的文档
+func testDOCM() map[string]string {
+	files := testDOCX()
+	files["[Content_Types].xml"] = strings.Replace(
+		strings.Replace(testContentTypes, "/word/document.xml", "/word/main.xml", 1),
+		"openxmlformats-officedocument.wordprocessingml.document.main+xml", "ms-word.document.macroEnabled.main+xml", 1,
+	)
+	files["_rels/.rels"] = strings.Replace(testRootRels, "word/document.xml", "word/main.xml", 1)
+	files["word/main.xml"] = files["word/document.xml"]
+	files["word/_rels/main.xml.rels"] = strings.Replace(testDocumentRels, "</Relationships>",
+		`<Relationship Id="rId2" Type="http://schemas.microsoft.com/office/2006/relationships/vbaProject" Target="vbaProject.bin"/></Relationships>`, 1)
+	files["word/vbaProject.bin"] = testVBAProject
+	delete(files, "word/document.xml")
+	delete(files, "word/_rels/document.xml.rels")
+	return files
+}
+
+// testXLSM 第一个工作表不是 sheet1.xml 的启用宏的表格
+func testXLSM() map[string]string {
+	files := testXLSX()
+	files["[Content_Types].xml"] = strings.Replace(
+		strings.Replace(testXLSXContentTypes, "/xl/worksheets/sheet1.xml", "/xl/worksheets/data.xml", 1),
+		"openxmlformats-officedocument.spreadsheetml.sheet.main+xml", "ms-excel.sheet.macroEnabled.main+xml", 1,
+	)
+	files["xl/_rels/workbook.xml.rels"] = strings.Replace(testWorkbookRels, "worksheets/sheet1.xml", "worksheets/data.xml", 1)
+	files["xl/worksheets/data.xml"] = testSheet
+	files["xl/vbaProject.bin"] = testVBAProject
+	delete(files, "xl/worksheets/sheet1.xml")
+	return files
+}
+
+// testPPTM 第一张幻灯片是 slide2.xml 的启用宏的演示文稿
+func testPPTM() map[string]string {
+	files := testPPTX()
+	files["[Content_Types].xml"] = strings.Replace(testPPTXContentTypes,
+		"openxmlformats-officedocument.presentationml.presentation.main+xml", "ms-powerpoint.presentation.macroEnabled.main+xml", 1)
+	files["ppt/presentation.xml"] = `<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+    <p:sldIdLst>
+        <p:sldId id="256" r:id="rId3"/>
+        <p:sldId id="257" r:id="rId2"/>
+    </p:sldIdLst>
+</p:presentation>`
+	files["ppt/_rels/presentation.xml.rels"] = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+    <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/>
+    <Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/>
+</Relationships>`
+	files["ppt/slides/slide2.xml"] = testSlide
+	files["ppt/vbaProject.bin"] = testVBAProject
+	return files
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		contentType string
+		want        string
+	}{
+		{"docx", testDOCX(), "", "docx"},
+		{"docm", testDOCM(), "", "docx"},
+		{"dotx", testDOCX(), "application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml", "docx"},
+		{"xlsm", testXLSM(), "", "xlsx"},
+		{"xltx", testXLSX(), "application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml", "xlsx"},
+		{"pptm", testPPTM(), "", "pptx"},
+		{"potx", testPPTX(), "application/vnd.openxmlformats-officedocument.presentationml.template.main+xml", "pptx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.contentType != "" {
+				content := tt.files["[Content_Types].xml"]
+				for contentType := range formats {
+					content = strings.Replace(content, `"`+contentType+`"`, `"`+tt.contentType+`"`, 1)
+				}
+				tt.files["[Content_Types].xml"] = content
+			}
+
+			tempDir, err := utils.ExtractZip(testutil.WriteZip(t, "source.zip", tt.files), "file-trace-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = os.RemoveAll(tempDir)
+			}()
+
+			got, err := DetectFormat(tempDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormat() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenTracerMacroEnabled(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+	tests := []struct {
+		format string
+		files  map[string]string
+		part   string // 应包含追踪信息的部件
+		vba    string
+	}{
+		{"docx", testDOCM(), "", "word/vbaProject.bin"},
+		{"xlsx", testXLSM(), "", "xl/vbaProject.bin"},
+		{"pptx", testPPTM(), "ppt/slides/_rels/slide2.xml.rels", "ppt/vbaProject.bin"},
+	}
+	for _, tt := range tests {
+		for _, technique := range Techniques(tt.format) {
+			t.Run(technique.Name, func(t *testing.T) {
+				srcFile := testutil.WriteZip(t, "source.zip", tt.files)
+				dstFile := filepath.Join(t.TempDir(), "tracer")
+				if err := GenTracer(srcFile, dstFile, traceUrl, technique.Name); err != nil {
+					t.Fatal(err)
+				}
+
+				files := testutil.ReadZipFile(t, dstFile)
+				if files[tt.vba] != testVBAProject {
+					t.Errorf("%s changed", tt.vba)
+				}
+				if tt.part != "" && !strings.Contains(files[tt.part], traceUrl) {
+					t.Errorf("%s: traceUrl not found", tt.part)
+				}
+
+				// 不应创建默认路径的部件
+				for _, name := range []string{"word/document.xml", "word/_rels/document.xml.rels", "xl/worksheets/sheet1.xml", "ppt/slides/_rels/slide1.xml.rels"} {
+					if _, ok := files[name]; ok && tt.files[name] == "" {
+						t.Errorf("unexpected part %s", name)
+					}
+				}
+				if strings.Contains(files["ppt/slides/_rels/slide1.xml.rels"], traceUrl) {
+					t.Error("traced slide1.xml instead of first slide")
+				}
+			})
+		}
+	}
+}
+
+func TestGenTracerFormatMismatch(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testXLSM())
+	dstFile := filepath.Join(t.TempDir(), "tracer.xlsm")
+	if err := GenTracer(srcFile, dstFile, "http://localhost:9090/trace", "docx-template"); err == nil {
+		t.Error("GenTracer() want error")
+	}
+}
@@ -0,0 +1,330 @@
+package ms_office
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+
+	"tracer/pkg/utils"
+)
+
+// PowerPoint 97-2003 记录类型
+const (
+	pptDocument           = 0x03e8
+	pptSlide              = 0x03ee
+	pptSlidePersistAtom   = 0x03f3
+	pptDrawing            = 0x040c
+	pptSlideListWithText  = 0x0ff0
+	pptUserEditAtom       = 0x0ff5
+	pptCurrentUserAtom    = 0x0ff6
+	pptPersistDirectory   = 0x1772
+	pptDgContainer        = 0xf002
+	pptSpgrContainer      = 0xf003
+	pptSpContainer        = 0xf004
+	pptFDG                = 0xf008
+	pptFSP                = 0xf00a
+	pptFOPT               = 0xf00b
+	pptClientAnchor       = 0xf010
+	pptHeaderToken        = 0xe391c05f
+	pptPictureFrame       = 75
+	pptPropPibName        = 0x0105
+	pptPropPibFlags       = 0x0106
+	pptPropComplex        = 0x8000
+	pptBlipFlagURL        = 0x02
+	pptBlipFlagLinkToFile = 0x08
+)
+
+var errPpt = errors.New("invalid powerpoint document")
+
+// pptRecord 记录头
+type pptRecord struct {
+	Offset   int // 记录头在流中的位置
+	Ver      uint16
+	Instance uint16
+	Type     uint16
+	Len      int
+}
+
+// GenTracerPPT 生成可追踪演示文稿（PowerPoint 97-2003）
+// 以增量保存的方式追加第一张幻灯片的新版本，添加链接到 traceUrl 的图片
+// 原有记录位置不变，只追加新的 PersistDirectoryAtom、UserEditAtom 并修改 Current User
+func GenTracerPPT(srcFile, dstFile, traceUrl string) (err error) {
+	var (
+		cf *utils.CompoundFile
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、读取复合文档
+	cf, err = utils.ReadCFB(srcFile)
+	if err != nil {
+		return err
+	}
+
+	// 2、添加远程图片
+	err = tracePPTLinkedImage(cf, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、生成新的 ppt 文件
+	return utils.WriteCFB(cf, dstFile)
+}
+
+func tracePPTLinkedImage(cf *utils.CompoundFile, traceUrl string) error {
+	le := binary.LittleEndian
+
+	// 1、读取 Current User，获取最后一次编辑的位置
+	user := cf.Stream("Current User")
+	if len(user) < 20 {
+		return errPpt
+	}
+	if le.Uint32(user[12:]) != pptHeaderToken {
+		return ErrEncrypted
+	}
+	lastEdit := int(le.Uint32(user[16:]))
+
+	stream := cf.Stream("PowerPoint Document")
+	edit, err := readPPTRecord(stream, lastEdit)
+	if err != nil || edit.Type != pptUserEditAtom || edit.Len < 28 {
+		return errPpt
+	}
+	if edit.Len > 28 {
+		// 存在 encryptSessionPersistIdRef
+		return ErrEncrypted
+	}
+
+	// 2、读取持久化目录，新的编辑覆盖旧的编辑
+	persist, err := readPPTPersist(stream, lastEdit)
+	if err != nil {
+		return err
+	}
+
+	// 3、查找第一张幻灯片
+	body := stream[lastEdit+8:]
+	docOffset, ok := persist[le.Uint32(body[16:])]
+	if !ok {
+		return errPpt
+	}
+	document, err := readPPTRecord(stream, docOffset)
+	if err != nil || document.Type != pptDocument {
+		return errPpt
+	}
+
+	slideId := uint32(0)
+	for _, list := range pptChildren(stream, document) {
+		if list.Type != pptSlideListWithText || list.Instance != 0 {
+			continue
+		}
+		for _, atom := range pptChildren(stream, list) {
+			if atom.Type == pptSlidePersistAtom && atom.Len >= 4 {
+				slideId = le.Uint32(stream[atom.Offset+8:])
+				break
+			}
+		}
+	}
+	slideOffset, ok := persist[slideId]
+	if slideId == 0 || !ok {
+		return errPpt
+	}
+
+	slide, err := readPPTRecord(stream, slideOffset)
+	if err != nil || slide.Type != pptSlide {
+		return errPpt
+	}
+	slideData := append([]byte{}, stream[slideOffset:slideOffset+8+slide.Len]...)
+
+	name := encodePPTString(traceUrl)
+	if bytes.Contains(slideData, name) {
+		// 已存在追踪信息
+		return nil
+	}
+
+	// 4、查找 PPDrawing > DgContainer > FDG、SpgrContainer
+	path := []pptRecord{{Offset: 0, Type: pptSlide, Len: slide.Len}}
+	current := path[0]
+	for _, typ := range []uint16{pptDrawing, pptDgContainer} {
+		var next *pptRecord
+		for _, child := range pptChildren(slideData, current) {
+			if child.Type == typ {
+				child := child
+				next = &child
+				break
+			}
+		}
+		if next == nil {
+			return errPpt
+		}
+		path = append(path, *next)
+		current = *next
+	}
+
+	var fdg, spgr *pptRecord
+	for _, child := range pptChildren(slideData, current) {
+		child := child
+		switch child.Type {
+		case pptFDG:
+			fdg = &child
+		case pptSpgrContainer:
+			if spgr == nil {
+				spgr = &child
+			}
+		}
+	}
+	if fdg == nil || spgr == nil || fdg.Len < 8 {
+		return errPpt
+	}
+	path = append(path, *spgr)
+
+	// 5、添加图片形状，更新 FDG 中的形状数量、最后的形状 Id
+	csp := le.Uint32(slideData[fdg.Offset+8:])
+	spid := le.Uint32(slideData[fdg.Offset+12:]) + 1
+	le.PutUint32(slideData[fdg.Offset+8:], csp+1)
+	le.PutUint32(slideData[fdg.Offset+12:], spid)
+
+	shape := pptLinkedPicture(spid, name)
+	end := spgr.Offset + 8 + spgr.Len
+	slideData = append(slideData[:end], append(shape, slideData[end:]...)...)
+	for _, record := range path {
+		le.PutUint32(slideData[record.Offset+4:], uint32(record.Len+len(shape)))
+	}
+
+	// 6、追加幻灯片、PersistDirectoryAtom、UserEditAtom
+	newSlide := len(stream)
+	stream = append(stream, slideData...)
+
+	newPersist := len(stream)
+	stream = appendPPTHeader(stream, 0, 0, pptPersistDirectory, 8)
+	stream = le.AppendUint32(stream, slideId|1<<20)
+	stream = le.AppendUint32(stream, uint32(newSlide))
+
+	newEdit := len(stream)
+	editData := append([]byte{}, stream[lastEdit:lastEdit+8+edit.Len]...)
+	le.PutUint32(editData[8+8:], uint32(lastEdit))
+	le.PutUint32(editData[8+12:], uint32(newPersist))
+	stream = append(stream, editData...)
+
+	le.PutUint32(user[16:], uint32(newEdit))
+	cf.SetStream("PowerPoint Document", stream)
+	return nil
+}
+
+// readPPTPersist 读取持久化目录，返回 persistId => 偏移
+func readPPTPersist(stream []byte, lastEdit int) (map[uint32]int, error) {
+	le := binary.LittleEndian
+
+	persist := make(map[uint32]int)
+	for offset, i := lastEdit, 0; i < 1024; i++ {
+		edit, err := readPPTRecord(stream, offset)
+		if err != nil || edit.Type != pptUserEditAtom || edit.Len < 28 {
+			return nil, errPpt
+		}
+		body := stream[offset+8:]
+
+		dir, err := readPPTRecord(stream, int(le.Uint32(body[12:])))
+		if err != nil || dir.Type != pptPersistDirectory {
+			return nil, errPpt
+		}
+		data := stream[dir.Offset+8 : dir.Offset+8+dir.Len]
+		for j := 0; j+4 <= len(data); {
+			entry := le.Uint32(data[j:])
+			id, count := entry&0xfffff, int(entry>>20)
+			j += 4
+			for k := 0; k < count && j+4 <= len(data); k++ {
+				if _, ok := persist[id+uint32(k)]; !ok {
+					persist[id+uint32(k)] = int(le.Uint32(data[j:]))
+				}
+				j += 4
+			}
+		}
+
+		offset = int(le.Uint32(body[8:]))
+		if offset == 0 {
+			return persist, nil
+		}
+	}
+	return nil, errPpt
+}
+
+// readPPTRecord 读取记录头
+func readPPTRecord(stream []byte, offset int) (pptRecord, error) {
+	le := binary.LittleEndian
+	if offset < 0 || offset+8 > len(stream) {
+		return pptRecord{}, errPpt
+	}
+
+	verInstance := le.Uint16(stream[offset:])
+	record := pptRecord{
+		Offset:   offset,
+		Ver:      verInstance & 0x0f,
+		Instance: verInstance >> 4,
+		Type:     le.Uint16(stream[offset+2:]),
+		Len:      int(le.Uint32(stream[offset+4:])),
+	}
+	if offset+8+record.Len > len(stream) {
+		return pptRecord{}, errPpt
+	}
+	return record, nil
+}
+
+// pptChildren 读取容器记录的子记录
+func pptChildren(stream []byte, parent pptRecord) (children []pptRecord) {
+	end := parent.Offset + 8 + parent.Len
+	for offset := parent.Offset + 8; offset+8 <= end; {
+		record, err := readPPTRecord(stream, offset)
+		if err != nil || offset+8+record.Len > end {
+			break
+		}
+		children = append(children, record)
+		offset += 8 + record.Len
+	}
+	return children
+}
+
+// appendPPTHeader 追加记录头
+func appendPPTHeader(b []byte, ver, instance, typ uint16, length int) []byte {
+	le := binary.LittleEndian
+	b = le.AppendUint16(b, ver|instance<<4)
+	b = le.AppendUint16(b, typ)
+	return le.AppendUint32(b, uint32(length))
+}
+
+// pptLinkedPicture 生成链接图片形状（OfficeArtSpContainer）
+// pibName 为图片地址，pibFlags 为 msoblipflagURL | msoblipflagLinkToFile
+func pptLinkedPicture(spid uint32, name []byte) []byte {
+	le := binary.LittleEndian
+
+	// OfficeArtFSP：fHaveAnchor | fHaveSpt
+	fsp := appendPPTHeader(nil, 2, pptPictureFrame, pptFSP, 8)
+	fsp = le.AppendUint32(fsp, spid)
+	fsp = le.AppendUint32(fsp, 0x00000a00)
+
+	// OfficeArtFOPT
+	var props []byte
+	props = le.AppendUint16(props, pptPropPibName|pptPropComplex)
+	props = le.AppendUint32(props, uint32(len(name)))
+	props = le.AppendUint16(props, pptPropPibFlags)
+	props = le.AppendUint32(props, pptBlipFlagURL|pptBlipFlagLinkToFile)
+	props = append(props, name...)
+	fopt := appendPPTHeader(nil, 3, 2, pptFOPT, len(props))
+	fopt = append(fopt, props...)
+
+	// OfficeArtClientAnchor：top left right bottom
+	anchor := appendPPTHeader(nil, 0, 0, pptClientAnchor, 8)
+	anchor = append(anchor, 0, 0, 0, 0, 1, 0, 1, 0)
+
+	body := append(append(fsp, fopt...), anchor...)
+	return append(appendPPTHeader(nil, 0x0f, 0, pptSpContainer, len(body)), body...)
+}
+
+// encodePPTString UTF-16 编码，以 0 结尾
+func encodePPTString(s string) []byte {
+	le := binary.LittleEndian
+	var b []byte
+	for _, v := range utf16.Encode([]rune(s)) {
+		b = le.AppendUint16(b, v)
+	}
+	return le.AppendUint16(b, 0)
+}
@@ -0,0 +1,159 @@
+package ms_office
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const relsTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"></Relationships>`
+
+// readRels 读取 .rels 文件，不存在时返回空的 Relationships
+func readRels(relsFile string) (document *etree.Document, err error) {
+	if _, err = os.Stat(relsFile); err != nil {
+		document = etree.NewDocument()
+		err = document.ReadFromString(relsTemp)
+		if err != nil {
+			return nil, err
+		}
+		return document, nil
+	}
+	return utils.ReadXml(relsFile)
+}
+
+// writeRels 输出 .rels 文件，目录不存在时自动创建
+func writeRels(document *etree.Document, relsFile string) (err error) {
+	err = utils.CreateDir(filepath.Dir(relsFile))
+	if err != nil {
+		return err
+	}
+	return utils.WriteXml(document, relsFile)
+}
+
+// nextRelId 获取下一个可用的 rId
+func nextRelId(relationships *etree.Element) string {
+	max := 0
+	for _, element := range relationships.ChildElements() {
+		id := element.SelectAttrValue("Id", "")
+		if n, err := strconv.Atoi(strings.TrimPrefix(id, "rId")); err == nil && n > max && n != 9999 {
+			max = n
+		}
+	}
+	return fmt.Sprintf("rId%d", max+1)
+}
+
+// findRels 查找指定类型的关系节点
+func findRels(relationships *etree.Element, relType string) (elements []*etree.Element) {
+	for _, element := range relationships.ChildElements() {
+		if element.SelectAttrValue("Type", "") == relType {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// setTraceRels 添加/修改追踪关系
+// relsFile: .rels 文件
+// id: 关系 Id
+// relType: 关系类型
+// traceUrl: 追踪地址
+func setTraceRels(relsFile, id, relType, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	document, err = readRels(relsFile)
+	if err != nil {
+		return err
+	}
+
+	exist := false
+	relationships := document.SelectElement("Relationships")
+	for _, element := range relationships.ChildElements() {
+		// 判断 Id 属性，存在则替换为 traceUrl
+		if element.SelectAttrValue("Id", "") == id {
+			element.CreateAttr("Type", relType)
+			element.CreateAttr("Target", traceUrl)
+			element.CreateAttr("TargetMode", "External")
+			exist = true
+			break
+		}
+	}
+
+	if !exist {
+		// 添加节点
+		node := relationships.CreateElement("Relationship")
+		node.CreateAttr("Id", id)
+		node.CreateAttr("Type", relType)
+		node.CreateAttr("Target", traceUrl)
+		node.CreateAttr("TargetMode", "External")
+	}
+
+	return writeRels(document, relsFile)
+}
+
+// addContentType 添加 Override 节点到 [Content_Types].xml 文件
+// partName: 部件名称，例如 /word/header1.xml
+// contentType: 部件类型
+func addContentType(tempDir, partName, contentType string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	typesFile := filepath.Join(tempDir, "[Content_Types].xml")
+	document, err = utils.ReadXml(typesFile)
+	if err != nil {
+		return err
+	}
+
+	types := document.SelectElement("Types")
+	for _, element := range types.SelectElements("Override") {
+		if element.SelectAttrValue("PartName", "") == partName {
+			element.CreateAttr("ContentType", contentType)
+			return utils.WriteXml(document, typesFile)
+		}
+	}
+
+	node := types.CreateElement("Override")
+	node.CreateAttr("PartName", partName)
+	node.CreateAttr("ContentType", contentType)
+
+	return utils.WriteXml(document, typesFile)
+}
+
+// addRels 添加内部关系，已存在相同类型和目标的关系时直接返回其 Id
+// relsFile: .rels 文件
+// relType: 关系类型
+// target: 目标部件，相对于 .rels 所属部件
+func addRels(relsFile, relType, target string) (id string, err error) {
+	var (
+		document *etree.Document
+	)
+
+	document, err = readRels(relsFile)
+	if err != nil {
+		return "", err
+	}
+
+	relationships := document.SelectElement("Relationships")
+	for _, element := range findRels(relationships, relType) {
+		if element.SelectAttrValue("Target", "") == target {
+			return element.SelectAttrValue("Id", ""), nil
+		}
+	}
+
+	id = nextRelId(relationships)
+	node := relationships.CreateElement("Relationship")
+	node.CreateAttr("Id", id)
+	node.CreateAttr("Type", relType)
+	node.CreateAttr("Target", target)
+
+	return id, writeRels(document, relsFile)
+}
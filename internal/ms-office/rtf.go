@@ -0,0 +1,351 @@
+package ms_office
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"tracer/pkg/utils"
+)
+
+const rtfMagic = `{\rtf`
+const rtfTextHeader = `{\rtf1\ansi\ansicpg1252\deff0\uc1{\fonttbl{\f0\fswiss Arial;}}` + "\n" + `\pard\plain\f0\fs22 `
+
+var errRtf = errors.New("invalid rtf document")
+
+// 位于文档头部的分组，模板分组插入到这些分组之后
+var rtfHeaderGroups = map[string]bool{
+	"fonttbl": true, "filetbl": true, "colortbl": true, "stylesheet": true, "listtable": true,
+	"listoverridetable": true, "revtbl": true, "rsidtbl": true, "generator": true, "info": true,
+}
+
+// rtfGroup RTF 分组
+type rtfGroup struct {
+	Start int    // { 的位置
+	End   int    // } 之后的位置
+	Depth int    // 深度，最外层为 0
+	Word  string // 第一个控制字，忽略 \*
+}
+
+// GenTracerRTF 生成可追踪文档（RTF）
+// 添加远程模板 {\*\template traceUrl} 和链接图片域 INCLUDEPICTURE，已存在的远程追踪信息会被替换
+// 文档只能有一个模板，已有的本地模板也会被替换，删除追踪信息后不会恢复
+// srcFile 不是 RTF 文件时作为纯文本转换
+func GenTracerRTF(srcFile, dstFile, traceUrl string) (err error) {
+	var (
+		data []byte
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、读取文件，纯文本转换为 RTF
+	data, err = os.ReadFile(srcFile)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, []byte(rtfMagic)) {
+		data = rtfFromText(data)
+	}
+
+	// 2、删除已存在的追踪信息
+	data, err = removeRTFTracer(data)
+	if err != nil {
+		return err
+	}
+
+	// 3、添加远程模板、链接图片
+	data, err = traceRTF(data, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 4、生成新的 rtf 文件
+	return os.WriteFile(dstFile, data, os.ModePerm)
+}
+
+// VerifyTracerRTF 获取文档中的远程模板、链接图片地址
+func VerifyTracerRTF(filename string) (urls []string, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := parseRTFGroups(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if traceUrl, ok := rtfTraceUrl(data, group); ok {
+			urls = append(urls, traceUrl)
+		}
+	}
+	return urls, nil
+}
+
+// RemoveTracerRTF 删除文档中的远程模板、链接图片
+func RemoveTracerRTF(srcFile, dstFile string) (err error) {
+	data, err := os.ReadFile(srcFile)
+	if err != nil {
+		return err
+	}
+
+	data, err = removeRTFTracer(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dstFile, data, os.ModePerm)
+}
+
+func traceRTF(data []byte, traceUrl string) ([]byte, error) {
+	groups, err := parseRTFGroups(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1、已存在模板分组（例如本地模板）时替换，文档中只保留一个 \template 分组
+	// 不存在时插入到头部分组之后，不存在头部分组时插入到 \rtf1 之后
+	var templates []rtfGroup
+	insert := -1
+	for _, group := range groups {
+		if group.Depth != 1 {
+			continue
+		}
+		if group.Word == "template" {
+			templates = append(templates, group)
+		} else if rtfHeaderGroups[group.Word] {
+			insert = group.End
+		}
+	}
+	if len(templates) > 0 {
+		insert = templates[0].Start
+	}
+	if insert < 0 {
+		insert = len(rtfMagic)
+		for insert < len(data) && (data[insert] >= '0' && data[insert] <= '9') {
+			insert++
+		}
+	}
+	template := `{\*\template ` + rtfEscape(traceUrl) + `}`
+
+	// 2、链接图片域插入到文档末尾，\d 表示只保存链接
+	field := `{\field{\*\fldinst {INCLUDEPICTURE "` + rtfEscape(traceUrl) + `" \\d \\* MERGEFORMAT}}{\fldrslt }}`
+	end := groups[0].End - 1
+
+	var b bytes.Buffer
+	b.Write(data[:insert])
+	b.WriteString(template)
+	offset := insert
+	for _, group := range templates {
+		b.Write(data[offset:group.Start])
+		offset = group.End
+	}
+	b.Write(data[offset:end])
+	b.WriteString(field)
+	b.Write(data[end:])
+	return b.Bytes(), nil
+}
+
+// removeRTFTracer 删除远程模板分组、链接到 URL 的 INCLUDEPICTURE 域
+func removeRTFTracer(data []byte) ([]byte, error) {
+	groups, err := parseRTFGroups(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	offset := 0
+	for _, group := range groups {
+		if group.Start < offset {
+			// 位于已删除的分组中
+			continue
+		}
+		if _, ok := rtfTraceUrl(data, group); ok {
+			b.Write(data[offset:group.Start])
+			offset = group.End
+		}
+	}
+	b.Write(data[offset:])
+	return b.Bytes(), nil
+}
+
+// rtfTraceUrl 判断分组是否为远程模板或链接图片域，返回地址
+func rtfTraceUrl(data []byte, group rtfGroup) (string, bool) {
+	var traceUrl string
+	switch group.Word {
+	case "template":
+		traceUrl = strings.TrimSpace(rtfText(data[group.Start:group.End]))
+	case "field":
+		instr := strings.TrimSpace(rtfText(data[group.Start:group.End]))
+		if !strings.HasPrefix(strings.ToUpper(instr), "INCLUDEPICTURE") {
+			return "", false
+		}
+		instr = strings.TrimSpace(instr[len("INCLUDEPICTURE"):])
+		if strings.HasPrefix(instr, `"`) {
+			traceUrl, _, _ = strings.Cut(instr[1:], `"`)
+		} else {
+			traceUrl, _, _ = strings.Cut(instr, " ")
+		}
+	default:
+		return "", false
+	}
+	return traceUrl, strings.Contains(traceUrl, "://")
+}
+
+// parseRTFGroups 解析分组，按照起始位置排序
+func parseRTFGroups(data []byte) (groups []rtfGroup, err error) {
+	var stack []int
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '{':
+			stack = append(stack, len(groups))
+			groups = append(groups, rtfGroup{Start: i, Depth: len(stack) - 1})
+
+			// 读取第一个控制字，忽略 \*
+			j := i + 1
+			if bytes.HasPrefix(data[j:], []byte(`\*`)) {
+				j += 2
+			}
+			for j < len(data) && (data[j] == ' ' || data[j] == '\r' || data[j] == '\n') {
+				j++
+			}
+			if j < len(data) && data[j] == '\\' {
+				word, _, _ := readRTFControl(data, j)
+				groups[len(groups)-1].Word = word
+			}
+		case '}':
+			if len(stack) == 0 {
+				return nil, errRtf
+			}
+			groups[stack[len(stack)-1]].End = i + 1
+			stack = stack[:len(stack)-1]
+		case '\\':
+			word, param, next := readRTFControl(data, i)
+			if word == "bin" && param > 0 {
+				// 跳过二进制数据
+				next += param
+			}
+			i = next - 1
+		}
+	}
+	if len(stack) != 0 || len(groups) == 0 || groups[0].Start != 0 {
+		return nil, errRtf
+	}
+	return groups, nil
+}
+
+// readRTFControl 读取控制字或控制符，返回名称、参数、下一个位置
+func readRTFControl(data []byte, i int) (word string, param int, next int) {
+	j := i + 1
+	if j >= len(data) {
+		return "", 0, j
+	}
+
+	// 控制符，例如 \\ \{ \} \'hh
+	if !isRTFLetter(data[j]) {
+		if data[j] == '\'' && j+3 <= len(data) {
+			return "'", 0, j + 3
+		}
+		return string(data[j]), 0, j + 1
+	}
+
+	for j < len(data) && isRTFLetter(data[j]) {
+		j++
+	}
+	word = string(data[i+1 : j])
+
+	k := j
+	if k < len(data) && data[k] == '-' {
+		k++
+	}
+	for k < len(data) && data[k] >= '0' && data[k] <= '9' {
+		k++
+	}
+	if k > j {
+		param, _ = strconv.Atoi(string(data[j:k]))
+	}
+
+	// 空格是控制字的一部分
+	if k < len(data) && data[k] == ' ' {
+		k++
+	}
+	return word, param, k
+}
+
+func isRTFLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// rtfText 获取分组中的文本，忽略控制字
+func rtfText(data []byte) string {
+	var b strings.Builder
+	skip := 0
+	for i := 0; i < len(data); {
+		switch data[i] {
+		case '{', '}', '\r', '\n':
+			i++
+		case '\\':
+			word, param, next := readRTFControl(data, i)
+			switch {
+			case word == "'" && next-i == 4:
+				if v, err := strconv.ParseUint(string(data[i+2:i+4]), 16, 8); err == nil {
+					b.WriteRune(rune(v))
+				}
+			case word == "u":
+				b.WriteRune(rune(uint16(int16(param))))
+				skip = 1
+			case word == "bin":
+				next += param
+			case len(word) == 1 && !isRTFLetter(word[0]) && word != "*":
+				b.WriteString(word)
+			}
+			i = next
+		default:
+			if skip > 0 {
+				skip--
+			} else {
+				b.WriteByte(data[i])
+			}
+			i++
+		}
+	}
+	return b.String()
+}
+
+// rtfEscape 转义特殊字符，非 ASCII 字符使用 \uN?
+func rtfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '{' || r == '}':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			for _, u := range utf16.Encode([]rune{r}) {
+				b.WriteString(`\u` + strconv.Itoa(int(int16(u))) + "?")
+			}
+		}
+	}
+	return b.String()
+}
+
+// rtfFromText 纯文本转换为 RTF，每行一个段落
+func rtfFromText(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		data = bytes.ToValidUTF8(data, []byte("?"))
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = rtfEscape(line)
+	}
+	return []byte(rtfTextHeader + strings.Join(lines, "\\par\n") + "\\par\n}")
+}
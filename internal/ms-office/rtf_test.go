@@ -0,0 +1,84 @@
+package ms_office
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRTF = `{\rtf1\ansi\deff0{\fonttbl{\f0 Arial;}}{\*\template C:\\Templates\\Normal.dot}{\info{\author test}}
+\pard\plain hello \'e9{\field{\*\fldinst {HYPERLINK "http://example.com/"}}{\fldrslt link}}\par
+}`
+
+func TestGenTracerRTF(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"rtf", testRTF},
+		{"text", "第一行 {test}\r\nsecond \\ line\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			srcFile := filepath.Join(dir, "source")
+			dstFile := filepath.Join(dir, "tracer.rtf")
+			if err := os.WriteFile(srcFile, []byte(tt.content), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := GenTracerRTF(srcFile, dstFile, traceUrl); err != nil {
+				t.Fatal(err)
+			}
+			// 重复生成，替换已存在的追踪信息
+			if err := GenTracerRTF(dstFile, dstFile, traceUrl+"2"); err != nil {
+				t.Fatal(err)
+			}
+
+			urls, err := VerifyTracerRTF(dstFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(urls) != 2 || urls[0] != traceUrl+"2" || urls[1] != traceUrl+"2" {
+				t.Errorf("VerifyTracerRTF() = %v", urls)
+			}
+
+			data, err := os.ReadFile(dstFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(string(data), `{\rtf1`) || !strings.HasSuffix(string(data), "}") {
+				t.Errorf("invalid rtf: %s", data)
+			}
+			if n := strings.Count(string(data), `\template`); n != 1 {
+				t.Errorf("template count = %d, want 1: %s", n, data)
+			}
+
+			if err = RemoveTracerRTF(dstFile, dstFile); err != nil {
+				t.Fatal(err)
+			}
+			if urls, _ = VerifyTracerRTF(dstFile); len(urls) != 0 {
+				t.Errorf("VerifyTracerRTF() after remove = %v", urls)
+			}
+
+			// 本地模板被替换，删除追踪信息后不再包含模板
+			if tt.name == "rtf" {
+				want := strings.Replace(testRTF, `{\*\template C:\\Templates\\Normal.dot}`, "", 1)
+				data, _ = os.ReadFile(dstFile)
+				if string(data) != want {
+					t.Errorf("RemoveTracerRTF() = %s, want %s", data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRTFFromText(t *testing.T) {
+	got := string(rtfFromText([]byte("\xef\xbb\xbf中 {a}\\")))
+	want := rtfTextHeader + `\u20013? \{a\}\\\par` + "\n}"
+	if got != want {
+		t.Errorf("rtfFromText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,560 @@
+package ms_office
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+// Profile 追踪信息的生成方式
+type Profile string
+
+const (
+	ProfileDefault Profile = "default" // 固定的关系 Id（rId9999），重复生成时只替换追踪地址
+	ProfileStealth Profile = "stealth" // 关系 Id、形状 Id、名称、位置与文档中已有的内容一致
+)
+
+var ErrProfile = errors.New("unknown profile")
+
+// stealthPixel 1 像素对应的 EMU，Office 插入的图片尺寸都是像素的整数倍
+const stealthPixel = 9525
+
+// settings.xml 中 attachedTemplate 之前的子节点
+var docxSettingsBefore = []string{
+	"writeProtection", "view", "zoom", "removePersonalInformation", "removeDateAndTime",
+	"doNotDisplayPageBoundaries", "displayBackgroundShape", "printPostScriptOverText",
+	"printFractionalCharacterWidth", "printFormsData", "embedTrueTypeFonts", "embedSystemFonts",
+	"saveSubsetFonts", "saveFormsData", "mirrorMargins", "alignBordersAndEdges",
+	"bordersDoNotSurroundHeader", "bordersDoNotSurroundFooter", "gutterAtTop",
+	"hideSpellingErrors", "hideGrammaticalErrors", "activeWritingStyle", "proofState", "formsDesign",
+}
+
+// applyStealth 修改追踪技术添加的内容，避免对比 XML 时被发现
+// 1、rId9999 修改为关系文件中下一个可用的 Id，绝对路径的内部关系修改为相对路径
+// 2、图片使用文档中下一个可用的形状 Id，名称与 Office 插入图片时一致（Picture N、图片 N）
+// 3、图片尺寸为 1 像素，幻灯片和工作表中的图片移动到已有形状的下层
+// 4、删除模板中的缩进，w、r、wp 命名空间声明移动到根节点
+// 5、数据连接的 Id 9999 修改为下一个可用的连接 Id
+// 修改后不再包含固定的关系 Id，重复生成时会再次添加追踪信息
+func applyStealth(tempDir string) error {
+	err := filepath.WalkDir(tempDir, func(relsFile string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".rels") {
+			return nil
+		}
+		return stealthRels(tempDir, relsFile)
+	})
+	if err != nil {
+		return err
+	}
+	return stealthConnections(tempDir)
+}
+
+// stealthConnections 追踪连接的 Id 9999 修改为下一个可用的 Id，同时修改查询表中的引用
+func stealthConnections(tempDir string) error {
+	dir := path.Dir(xlsxWorkbookPart(tempDir))
+	xmlFile := partFile(tempDir, path.Join(dir, "connections.xml"))
+	document, err := utils.ReadXml(xmlFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var (
+		connection *etree.Element
+		max        = 0
+	)
+	for _, element := range document.Root().SelectElements("connection") {
+		id := element.SelectAttrValue("id", "")
+		if id == xlsxConnectionId {
+			connection = element
+		} else if n, err := strconv.Atoi(id); err == nil && n > max {
+			max = n
+		}
+	}
+	if connection == nil {
+		return nil
+	}
+	id := strconv.Itoa(max + 1)
+	connection.CreateAttr("id", id)
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(partFile(tempDir, path.Join(dir, "queryTables")), "*.xml"))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		document, err = utils.ReadXml(match)
+		if err != nil {
+			return err
+		}
+		if root := document.Root(); root.SelectAttrValue("connectionId", "") == xlsxConnectionId {
+			root.CreateAttr("connectionId", id)
+			err = utils.WriteXml(document, match)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stealthRels 修改关系文件及其所属部件
+func stealthRels(tempDir, relsFile string) (err error) {
+	var (
+		document *etree.Document
+		part     *etree.Document
+	)
+
+	// 包的关系 _rels/.rels 不包含追踪信息
+	if filepath.Dir(relsFile) == filepath.Join(tempDir, "_rels") {
+		return nil
+	}
+
+	document, err = utils.ReadXml(relsFile)
+	if err != nil {
+		return err
+	}
+	relationships := document.SelectElement("Relationships")
+	if relationships == nil {
+		return nil
+	}
+
+	// 所属部件，例如 word/_rels/settings.xml.rels => word/settings.xml
+	xmlFile := filepath.Join(filepath.Dir(filepath.Dir(relsFile)), strings.TrimSuffix(filepath.Base(relsFile), ".rels"))
+	partName := filepath.ToSlash(strings.TrimPrefix(xmlFile, tempDir+string(filepath.Separator)))
+
+	// 1、绝对路径修改为相对路径
+	changed := false
+	for _, element := range relationships.ChildElements() {
+		target := element.SelectAttrValue("Target", "")
+		if element.SelectAttrValue("TargetMode", "") == "External" || !strings.HasPrefix(target, "/") {
+			continue
+		}
+		rel, err := filepath.Rel(filepath.Dir(xmlFile), filepath.Join(tempDir, filepath.FromSlash(target)))
+		if err == nil {
+			element.CreateAttr("Target", filepath.ToSlash(rel))
+			changed = true
+		}
+	}
+
+	// 2、rId9999 修改为下一个可用的 Id，各格式的追踪关系使用相同的 Id
+	id := ""
+	for _, element := range relationships.ChildElements() {
+		if element.SelectAttrValue("Id", "") == docxTraceId {
+			id = nextRelId(relationships)
+			element.CreateAttr("Id", id)
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+	compactXml(relationships)
+
+	// 3、修改所属部件中对关系的引用
+	part, err = utils.ReadXml(xmlFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return utils.WriteXml(document, relsFile)
+	}
+	if err != nil {
+		return err
+	}
+	root := part.Root()
+	if id != "" {
+		for _, element := range root.FindElements("//*") {
+			for i, attr := range element.Attr {
+				if attr.Space == "r" && attr.Value == docxTraceId {
+					element.Attr[i].Value = id
+				}
+			}
+		}
+
+		// 4、根据部件类型修改图片
+		switch root.Tag {
+		case "settings":
+			stealthSettings(root)
+		case "sld":
+			stealthSlide(root, id)
+		case "wsDr":
+			stealthDrawing(root, id)
+		default:
+			err = stealthDOCXPicture(tempDir, partName, root, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// 5、模板中的缩进和命名空间声明与 Office 输出不同
+	hoistNamespaces(root)
+	compactXml(root)
+
+	err = utils.WriteXml(part, xmlFile)
+	if err != nil {
+		return err
+	}
+	return utils.WriteXml(document, relsFile)
+}
+
+// stealthSettings 将 attachedTemplate 移动到 schema 规定的位置
+func stealthSettings(root *etree.Element) {
+	template := root.SelectElement("w:attachedTemplate")
+	if template == nil {
+		return
+	}
+	root.RemoveChild(template)
+
+	index := 0
+	for _, child := range root.ChildElements() {
+		for _, name := range docxSettingsBefore {
+			if child.Space == "w" && child.Tag == name {
+				index = child.Index() + 1
+			}
+		}
+	}
+	root.InsertChildAt(index, template)
+}
+
+// stealthSlide 幻灯片中的远程图片移动到最下层，位置和大小与第一个有填充的形状相同
+func stealthSlide(root *etree.Element, id string) {
+	tree := root.FindElement("p:cSld/p:spTree")
+	if tree == nil {
+		return
+	}
+
+	for _, pic := range tree.SelectElements("p:pic") {
+		if blip := pic.FindElement("p:blipFill/a:blip"); blip == nil || blip.SelectAttrValue("r:link", "") != id {
+			continue
+		}
+
+		// 1、删除占位符，使用下一个可用的 Id
+		if nvPr := pic.FindElement("p:nvPicPr/p:nvPr"); nvPr != nil {
+			if ph := nvPr.SelectElement("p:ph"); ph != nil {
+				nvPr.RemoveChild(ph)
+			}
+		}
+		if locks := pic.FindElement("p:nvPicPr/p:cNvPicPr/a:picLocks"); locks != nil {
+			locks.RemoveAttr("noGrp")
+		}
+		tree.RemoveChild(pic)
+		n := maxShapeId(tree, "cNvPr") + 1
+		if cNvPr := pic.FindElement("p:nvPicPr/p:cNvPr"); cNvPr != nil {
+			cNvPr.CreateAttr("id", strconv.Itoa(n))
+			cNvPr.CreateAttr("name", pictureName(tree, n-1))
+		}
+
+		// 2、位置和大小，没有形状时为左上角 1 像素
+		off, ext := [2]string{"0", "0"}, [2]string{strconv.Itoa(stealthPixel), strconv.Itoa(stealthPixel)}
+		if xfrm := slideBackShape(tree); xfrm != nil {
+			off = [2]string{xfrm.FindElement("a:off").SelectAttrValue("x", "0"), xfrm.FindElement("a:off").SelectAttrValue("y", "0")}
+			ext = [2]string{xfrm.FindElement("a:ext").SelectAttrValue("cx", "0"), xfrm.FindElement("a:ext").SelectAttrValue("cy", "0")}
+		}
+		if xfrm := pic.FindElement("p:spPr/a:xfrm"); xfrm != nil {
+			xfrm.FindElement("a:off").CreateAttr("x", off[0])
+			xfrm.FindElement("a:off").CreateAttr("y", off[1])
+			xfrm.FindElement("a:ext").CreateAttr("cx", ext[0])
+			xfrm.FindElement("a:ext").CreateAttr("cy", ext[1])
+		}
+
+		// 3、移动到 grpSpPr 之后，即最下层
+		index := 0
+		if grpSpPr := tree.SelectElement("p:grpSpPr"); grpSpPr != nil {
+			index = grpSpPr.Index() + 1
+		}
+		tree.InsertChildAt(index, pic)
+	}
+}
+
+// slideBackShape 获取用于遮挡图片的形状：优先选择有填充的形状，其次为第一个形状
+func slideBackShape(tree *etree.Element) *etree.Element {
+	var first *etree.Element
+	for _, sp := range tree.SelectElements("p:sp") {
+		xfrm := sp.FindElement("p:spPr/a:xfrm")
+		if xfrm == nil || xfrm.FindElement("a:off") == nil || xfrm.FindElement("a:ext") == nil {
+			continue
+		}
+		if sp.FindElement("p:spPr/a:solidFill") != nil {
+			return xfrm
+		}
+		if first == nil {
+			first = xfrm
+		}
+	}
+	return first
+}
+
+// stealthDrawing 工作表绘图中的图片移动到最下层，锚定在第一个已有形状的位置
+func stealthDrawing(root *etree.Element, id string) {
+	var (
+		ours   []*etree.Element
+		others []*etree.Element
+	)
+
+	// 1、区分追踪图片和已有形状
+	// 追踪图片包括远程图片和 traceXLSXImage 添加的内嵌图片（descr="Picture" name="Image N"）
+	for _, anchor := range root.ChildElements() {
+		pic := anchor.SelectElement("xdr:pic")
+		if pic == nil {
+			others = append(others, anchor)
+			continue
+		}
+		blip := pic.FindElement("xdr:blipFill/a:blip")
+		cNvPr := pic.FindElement("xdr:nvPicPr/xdr:cNvPr")
+		if (blip != nil && blip.SelectAttrValue("r:link", "") == id) ||
+			(cNvPr != nil && cNvPr.SelectAttrValue("descr", "") == "Picture" && strings.HasPrefix(cNvPr.SelectAttrValue("name", ""), "Image ")) {
+			ours = append(ours, anchor)
+		} else {
+			others = append(others, anchor)
+		}
+	}
+	if len(ours) == 0 {
+		return
+	}
+
+	// 2、锚点位置，没有形状时为 A1
+	col, colOff, row, rowOff := "0", 0, "0", 0
+	if len(others) > 0 {
+		if from := others[0].SelectElement("xdr:from"); from != nil {
+			col, row = childText(from, "xdr:col", "0"), childText(from, "xdr:row", "0")
+			colOff, _ = strconv.Atoi(childText(from, "xdr:colOff", "0"))
+			rowOff, _ = strconv.Atoi(childText(from, "xdr:rowOff", "0"))
+		}
+	}
+
+	n := 1
+	for _, anchor := range others {
+		if m := maxShapeId(anchor, "cNvPr"); m > n {
+			n = m
+		}
+	}
+	for i, anchor := range ours {
+		root.RemoveChild(anchor)
+
+		// 3、Id 和名称，Excel 插入图片时 Id 为序号加 1
+		n++
+		cNvPr := anchor.FindElement("xdr:pic/xdr:nvPicPr/xdr:cNvPr")
+		if cNvPr != nil {
+			cNvPr.RemoveAttr("descr")
+			cNvPr.CreateAttr("id", strconv.Itoa(n))
+			cNvPr.CreateAttr("name", pictureName(root, n-1))
+		}
+
+		// 4、位置和大小
+		setAnchorPoint(anchor.SelectElement("xdr:from"), col, colOff, row, rowOff)
+		setAnchorPoint(anchor.SelectElement("xdr:to"), col, colOff+stealthPixel, row, rowOff+stealthPixel)
+		if ext := anchor.SelectElement("xdr:ext"); ext != nil {
+			ext.CreateAttr("cx", strconv.Itoa(stealthPixel))
+			ext.CreateAttr("cy", strconv.Itoa(stealthPixel))
+		}
+		if xfrm := anchor.FindElement("xdr:pic/xdr:spPr/a:xfrm"); xfrm != nil {
+			setXfrm(xfrm)
+		}
+
+		// 5、移动到最下层
+		root.InsertChildAt(i, anchor)
+	}
+}
+
+// setAnchorPoint 修改锚点的单元格和偏移
+func setAnchorPoint(point *etree.Element, col string, colOff int, row string, rowOff int) {
+	if point == nil {
+		return
+	}
+	for tag, value := range map[string]string{
+		"xdr:col":    col,
+		"xdr:colOff": strconv.Itoa(colOff),
+		"xdr:row":    row,
+		"xdr:rowOff": strconv.Itoa(rowOff),
+	} {
+		if element := point.SelectElement(tag); element != nil {
+			element.SetText(value)
+		}
+	}
+}
+
+// stealthDOCXPicture 正文、页眉/页脚中的远程图片使用文档中下一个可用的 Id
+func stealthDOCXPicture(tempDir, part string, root *etree.Element, id string) error {
+	var (
+		pictures []*etree.Element
+	)
+
+	for _, inline := range root.FindElements("//wp:inline") {
+		blip := inline.FindElement(".//a:blip")
+		if blip != nil && blip.SelectAttrValue("r:link", "") == id {
+			pictures = append(pictures, inline)
+		}
+	}
+	if len(pictures) == 0 {
+		return nil
+	}
+
+	// 1、docPr Id 在整个文档中唯一，查找其它部件中的最大 Id
+	n := 0
+	entries, err := os.ReadDir(partFile(tempDir, path.Dir(part)))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".xml") || entry.Name() == path.Base(part) {
+			continue
+		}
+		document, err := utils.ReadXml(partFile(tempDir, path.Join(path.Dir(part), entry.Name())))
+		if err != nil || document.Root() == nil {
+			continue
+		}
+		// 其它部件中尚未处理的追踪图片
+		for _, docPr := range document.Root().FindElements("//wp:docPr") {
+			inline := docPr.Parent()
+			if blip := inline.FindElement(".//a:blip"); blip != nil && blip.SelectAttrValue("r:link", "") == docxTraceId {
+				continue
+			}
+			if m, err := strconv.Atoi(docPr.SelectAttrValue("id", "")); err == nil && m > n {
+				n = m
+			}
+		}
+	}
+	for _, docPr := range root.FindElements("//wp:docPr") {
+		if ours(docPr, pictures) {
+			continue
+		}
+		if m, err := strconv.Atoi(docPr.SelectAttrValue("id", "")); err == nil && m > n {
+			n = m
+		}
+	}
+
+	// 2、Id、名称、大小，Word 插入图片时名称中的序号与 Id 相同
+	for _, inline := range pictures {
+		n++
+		name := pictureName(root, n)
+		for _, element := range []*etree.Element{inline.SelectElement("wp:docPr"), inline.FindElement(".//pic:cNvPr")} {
+			if element != nil {
+				element.CreateAttr("id", strconv.Itoa(n))
+				element.CreateAttr("name", name)
+			}
+		}
+		if extent := inline.SelectElement("wp:extent"); extent != nil {
+			extent.CreateAttr("cx", strconv.Itoa(stealthPixel))
+			extent.CreateAttr("cy", strconv.Itoa(stealthPixel))
+		}
+		if xfrm := inline.FindElement(".//pic:spPr/a:xfrm"); xfrm != nil {
+			setXfrm(xfrm)
+		}
+	}
+	return nil
+}
+
+// ours 判断节点是否位于追踪图片中
+func ours(element *etree.Element, pictures []*etree.Element) bool {
+	for p := element.Parent(); p != nil; p = p.Parent() {
+		for _, picture := range pictures {
+			if p == picture {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setXfrm 修改图片位置为 0，大小为 1 像素
+func setXfrm(xfrm *etree.Element) {
+	if off := xfrm.SelectElement("a:off"); off != nil {
+		off.CreateAttr("x", "0")
+		off.CreateAttr("y", "0")
+	}
+	if ext := xfrm.SelectElement("a:ext"); ext != nil {
+		ext.CreateAttr("cx", strconv.Itoa(stealthPixel))
+		ext.CreateAttr("cy", strconv.Itoa(stealthPixel))
+	}
+}
+
+// maxShapeId 获取节点中 cNvPr、docPr 的最大 Id
+func maxShapeId(element *etree.Element, tag string) int {
+	max := 0
+	for _, child := range element.FindElements(".//*") {
+		if child.Tag != tag {
+			continue
+		}
+		if id, err := strconv.Atoi(child.SelectAttrValue("id", "")); err == nil && id > max {
+			max = id
+		}
+	}
+	return max
+}
+
+// pictureName 图片名称，部件中已有中文名称的形状时使用中文
+func pictureName(element *etree.Element, n int) string {
+	for _, child := range element.FindElements(".//*") {
+		if child.Tag != "cNvPr" && child.Tag != "docPr" {
+			continue
+		}
+		for _, r := range child.SelectAttrValue("name", "") {
+			if unicode.Is(unicode.Han, r) {
+				return "图片 " + strconv.Itoa(n)
+			}
+		}
+	}
+	return "Picture " + strconv.Itoa(n)
+}
+
+// childText 获取子节点的文本
+func childText(element *etree.Element, tag, fallback string) string {
+	if child := element.SelectElement(tag); child != nil {
+		return child.Text()
+	}
+	return fallback
+}
+
+// hoistNamespaces 删除子节点中的 w、r、wp 命名空间声明，根节点未声明时添加到根节点
+func hoistNamespaces(root *etree.Element) {
+	for _, element := range root.FindElements(".//*") {
+		for _, key := range []string{"w", "r", "wp"} {
+			attr := element.SelectAttr("xmlns:" + key)
+			if attr == nil {
+				continue
+			}
+			value := attr.Value
+			if declared := root.SelectAttr("xmlns:" + key); declared == nil {
+				root.CreateAttr("xmlns:"+key, value)
+			} else if declared.Value != value {
+				continue
+			}
+			element.RemoveAttr("xmlns:" + key)
+		}
+	}
+}
+
+// compactXml 删除节点之间只有空白的文本，文本节点（w:t、a:t 等）保持不变
+func compactXml(element *etree.Element) {
+	switch element.Tag {
+	case "t", "delText", "instrText", "delInstrText":
+		return
+	}
+	for i := len(element.Child) - 1; i >= 0; i-- {
+		switch child := element.Child[i].(type) {
+		case *etree.CharData:
+			if child.IsWhitespace() {
+				element.RemoveChildAt(i)
+			}
+		case *etree.Element:
+			compactXml(child)
+		}
+	}
+}
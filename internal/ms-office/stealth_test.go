@@ -0,0 +1,97 @@
+package ms_office
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+)
+
+func TestGenTracerStealth(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+	for _, technique := range Techniques("") {
+		t.Run(technique.Name, func(t *testing.T) {
+			srcFile := testutil.WriteZip(t, "source.zip", testFiles(technique.Format))
+			dstFile := filepath.Join(t.TempDir(), "tracer."+technique.Format)
+			if err := GenTracerProfile(srcFile, dstFile, traceUrl, ProfileStealth, technique.Name); err != nil {
+				t.Fatal(err)
+			}
+
+			found := false
+			for name, content := range testutil.ReadZipFile(t, dstFile) {
+				if strings.Contains(content, traceUrl) {
+					found = true
+				}
+				for _, tell := range []string{"rId9999", "Content Placeholder", `name="Image `, `cx="1"`, `Target="/`, "<w:p xmlns"} {
+					if strings.Contains(content, tell) {
+						t.Errorf("%s contains %s", name, tell)
+					}
+				}
+			}
+			if !found {
+				t.Error("traceUrl not found")
+			}
+		})
+	}
+}
+
+func TestStealthParts(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	settings := testDOCX()
+	settings["word/settings.xml"] = strings.Replace(testSettings, `<w:zoom w:percent="100"/>`,
+		`<w:zoom w:percent="100"/><w:proofState w:spelling="clean"/><w:defaultTabStop w:val="420"/>`, 1)
+
+	slide := testPPTX()
+	slide["ppt/slides/slide1.xml"] = strings.Replace(testSlide, "<p:grpSpPr/>", `<p:grpSpPr/>`+
+		`<p:sp><p:nvSpPr><p:cNvPr id="4" name="矩形 3"/><p:cNvSpPr/><p:nvPr/></p:nvSpPr><p:spPr><a:xfrm><a:off x="100" y="200"/><a:ext cx="300" cy="400"/></a:xfrm><a:solidFill><a:srgbClr val="4472C4"/></a:solidFill></p:spPr></p:sp>`, 1)
+
+	tests := []struct {
+		name      string
+		technique string
+		files     map[string]string
+		part      string
+		wants     []string
+	}{
+		{"settings", "docx-template", settings, "word/settings.xml",
+			[]string{`<w:proofState w:spelling="clean"/><w:attachedTemplate r:id="rId1"/><w:defaultTabStop`}},
+		{"settings rels", "docx-template", testDOCX(), "word/_rels/settings.xml.rels",
+			[]string{`Id="rId1"`}},
+		{"docx image", "docx-image", testDOCX(), "word/document.xml",
+			[]string{`<wp:extent cx="9525" cy="9525"/>`, `<wp:docPr id="1" name="Picture 1"/>`, `r:link="rId2"`}},
+		{"slide", "pptx-image", slide, "ppt/slides/slide1.xml",
+			[]string{`<p:grpSpPr/><p:pic>`, `<p:cNvPr id="5" name="图片 4"/>`, `<a:off x="100" y="200"/><a:ext cx="300" cy="400"/>`, `r:link="rId1"`}},
+		{"drawing", "xlsx-image", testXLSX(), "xl/drawings/drawing1.xml",
+			[]string{`<xdr:cNvPr id="2" name="Picture 1"/>`, `<xdr:cNvPr id="3" name="Picture 2"/>`, `<xdr:row>0</xdr:row>`, `r:link="rId2"`}},
+		{"drawing rels", "xlsx-image", testXLSX(), "xl/drawings/_rels/drawing1.xml.rels",
+			[]string{`Target="../media/image1.png"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcFile := testutil.WriteZip(t, "source.zip", tt.files)
+			dstFile := filepath.Join(t.TempDir(), "tracer.zip")
+			if err := GenTracerProfile(srcFile, dstFile, traceUrl, ProfileStealth, tt.technique); err != nil {
+				t.Fatal(err)
+			}
+
+			content := testutil.ReadZipFile(t, dstFile)[tt.part]
+			compact := strings.NewReplacer("\n", "", "\t", "", "    ", "").Replace(content)
+			for _, want := range tt.wants {
+				if !strings.Contains(compact, want) {
+					t.Errorf("%s does not contain %s:\n%s", tt.part, want, compact)
+				}
+			}
+		})
+	}
+}
+
+func TestGenTracerProfileUnknown(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCX())
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	err := GenTracerProfile(srcFile, dstFile, "http://localhost:9090/trace", "unknown", "docx-template")
+	if !errors.Is(err, ErrProfile) {
+		t.Errorf("GenTracerProfile() = %v, want ErrProfile", err)
+	}
+}
@@ -0,0 +1,86 @@
+package ms_office
+
+import (
+	"html"
+	"os"
+	"path"
+	"strings"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const docxAltChunkType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk"
+const docxAltChunkContentType = "text/html"
+
+// docxAltChunkName 追踪使用的 HTML 部件，与 Word 导入 HTML 片段时的命名一致
+const docxAltChunkName = "afchunk.htm"
+const docxAltChunkTemp = `<html><head><meta http-equiv="Content-Type" content="text/html; charset=utf-8"><link rel="stylesheet" type="text/css" href="${traceUrl}"></head><body></body></html>`
+
+// traceDOCXStylesheet 在正文末尾添加 altChunk 导入的 HTML 片段，片段中引用远程样式表
+// 打开文档时 Word 将 HTML 片段转换为正文，转换时请求样式表；追踪地址位于 HTML 中，不在 .rels 文件中
+func traceDOCXStylesheet(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 1、写入 afchunk.htm 文件，已存在时替换追踪地址
+	main := docxMainPart(tempDir)
+	part := path.Join(path.Dir(main), docxAltChunkName)
+	content := strings.Replace(docxAltChunkTemp, "${traceUrl}", html.EscapeString(traceUrl), 1)
+	err = os.WriteFile(partFile(tempDir, part), []byte(content), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	err = addContentType(tempDir, "/"+part, docxAltChunkContentType)
+	if err != nil {
+		return err
+	}
+
+	// 2、添加 document.xml.rels 关系，已存在时返回原有的 Id
+	id, err := addRels(partRels(tempDir, main), docxAltChunkType, docxAltChunkName)
+	if err != nil {
+		return err
+	}
+
+	// 3、修改 document.xml 文件
+	xmlFile := partFile(tempDir, main)
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	body := document.FindElement("w:document/w:body")
+	if body == nil {
+		return os.ErrNotExist
+	}
+	for _, element := range body.SelectElements("w:altChunk") {
+		if element.SelectAttrValue("r:id", "") == id {
+			// 已存在追踪信息
+			return nil
+		}
+	}
+
+	// altChunk 位于 sectPr 之前
+	chunk := etree.NewElement("w:altChunk")
+	chunk.CreateAttr("r:id", id)
+	if sectPr := body.SelectElement("w:sectPr"); sectPr != nil {
+		body.InsertChildAt(sectPr.Index(), chunk)
+	} else {
+		body.AddChild(chunk)
+	}
+
+	root := document.Root()
+	if root.SelectAttr("xmlns:r") == nil {
+		root.CreateAttr("xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships")
+	}
+
+	// 更新 document.xml 文件
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package ms_office
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+)
+
+func TestTraceDOCXStylesheet(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCX())
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	if err := GenTracer(srcFile, dstFile, "http://localhost:9090/old", "docx-stylesheet"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重复生成时只替换追踪地址，& 需要转义
+	traceUrl := "http://localhost:9090/trace?a=1&b=2"
+	if err := GenTracer(dstFile, dstFile+"2", traceUrl, "docx-stylesheet"); err != nil {
+		t.Fatal(err)
+	}
+
+	files := testutil.ReadZipFile(t, dstFile+"2")
+	chunk := files["word/"+docxAltChunkName]
+	if !strings.Contains(chunk, `<link rel="stylesheet" type="text/css" href="http://localhost:9090/trace?a=1&amp;b=2">`) {
+		t.Errorf("%s = %s", docxAltChunkName, chunk)
+	}
+
+	document := files["word/document.xml"]
+	if strings.Count(document, "w:altChunk") != 1 || strings.Index(document, "w:altChunk") > strings.Index(document, "w:sectPr") {
+		t.Errorf("document.xml = %s", document)
+	}
+	if strings.Count(files["word/_rels/document.xml.rels"], docxAltChunkType) != 1 {
+		t.Error("altChunk relationship count != 1")
+	}
+	if !strings.Contains(files["[Content_Types].xml"], `PartName="/word/`+docxAltChunkName+`"`) {
+		t.Error("missing content type")
+	}
+}
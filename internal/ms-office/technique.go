@@ -0,0 +1,365 @@
+package ms_office
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"errors"
+	"tracer/pkg/utils"
+)
+
+// Client 文档客户端
+type Client string
+
+const (
+	ClientMSOffice    Client = "Microsoft Office"
+	ClientLibreOffice Client = "LibreOffice"
+	ClientWPS         Client = "WPS"
+	ClientOnline      Client = "Office Online"
+)
+
+// Clients 兼容性表格中的客户端顺序
+var Clients = []Client{ClientMSOffice, ClientLibreOffice, ClientWPS, ClientOnline}
+
+// Support 客户端是否会请求远程资源
+type Support string
+
+const (
+	SupportYes     Support = "yes"     // 打开文档即请求
+	SupportPrompt  Support = "prompt"  // 需要用户确认（更新链接、启用编辑等）
+	SupportNo      Support = "no"      // 不会请求
+	SupportUnknown Support = "unknown" // 未验证
+)
+
+// Technique 追踪技术
+type Technique struct {
+	Name        string             // 名称，例如 docx-template
+	Format      string             // 文件格式：docx、xlsx、pptx，包括对应的宏文件、模板文件
+	Description string             // 说明
+	Compat      map[Client]Support // 客户端兼容性
+	Apply       func(tempDir, traceUrl string) error
+}
+
+var techniques = make(map[string]*Technique)
+
+func init() {
+	RegisterTechnique(&Technique{
+		Name:        "docx-template",
+		Format:      "docx",
+		Description: "settings.xml 中的 attachedTemplate 指向远程模板",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportNo,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceDOCXTemplate,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "docx-header",
+		Format:      "docx",
+		Description: "页眉/页脚中的远程图片（r:link）",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportUnknown,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceDOCXHeader,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "docx-image",
+		Format:      "docx",
+		Description: "正文末尾的远程图片（r:link）",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportUnknown,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceDOCXBody,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "docx-frame",
+		Format:      "docx",
+		Description: "webSettings.xml 中的 frameset 外部框架，文档会以框架页显示",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportNo,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceDOCXFrame,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "docx-customxml",
+		Format:      "docx",
+		Description: "document.xml.rels 中外部的 customXml 数据存储部件，打开时加载到数据存储",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportNo,
+			ClientWPS:         SupportNo,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceDOCXCustomXml,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "docx-font",
+		Format:      "docx",
+		Description: "fontTable.xml 中的远程嵌入字体（w:embedRegular）",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportNo,
+			ClientWPS:         SupportNo,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceDOCXFont,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "docx-stylesheet",
+		Format:      "docx",
+		Description: "altChunk 导入的 HTML 片段中的远程样式表，追踪地址不在 .rels 文件中",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportNo,
+			ClientWPS:         SupportNo,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceDOCXStylesheet,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "pptx-image",
+		Format:      "pptx",
+		Description: "slide1.xml 中的远程图片（r:link）",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportYes,
+			ClientLibreOffice: SupportUnknown,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: tracePPTXImage,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "xlsx-image",
+		Format:      "xlsx",
+		Description: "drawing1.xml 中的远程图片（r:link），受保护视图下不会请求",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportPrompt,
+			ClientLibreOffice: SupportUnknown,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceXLSXImage,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "xlsx-externallink",
+		Format:      "xlsx",
+		Description: "xl/externalLinks 中的外部工作簿链接，打开时提示更新链接",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportPrompt,
+			ClientLibreOffice: SupportPrompt,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceXLSXExternalLink,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "xlsx-webservice",
+		Format:      "xlsx",
+		Description: "隐藏行中的 WEBSERVICE 公式，启用编辑后重新计算时请求",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportPrompt,
+			ClientLibreOffice: SupportPrompt,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceXLSXWebService,
+	})
+	RegisterTechnique(&Technique{
+		Name:        "xlsx-connection",
+		Format:      "xlsx",
+		Description: "connections.xml 中的 Web 查询数据连接，启用内容后刷新时请求",
+		Compat: map[Client]Support{
+			ClientMSOffice:    SupportPrompt,
+			ClientLibreOffice: SupportNo,
+			ClientWPS:         SupportUnknown,
+			ClientOnline:      SupportNo,
+		},
+		Apply: traceXLSXConnection,
+	})
+}
+
+// RegisterTechnique 注册追踪技术，名称相同时覆盖
+func RegisterTechnique(technique *Technique) {
+	techniques[technique.Name] = technique
+}
+
+// LookupTechnique 根据名称查找追踪技术
+func LookupTechnique(name string) (*Technique, bool) {
+	technique, ok := techniques[name]
+	return technique, ok
+}
+
+// Techniques 获取指定格式的追踪技术，format 为空时返回全部
+func Techniques(format string) (list []*Technique) {
+	for _, technique := range techniques {
+		if format == "" || technique.Format == format {
+			list = append(list, technique)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// GenTracer 使用指定的追踪技术生成可追踪文件
+// names: 追踪技术名称，按顺序依次执行
+func GenTracer(srcFile, dstFile, traceUrl string, names ...string) (err error) {
+	return GenTracerProfile(srcFile, dstFile, traceUrl, ProfileDefault, names...)
+}
+
+// GenTracerProfile 使用指定的追踪技术和生成方式生成可追踪文件
+// profile: 生成方式，ProfileStealth 时修改追踪信息的 Id、名称、位置
+func GenTracerProfile(srcFile, dstFile, traceUrl string, profile Profile, names ...string) (err error) {
+	if profile == "" {
+		return fmt.Errorf("%w: %s", ErrProfile, profile)
+	}
+	return GenTracerOptions(srcFile, dstFile, traceUrl, Options{Profile: profile}, names...)
+}
+
+var ErrTagStealth = errors.New("technique tag cannot be used with stealth profile")
+
+// Options 生成可追踪文件的选项
+type Options struct {
+	Profile Profile // 生成方式，默认为 ProfileDefault
+
+	// TagTechnique http、https 追踪地址添加查询参数 v 为追踪技术名称，用于区分同一 token 的不同追踪技术
+	// 追踪技术名称容易被检测规则发现（technique-label），默认不添加，不能与 ProfileStealth 一起使用
+	TagTechnique bool
+}
+
+// GenTracerOptions 使用指定的追踪技术和选项生成可追踪文件
+func GenTracerOptions(srcFile, dstFile, traceUrl string, opts Options, names ...string) (err error) {
+	var (
+		tempDir string
+		list    []*Technique
+		profile = opts.Profile
+	)
+
+	if profile == "" {
+		profile = ProfileDefault
+	}
+	if profile != ProfileDefault && profile != ProfileStealth {
+		return fmt.Errorf("%w: %s", ErrProfile, profile)
+	}
+	if profile == ProfileStealth && opts.TagTechnique {
+		return ErrTagStealth
+	}
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	for _, name := range names {
+		technique, ok := LookupTechnique(name)
+		if !ok {
+			return fmt.Errorf("unknown technique: %s", name)
+		}
+		list = append(list, technique)
+	}
+
+	// 1、解压文件
+	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
+	if err != nil {
+		return err
+	}
+
+	// 2、根据主文档部件判断格式，支持宏文件、模板文件
+	format, err := DetectFormat(tempDir)
+	if err != nil {
+		return err
+	}
+	for _, technique := range list {
+		if technique.Format != format {
+			return fmt.Errorf("%s: technique does not support %s", technique.Name, format)
+		}
+	}
+
+	// 3、依次执行追踪技术，TagTechnique 时追踪地址中的查询参数 v 标识追踪技术
+	for _, technique := range list {
+		techniqueTraceUrl := traceUrl
+		if opts.TagTechnique {
+			techniqueTraceUrl = techniqueUrl(traceUrl, technique.Name)
+		}
+		err = technique.Apply(tempDir, techniqueTraceUrl)
+		if err != nil {
+			return fmt.Errorf("%s: %w", technique.Name, err)
+		}
+	}
+
+	// ProfileStealth 时修改追踪信息，使其与文档中已有的内容一致
+	if profile == ProfileStealth {
+		err = applyStealth(tempDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 4、压缩文件夹，生成新的文件
+	err = utils.CompressZip(tempDir, dstFile)
+	if err != nil {
+		return err
+	}
+
+	// 5、删除临时目录
+	err = os.RemoveAll(tempDir)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// techniqueUrl 在 http、https 追踪地址中设置查询参数 v 为追踪技术名称，用于区分同一 token 的不同追踪技术
+// file 地址（包括 UNC 路径）原样返回，Windows 文件路径不能包含查询参数
+// http://host/t/abc, docx-template => http://host/t/abc?v=docx-template
+func techniqueUrl(traceUrl, name string) string {
+	u, err := url.Parse(traceUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return traceUrl
+	}
+	query := u.Query()
+	query.Set("v", name)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// CompatTable 输出 markdown 格式的兼容性表格
+func CompatTable() string {
+	var b strings.Builder
+
+	b.WriteString("| technique | format |")
+	for _, client := range Clients {
+		b.WriteString(" " + string(client) + " |")
+	}
+	b.WriteString("\n|---|---|")
+	for range Clients {
+		b.WriteString("---|")
+	}
+	b.WriteString("\n")
+
+	for _, technique := range Techniques("") {
+		b.WriteString("| " + technique.Name + " | " + technique.Format + " |")
+		for _, client := range Clients {
+			support, ok := technique.Compat[client]
+			if !ok {
+				support = SupportUnknown
+			}
+			b.WriteString(" " + string(support) + " |")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
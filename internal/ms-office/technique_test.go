@@ -0,0 +1,123 @@
+package ms_office
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"errors"
+	"tracer/internal/testutil"
+)
+
+func TestTechniques(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+	for _, technique := range Techniques("") {
+		t.Run(technique.Name, func(t *testing.T) {
+			srcFile := testutil.WriteZip(t, "source.zip", testFiles(technique.Format))
+			dstFile := filepath.Join(t.TempDir(), "tracer."+technique.Format)
+			if err := GenTracer(srcFile, dstFile, traceUrl, technique.Name); err != nil {
+				t.Fatal(err)
+			}
+
+			// 重复生成，不应报错
+			if err := GenTracer(dstFile, dstFile+"2", traceUrl, technique.Name); err != nil {
+				t.Fatal(err)
+			}
+
+			found := false
+			for _, content := range testutil.ReadZipFile(t, dstFile+"2") {
+				if strings.Contains(content, traceUrl) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s: traceUrl not found", technique.Name)
+			}
+
+			for _, client := range Clients {
+				if _, ok := technique.Compat[client]; !ok {
+					t.Errorf("%s: missing compat for %s", technique.Name, client)
+				}
+			}
+		})
+	}
+}
+
+func TestGenTracerUnknown(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCX())
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	if err := GenTracer(srcFile, dstFile, "http://localhost:9090/trace", "unknown"); err == nil {
+		t.Error("GenTracer() want error")
+	}
+}
+
+func TestCompatTable(t *testing.T) {
+	table := CompatTable()
+	for _, technique := range Techniques("") {
+		if !strings.Contains(table, "| "+technique.Name+" |") {
+			t.Errorf("missing %s", technique.Name)
+		}
+	}
+	t.Log("\n" + table)
+}
+
+func TestGenTracerTechniqueUrl(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCX())
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	opts := Options{TagTechnique: true}
+	if err := GenTracerOptions(srcFile, dstFile, "http://localhost:9090/t/abc?v=old", opts, "docx-template", "docx-header"); err != nil {
+		t.Fatal(err)
+	}
+
+	// TagTechnique 时每个追踪技术的地址使用各自的名称作为查询参数 v
+	files := testutil.ReadZipFile(t, dstFile)
+	if !strings.Contains(files["word/_rels/settings.xml.rels"], `Target="http://localhost:9090/t/abc?v=docx-template"`) {
+		t.Errorf("settings.xml.rels = %s", files["word/_rels/settings.xml.rels"])
+	}
+	if !strings.Contains(files["word/_rels/header1.xml.rels"], `Target="http://localhost:9090/t/abc?v=docx-header"`) {
+		t.Errorf("header1.xml.rels = %s", files["word/_rels/header1.xml.rels"])
+	}
+
+	if got := techniqueUrl("file://host/share/t/abc", "docx-template"); got != "file://host/share/t/abc" {
+		t.Errorf("techniqueUrl = %s", got)
+	}
+
+	// 默认不添加，不能与 ProfileStealth 一起使用
+	if err := GenTracer(srcFile, dstFile, "http://localhost:9090/t/abc", "docx-template"); err != nil {
+		t.Fatal(err)
+	}
+	if rels := testutil.ReadZipFile(t, dstFile)["word/_rels/settings.xml.rels"]; !strings.Contains(rels, `Target="http://localhost:9090/t/abc"`) {
+		t.Errorf("settings.xml.rels = %s", rels)
+	}
+	opts.Profile = ProfileStealth
+	if err := GenTracerOptions(srcFile, dstFile, "http://localhost:9090/t/abc", opts, "docx-template"); !errors.Is(err, ErrTagStealth) {
+		t.Errorf("GenTracerOptions(stealth) = %v, want ErrTagStealth", err)
+	}
+}
+
+func TestGenTracerUNC(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCX())
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	if err := GenTracer(srcFile, dstFile, `\\192.168.1.10\share\template.dotx`, "docx-template"); err != nil {
+		t.Fatal(err)
+	}
+
+	rels := testutil.ReadZipFile(t, dstFile)["word/_rels/settings.xml.rels"]
+	if !strings.Contains(rels, `Target="file://192.168.1.10/share/template.dotx"`) {
+		t.Errorf("rels = %s", rels)
+	}
+}
+
+func TestGenTracerDNS(t *testing.T) {
+	srcFile := testutil.WriteZip(t, "source.zip", testDOCX())
+	dstFile := filepath.Join(t.TempDir(), "tracer.docx")
+	tok, err := GenTracerDNS(srcFile, dstFile, "canary.test", nil, "docx-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rels := testutil.ReadZipFile(t, dstFile)["word/_rels/settings.xml.rels"]
+	if !strings.Contains(rels, `Target="http://`+tok+`.canary.test/"`) {
+		t.Errorf("rels = %s", rels)
+	}
+}
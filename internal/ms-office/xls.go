@@ -0,0 +1,218 @@
+package ms_office
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf16"
+
+	"tracer/pkg/utils"
+)
+
+// BIFF8 记录类型
+const (
+	xlsBOF         = 0x0809
+	xlsEOF         = 0x000a
+	xlsFilePass    = 0x002f
+	xlsBoundSheet  = 0x0085
+	xlsCountry     = 0x008c
+	xlsSupBook     = 0x01ae
+	xlsExternName  = 0x0023
+	xlsXCT         = 0x0059
+	xlsCRN         = 0x005a
+	xlsExternSheet = 0x0017
+	xlsIndex       = 0x020b
+
+	xlsMaxRecord = 8224
+)
+
+var errXls = errors.New("invalid excel workbook")
+
+// xlsRecord BIFF 记录
+type xlsRecord struct {
+	Type uint16
+	Data []byte
+}
+
+// GenTracerXLS 生成可追踪表格（Excel 97-2003）
+// 在全局子流中添加外部工作簿引用（SUPBOOK、EXTERNSHEET），打开时提示更新链接
+func GenTracerXLS(srcFile, dstFile, traceUrl string) (err error) {
+	var (
+		cf *utils.CompoundFile
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、读取复合文档
+	cf, err = utils.ReadCFB(srcFile)
+	if err != nil {
+		return err
+	}
+
+	// 2、添加外部工作簿引用
+	err = traceXLSExternalLink(cf, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	// 3、生成新的 xls 文件
+	return utils.WriteCFB(cf, dstFile)
+}
+
+func traceXLSExternalLink(cf *utils.CompoundFile, traceUrl string) (err error) {
+	le := binary.LittleEndian
+
+	// 1、读取 Workbook 流
+	stream := cf.Stream("Workbook")
+	if stream == nil {
+		return errXls
+	}
+	records, err := parseXLSRecords(stream)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 || records[0].Type != xlsBOF || len(records[0].Data) < 2 || le.Uint16(records[0].Data) != 0x0600 {
+		return errXls
+	}
+
+	// 2、在全局子流中查找插入位置
+	supBook := xlsSupBookRecord(traceUrl)
+	insert, externSheet, supBooks := -1, -1, 0
+	for i, record := range records {
+		if record.Type == xlsEOF {
+			break
+		}
+		switch record.Type {
+		case xlsFilePass:
+			return ErrEncrypted
+		case xlsSupBook:
+			if string(record.Data) == string(supBook.Data) {
+				// 已存在追踪信息
+				return nil
+			}
+			supBooks++
+			insert = i
+		case xlsBoundSheet, xlsCountry, xlsExternName, xlsXCT, xlsCRN:
+			insert = i
+		case xlsExternSheet:
+			externSheet = i
+		}
+	}
+	if insert < 0 {
+		return errXls
+	}
+
+	// 3、添加 SUPBOOK，修改/添加 EXTERNSHEET
+	xti := make([]byte, 6)
+	le.PutUint16(xti, uint16(supBooks))
+
+	added := []xlsRecord{supBook}
+	if externSheet >= 0 {
+		data := records[externSheet].Data
+		if len(data) < 2 || len(data)+6 > xlsMaxRecord {
+			return errXls
+		}
+		data = append(append([]byte{}, data...), xti...)
+		le.PutUint16(data, le.Uint16(data)+1)
+		records[externSheet].Data = data
+	} else {
+		added = append(added, xlsRecord{Type: xlsExternSheet, Data: append([]byte{1, 0}, xti...)})
+	}
+
+	// 插入位置之后的记录整体后移
+	position := 0
+	for _, record := range records[:insert+1] {
+		position += 4 + len(record.Data)
+	}
+	delta := 0
+	for _, record := range added {
+		delta += 4 + len(record.Data)
+	}
+	if externSheet >= 0 {
+		delta += 6
+	}
+
+	records = append(records[:insert+1], append(added, records[insert+1:]...)...)
+
+	// 4、修正 BOUNDSHEET、INDEX 中的流偏移
+	for _, record := range records {
+		switch record.Type {
+		case xlsBoundSheet:
+			if len(record.Data) >= 4 && int(le.Uint32(record.Data)) >= position {
+				le.PutUint32(record.Data, le.Uint32(record.Data)+uint32(delta))
+			}
+		case xlsIndex:
+			// reserved(4) rwMic(4) rwMac(4) ibXF(4) rgibRw(4*n)
+			for offset := 12; offset+4 <= len(record.Data); offset += 4 {
+				if n := le.Uint32(record.Data[offset:]); n != 0 && int(n) >= position {
+					le.PutUint32(record.Data[offset:], n+uint32(delta))
+				}
+			}
+		}
+	}
+
+	var out []byte
+	for _, record := range records {
+		out = le.AppendUint16(out, record.Type)
+		out = le.AppendUint16(out, uint16(len(record.Data)))
+		out = append(out, record.Data...)
+	}
+	cf.SetStream("Workbook", out)
+	return nil
+}
+
+// parseXLSRecords 解析 BIFF 记录
+func parseXLSRecords(b []byte) (records []xlsRecord, err error) {
+	le := binary.LittleEndian
+	for offset := 0; offset+4 <= len(b); {
+		typ := le.Uint16(b[offset:])
+		length := int(le.Uint16(b[offset+2:]))
+		if offset+4+length > len(b) {
+			return nil, errXls
+		}
+		// 复制数据，避免修改原始流
+		data := append([]byte{}, b[offset+4:offset+4+length]...)
+		records = append(records, xlsRecord{Type: typ, Data: data})
+		offset += 4 + length
+	}
+	return records, nil
+}
+
+// xlsSupBookRecord 生成外部工作簿引用
+// virtPath 使用 VirtualPath 编码：http 地址以 0x05 开头，UNC 路径以 0x01 @ 开头，目录以 0x03 分隔
+func xlsSupBookRecord(traceUrl string) xlsRecord {
+	le := binary.LittleEndian
+
+	path := "\x01" + traceUrl
+	if u, err := url.Parse(traceUrl); err == nil && u.Host != "" {
+		elem := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+		if u.RawQuery != "" {
+			elem[len(elem)-1] += "?" + u.RawQuery
+		}
+		if u.Scheme == "file" {
+			path = "\x01\x01@" + u.Host + "\x03" + strings.Join(elem, "\x03")
+		} else {
+			volume := u.Scheme + "://" + u.Host
+			path = "\x01\x05" + string(rune(len(volume))) + volume + strings.Join(elem, "\x03")
+		}
+	}
+
+	xlString := func(b []byte, s string) []byte {
+		u := utf16.Encode([]rune(s))
+		b = append(b, 1) // fHighByte
+		for _, v := range u {
+			b = le.AppendUint16(b, v)
+		}
+		return b
+	}
+
+	// ctab cch virtPath rgst
+	data := le.AppendUint16(nil, 1)
+	data = le.AppendUint16(data, uint16(len(utf16.Encode([]rune(path)))))
+	data = xlString(data, path)
+	data = le.AppendUint16(data, uint16(len("Sheet1")))
+	data = xlString(data, "Sheet1")
+	return xlsRecord{Type: xlsSupBook, Data: data}
+}
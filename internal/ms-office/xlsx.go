@@ -0,0 +1,383 @@
+package ms_office
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const xlsxExternalLinkType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLink"
+const xlsxExternalLinkPathType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/externalLinkPath"
+const xlsxExternalLinkContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.externalLink+xml"
+const xlsxExternalLinkTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<externalLink xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+    <externalBook r:id="rId9999">
+        <sheetNames>
+            <sheetName val="Sheet1"/>
+        </sheetNames>
+        <sheetDataSet>
+            <sheetData sheetId="0"/>
+        </sheetDataSet>
+    </externalBook>
+</externalLink>`
+
+const xlsxConnectionsType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/connections"
+const xlsxConnectionsContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.connections+xml"
+const xlsxConnectionsTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<connections xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"></connections>`
+
+// xlsxConnectionId 追踪连接使用固定的 Id，与 rId9999 相同，作为追踪信息的标识；名称与 Excel 默认名称一致，可能与已有连接重名
+const xlsxConnectionId = "9999"
+const xlsxConnectionName = "Connection"
+
+// ErrConnection 工作簿中已有 Id 相同但不是 Web 查询的连接
+var ErrConnection = errors.New("connection " + xlsxConnectionId + " is not a web query")
+
+const xlsxQueryTableType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/queryTable"
+const xlsxQueryTableContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.queryTable+xml"
+const xlsxQueryTableTemp = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<queryTable xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" name="${name}" connectionId="${id}" autoFormatId="16" applyNumberFormats="0" applyBorderFormats="0" applyFontFormats="0" applyPatternFormats="0" applyAlignmentFormats="0" applyWidthHeightFormats="0"/>`
+
+// workbook.xml 子节点顺序
+var xlsxWorkbookOrder = []string{
+	"fileVersion", "fileSharing", "workbookPr", "workbookProtection", "bookViews", "sheets",
+	"functionGroups", "externalReferences", "definedNames", "calcPr", "oleSize",
+	"customWorkbookViews", "pivotCaches", "smartTagPr", "smartTagTypes", "webPublishing",
+	"fileRecoveryPr", "webPublishObjects", "extLst",
+}
+
+// traceXLSXExternalLink 添加外部工作簿链接 xl/externalLinks/externalLink1.xml
+// Excel 打开时提示更新链接，确认后请求 traceUrl
+func traceXLSXExternalLink(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	workbook := xlsxWorkbookPart(tempDir)
+	linksDir := partFile(tempDir, path.Join(path.Dir(workbook), "externalLinks"))
+	err = utils.CreateDir(filepath.Join(linksDir, "_rels"))
+	if err != nil {
+		return err
+	}
+
+	// 1、已存在追踪链接时，只修改 traceUrl
+	entries, err := os.ReadDir(filepath.Join(linksDir, "_rels"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		relsFile := filepath.Join(linksDir, "_rels", entry.Name())
+		document, err = readRels(relsFile)
+		if err != nil {
+			return err
+		}
+		for _, element := range document.SelectElement("Relationships").ChildElements() {
+			if element.SelectAttrValue("Id", "") == xlsxTraceId {
+				return setTraceRels(relsFile, xlsxTraceId, xlsxExternalLinkPathType, traceUrl)
+			}
+		}
+	}
+
+	// 2、添加 externalLink1.xml，externalLink1.xml.rels 文件
+	name := ""
+	for i := 1; ; i++ {
+		name = "externalLink" + strconv.Itoa(i) + ".xml"
+		if _, err = os.Stat(filepath.Join(linksDir, name)); err != nil {
+			break
+		}
+	}
+
+	err = os.WriteFile(filepath.Join(linksDir, name), []byte(xlsxExternalLinkTemp), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	err = setTraceRels(filepath.Join(linksDir, "_rels", name+".rels"), xlsxTraceId, xlsxExternalLinkPathType, traceUrl)
+	if err != nil {
+		return err
+	}
+
+	err = addContentType(tempDir, "/"+path.Join(path.Dir(workbook), "externalLinks", name), xlsxExternalLinkContentType)
+	if err != nil {
+		return err
+	}
+
+	// 3、添加关系到 workbook.xml.rels 文件
+	relsFile := partRels(tempDir, workbook)
+	linkId, err := addRels(relsFile, xlsxExternalLinkType, "externalLinks/"+name)
+	if err != nil {
+		return err
+	}
+
+	// 4、添加 externalReference 到 workbook.xml 文件
+	xmlFile := partFile(tempDir, workbook)
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	root := document.Root()
+	references := selectOrInsert(root, "externalReferences", xlsxWorkbookOrder)
+	node := references.CreateElement("externalReference")
+	node.CreateAttr("r:id", linkId)
+	if root.SelectAttr("xmlns:r") == nil {
+		root.CreateAttr("xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships")
+	}
+
+	return utils.WriteXml(document, xmlFile)
+}
+
+// traceXLSXWebService 在 sheet1.xml 末尾添加隐藏行，单元格公式为 WEBSERVICE(traceUrl)
+// 并设置 fullCalcOnLoad，启用编辑后重新计算时请求 traceUrl
+func traceXLSXWebService(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	formula := `_xlfn.WEBSERVICE("` + strings.Replace(traceUrl, `"`, `""`, -1) + `")`
+
+	// 1、修改 sheet1.xml 文件
+	xmlFile := partFile(tempDir, xlsxFirstSheet(tempDir))
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	sheetData := document.FindElement("worksheet/sheetData")
+	if sheetData == nil {
+		return os.ErrNotExist
+	}
+
+	exist := false
+	last := 0
+	for _, row := range sheetData.SelectElements("row") {
+		if n, _ := strconv.Atoi(row.SelectAttrValue("r", "")); n > last {
+			last = n
+		}
+		for _, f := range row.FindElements("c/f") {
+			// 已存在追踪公式时替换 traceUrl
+			if strings.Contains(f.Text(), "WEBSERVICE(") {
+				f.SetText(formula)
+				exist = true
+			}
+		}
+	}
+
+	if !exist {
+		r := strconv.Itoa(last + 1)
+		row := sheetData.CreateElement("row")
+		row.CreateAttr("r", r)
+		row.CreateAttr("hidden", "1")
+
+		cell := row.CreateElement("c")
+		cell.CreateAttr("r", "A"+r)
+		cell.CreateAttr("t", "str")
+		cell.CreateElement("f").SetText(formula)
+		cell.CreateElement("v")
+	}
+
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+
+	// 2、修改 workbook.xml 文件，打开时重新计算
+	return setXLSXFullCalcOnLoad(tempDir)
+}
+
+// traceXLSXConnection 添加 Web 查询数据连接 xl/connections.xml，并在 sheet1 中添加查询表
+// 启用内容后刷新数据时请求 traceUrl
+func traceXLSXConnection(tempDir, traceUrl string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	// 1、添加/修改 connections.xml 文件
+	workbook := xlsxWorkbookPart(tempDir)
+	part := path.Join(path.Dir(workbook), "connections.xml")
+	xmlFile := partFile(tempDir, part)
+	if _, err = os.Stat(xmlFile); err != nil {
+		err = os.WriteFile(xmlFile, []byte(xlsxConnectionsTemp), os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	// 只修改 Id 为 9999 的追踪连接，不修改文档原有的连接
+	connections := document.Root()
+	names := make(map[string]bool)
+	for _, connection := range connections.SelectElements("connection") {
+		names[connection.SelectAttrValue("name", "")] = true
+		if connection.SelectAttrValue("id", "") != xlsxConnectionId {
+			continue
+		}
+
+		// 已存在追踪连接时替换 traceUrl
+		webPr := connection.SelectElement("webPr")
+		if webPr == nil {
+			return ErrConnection
+		}
+		webPr.CreateAttr("url", traceUrl)
+		return utils.WriteXml(document, xmlFile)
+	}
+
+	// 名称与 Excel 一致：Connection、Connection1、Connection2 ...
+	connectionName := xlsxConnectionName
+	for i := 1; names[connectionName]; i++ {
+		connectionName = xlsxConnectionName + strconv.Itoa(i)
+	}
+
+	connection := connections.CreateElement("connection")
+	connection.CreateAttr("id", xlsxConnectionId)
+	connection.CreateAttr("name", connectionName)
+	connection.CreateAttr("type", "4")
+	connection.CreateAttr("refreshedVersion", "6")
+	connection.CreateAttr("background", "1")
+	connection.CreateAttr("refreshOnLoad", "1")
+	connection.CreateAttr("saveData", "1")
+	webPr := connection.CreateElement("webPr")
+	webPr.CreateAttr("sourceData", "1")
+	webPr.CreateAttr("parsePre", "1")
+	webPr.CreateAttr("consecutive", "1")
+	webPr.CreateAttr("xl2000", "1")
+	webPr.CreateAttr("url", traceUrl)
+
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+
+	err = addContentType(tempDir, "/"+part, xlsxConnectionsContentType)
+	if err != nil {
+		return err
+	}
+
+	_, err = addRels(partRels(tempDir, workbook), xlsxConnectionsType, "connections.xml")
+	if err != nil {
+		return err
+	}
+
+	// 2、添加 queryTable1.xml，并添加关系到 sheet1.xml.rels 文件
+	queryDir := partFile(tempDir, path.Join(path.Dir(workbook), "queryTables"))
+	err = utils.CreateDir(queryDir)
+	if err != nil {
+		return err
+	}
+
+	name := ""
+	for i := 1; ; i++ {
+		name = "queryTable" + strconv.Itoa(i) + ".xml"
+		if _, err = os.Stat(filepath.Join(queryDir, name)); err != nil {
+			break
+		}
+	}
+
+	tpl := strings.Replace(xlsxQueryTableTemp, "${name}", connectionName, -1)
+	tpl = strings.Replace(tpl, "${id}", xlsxConnectionId, -1)
+	err = os.WriteFile(filepath.Join(queryDir, name), []byte(tpl), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	queryPart := "/" + path.Join(path.Dir(workbook), "queryTables", name)
+	err = addContentType(tempDir, queryPart, xlsxQueryTableContentType)
+	if err != nil {
+		return err
+	}
+
+	// 工作表可能不在 worksheets 目录中，使用绝对路径
+	relsFile := partRels(tempDir, xlsxFirstSheet(tempDir))
+	_, err = addRels(relsFile, xlsxQueryTableType, queryPart)
+	if err != nil {
+		return err
+	}
+
+	// 3、添加查询表区域名称到 workbook.xml 文件
+	xmlFile = partFile(tempDir, workbook)
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	sheetName := "Sheet1"
+	if sheet := document.FindElement("workbook/sheets/sheet"); sheet != nil {
+		sheetName = sheet.SelectAttrValue("name", sheetName)
+	}
+
+	// 查询表区域名称与 Excel 一致：ExternalData_1、ExternalData_2 ...
+	definedNames := selectOrInsert(document.Root(), "definedNames", xlsxWorkbookOrder)
+	names = make(map[string]bool)
+	for _, definedName := range definedNames.SelectElements("definedName") {
+		names[definedName.SelectAttrValue("name", "")] = true
+	}
+	dataName := ""
+	for i := 1; dataName == "" || names[dataName]; i++ {
+		dataName = "ExternalData_" + strconv.Itoa(i)
+	}
+
+	node := definedNames.CreateElement("definedName")
+	node.CreateAttr("name", dataName)
+	node.CreateAttr("localSheetId", "0")
+	node.CreateAttr("hidden", "1")
+	node.SetText("'" + strings.Replace(sheetName, "'", "''", -1) + "'!$A$1")
+
+	return utils.WriteXml(document, xmlFile)
+}
+
+// setXLSXFullCalcOnLoad 设置 workbook.xml 中的 calcPr fullCalcOnLoad
+func setXLSXFullCalcOnLoad(tempDir string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	xmlFile := partFile(tempDir, xlsxWorkbookPart(tempDir))
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	calcPr := selectOrInsert(document.Root(), "calcPr", xlsxWorkbookOrder)
+	calcPr.CreateAttr("fullCalcOnLoad", "1")
+
+	return utils.WriteXml(document, xmlFile)
+}
+
+// selectOrInsert 查找子节点，不存在时按照 order 中的顺序插入
+func selectOrInsert(parent *etree.Element, tag string, order []string) *etree.Element {
+	if element := parent.SelectElement(tag); element != nil {
+		return element
+	}
+
+	index := -1
+	for i, name := range order {
+		if name == tag {
+			index = i
+			break
+		}
+	}
+
+	element := etree.NewElement(tag)
+	for _, child := range parent.ChildElements() {
+		for _, name := range order[index+1:] {
+			if child.Tag == name {
+				parent.InsertChildAt(child.Index(), element)
+				return element
+			}
+		}
+	}
+
+	parent.AddChild(element)
+	return element
+}
@@ -0,0 +1,116 @@
+package ms_office
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+)
+
+func TestXLSXTechniques(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace?a=1&b=2"
+	tests := []struct {
+		name  string
+		parts map[string][]string
+	}{
+		{"xlsx-externallink", map[string][]string{
+			"xl/externalLinks/externalLink1.xml":            {`r:id="rId9999"`},
+			"xl/externalLinks/_rels/externalLink1.xml.rels": {xlsxExternalLinkPathType, `TargetMode="External"`},
+			"xl/_rels/workbook.xml.rels":                    {xlsxExternalLinkType},
+			"xl/workbook.xml":                               {"<externalReferences>", "<externalReference r:id=\"rId2\"/>"},
+			"[Content_Types].xml":                           {xlsxExternalLinkContentType},
+		}},
+		{"xlsx-webservice", map[string][]string{
+			"xl/worksheets/sheet1.xml": {`<row r="2" hidden="1">`, `_xlfn.WEBSERVICE(&quot;http://localhost:9090/trace?a=1&amp;b=2&quot;)`},
+			"xl/workbook.xml":          {`<calcPr fullCalcOnLoad="1"/>`},
+		}},
+		{"xlsx-connection", map[string][]string{
+			"xl/connections.xml":                  {`refreshOnLoad="1"`, `url="http://localhost:9090/trace?a=1&amp;b=2"`},
+			"xl/queryTables/queryTable1.xml":      {`connectionId="` + xlsxConnectionId + `"`},
+			"xl/worksheets/_rels/sheet1.xml.rels": {xlsxQueryTableType},
+			"xl/_rels/workbook.xml.rels":          {xlsxConnectionsType},
+			"xl/workbook.xml":                     {`<definedName name="ExternalData_1"`},
+			"[Content_Types].xml":                 {xlsxConnectionsContentType, xlsxQueryTableContentType},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcFile := testutil.WriteZip(t, "source.zip", testXLSX())
+			dstFile := filepath.Join(t.TempDir(), "tracer.xlsx")
+			if err := GenTracer(srcFile, dstFile, traceUrl, tt.name); err != nil {
+				t.Fatal(err)
+			}
+			if err := GenTracer(dstFile, dstFile+"2", traceUrl, tt.name); err != nil {
+				t.Fatal(err)
+			}
+
+			files := testutil.ReadZipFile(t, dstFile+"2")
+			for part, wants := range tt.parts {
+				content, ok := files[part]
+				if !ok {
+					t.Errorf("missing %s", part)
+					continue
+				}
+				for _, want := range wants {
+					if n := strings.Count(content, want); n != 1 {
+						t.Errorf("%s: count(%s) = %d, want 1", part, want, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTraceXLSXConnectionExisting(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+
+	// 工作簿已有名称为 Connection 的 Web 查询和查询表区域名称，不能修改
+	files := testXLSX()
+	files["xl/connections.xml"] = `<connections xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><connection id="1" name="Connection" type="4" refreshedVersion="6"><webPr url="https://intranet.example.com/report"/></connection></connections>`
+	files["xl/_rels/workbook.xml.rels"] = strings.Replace(testWorkbookRels, "</Relationships>",
+		`<Relationship Id="rId2" Type="`+xlsxConnectionsType+`" Target="connections.xml"/></Relationships>`, 1)
+	files["xl/workbook.xml"] = strings.Replace(testWorkbook, "</workbook>",
+		`<definedNames><definedName name="ExternalData_1" localSheetId="0" hidden="1">Sheet1!$C$3</definedName></definedNames></workbook>`, 1)
+
+	for _, profile := range []Profile{ProfileDefault, ProfileStealth} {
+		t.Run(string(profile), func(t *testing.T) {
+			srcFile := testutil.WriteZip(t, "source.zip", files)
+			dstFile := filepath.Join(t.TempDir(), "tracer.xlsx")
+			if err := GenTracerProfile(srcFile, dstFile, "http://localhost:9090/old", profile, "xlsx-connection"); err != nil {
+				t.Fatal(err)
+			}
+			if profile == ProfileDefault {
+				// 重复生成时只替换追踪连接的地址
+				if err := GenTracerProfile(dstFile, dstFile, traceUrl, profile, "xlsx-connection"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := testutil.ReadZipFile(t, dstFile)
+			connections := got["xl/connections.xml"]
+			if !strings.Contains(connections, `<connection id="1" name="Connection" type="4" refreshedVersion="6"><webPr url="https://intranet.example.com/report"/>`) {
+				t.Errorf("existing connection modified: %s", connections)
+			}
+			if strings.Count(connections, "<connection ") != 2 || !strings.Contains(connections, `name="Connection1"`) {
+				t.Errorf("connections.xml = %s", connections)
+			}
+			if !strings.Contains(got["xl/workbook.xml"], `<definedName name="ExternalData_2"`) {
+				t.Errorf("workbook.xml = %s", got["xl/workbook.xml"])
+			}
+
+			// stealth 时连接 Id 为下一个可用的 Id
+			id := xlsxConnectionId
+			if profile == ProfileStealth {
+				id = "2"
+			}
+			if !strings.Contains(connections, `<connection id="`+id+`" name="Connection1"`) ||
+				!strings.Contains(got["xl/queryTables/queryTable1.xml"], `connectionId="`+id+`"`) {
+				t.Errorf("connection id != %s: %s", id, connections)
+			}
+			if profile == ProfileDefault && !strings.Contains(connections, `url="`+traceUrl+`"`) {
+				t.Errorf("traceUrl not replaced: %s", connections)
+			}
+		})
+	}
+}
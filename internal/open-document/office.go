@@ -0,0 +1,269 @@
+package open_document
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+const (
+	MimeODT = "application/vnd.oasis.opendocument.text"
+	MimeODS = "application/vnd.oasis.opendocument.spreadsheet"
+	MimeODP = "application/vnd.oasis.opendocument.presentation"
+)
+
+// 追踪节点名称，用于判断是否已存在追踪信息
+const odfTraceImage = "Image9999"
+const odfTraceSection = "Section9999"
+
+var ErrFormat = errors.New("unsupported opendocument format")
+var ErrEncrypted = errors.New("encrypted file is not supported")
+
+// 命名空间，content.xml 根节点未声明时添加
+var odfNamespaces = map[string]string{
+	"text":  "urn:oasis:names:tc:opendocument:xmlns:text:1.0",
+	"table": "urn:oasis:names:tc:opendocument:xmlns:table:1.0",
+	"draw":  "urn:oasis:names:tc:opendocument:xmlns:drawing:1.0",
+	"svg":   "urn:oasis:names:tc:opendocument:xmlns:svg-compatible:1.0",
+	"xlink": "http://www.w3.org/1999/xlink",
+}
+
+// table:table 中位于 table:shapes 之前的子节点
+var odsShapesBefore = []string{"title", "desc", "table-source", "dde-source", "scenario", "forms"}
+
+// GenTracer 生成可追踪文件（odt、ods、odp）
+// 在 content.xml 中添加链接到 traceUrl 的外部图片
+func GenTracer(srcFile, dstFile, traceUrl string) (err error) {
+	return genTracer(srcFile, dstFile, traceUrl, traceImage)
+}
+
+// GenTracerSection 生成可追踪文档（odt）
+// 在 content.xml 末尾添加链接到 traceUrl 的区域，打开时提示更新链接
+func GenTracerSection(srcFile, dstFile, traceUrl string) (err error) {
+	return genTracer(srcFile, dstFile, traceUrl, traceSection)
+}
+
+func genTracer(srcFile, dstFile, traceUrl string, trace func(document *etree.Document, mimetype, traceUrl string) error) (err error) {
+	var (
+		tempDir  string
+		mimetype []byte
+		document *etree.Document
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、解压文件
+	tempDir, err = utils.ExtractZip(srcFile, "file-trace-*")
+	if err != nil {
+		return err
+	}
+
+	// 2、读取 mimetype，检查 manifest.xml
+	mimetype, err = os.ReadFile(filepath.Join(tempDir, "mimetype"))
+	if err != nil {
+		return ErrFormat
+	}
+
+	err = updateManifest(tempDir, string(mimetype))
+	if err != nil {
+		return err
+	}
+
+	// 3、修改 content.xml 文件
+	xmlFile := filepath.Join(tempDir, "content.xml")
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	err = trace(document, string(mimetype), traceUrl)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteXml(document, xmlFile)
+	if err != nil {
+		return err
+	}
+
+	// 4、压缩文件夹，mimetype 不压缩且位于第一个
+	err = utils.CompressODF(tempDir, dstFile)
+	if err != nil {
+		return err
+	}
+
+	// 5、删除临时目录
+	err = os.RemoveAll(tempDir)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// traceImage 添加外部图片
+// odt 添加到正文末尾的段落中，ods 添加到第一个工作表的 table:shapes 中，odp 添加到第一张幻灯片中
+func traceImage(document *etree.Document, mimetype, traceUrl string) error {
+	root := document.Root()
+	if root == nil {
+		return ErrFormat
+	}
+
+	// 已存在追踪信息时替换 traceUrl
+	for _, frame := range root.FindElements("//draw:frame") {
+		if frame.SelectAttrValue("draw:name", "") == odfTraceImage {
+			if image := frame.SelectElement("draw:image"); image != nil {
+				image.CreateAttr("xlink:href", traceUrl)
+				return nil
+			}
+		}
+	}
+
+	frame := etree.NewElement("draw:frame")
+	frame.CreateAttr("draw:name", odfTraceImage)
+	frame.CreateAttr("svg:width", "0.01cm")
+	frame.CreateAttr("svg:height", "0.01cm")
+	frame.CreateAttr("draw:z-index", "0")
+	image := frame.CreateElement("draw:image")
+	image.CreateAttr("xlink:href", traceUrl)
+	image.CreateAttr("xlink:type", "simple")
+	image.CreateAttr("xlink:show", "embed")
+	image.CreateAttr("xlink:actuate", "onLoad")
+
+	switch mimetype {
+	case MimeODT, MimeODT + "-template":
+		text := root.FindElement("office:body/office:text")
+		if text == nil {
+			return ErrFormat
+		}
+		frame.CreateAttr("text:anchor-type", "as-char")
+		text.CreateElement("text:p").AddChild(frame)
+		setNamespaces(root, "text", "draw", "svg", "xlink")
+	case MimeODS, MimeODS + "-template":
+		table := root.FindElement("office:body/office:spreadsheet/table:table")
+		if table == nil {
+			return ErrFormat
+		}
+		shapes := table.SelectElement("table:shapes")
+		if shapes == nil {
+			shapes = etree.NewElement("table:shapes")
+			index := 0
+			for _, child := range table.ChildElements() {
+				for _, tag := range odsShapesBefore {
+					if child.Tag == tag {
+						index = child.Index() + 1
+					}
+				}
+			}
+			table.InsertChildAt(index, shapes)
+		}
+		frame.CreateAttr("svg:x", "0cm")
+		frame.CreateAttr("svg:y", "0cm")
+		shapes.AddChild(frame)
+		setNamespaces(root, "table", "draw", "svg", "xlink")
+	case MimeODP, MimeODP + "-template":
+		page := root.FindElement("office:body/office:presentation/draw:page")
+		if page == nil {
+			return ErrFormat
+		}
+		frame.CreateAttr("svg:x", "0cm")
+		frame.CreateAttr("svg:y", "0cm")
+		page.AddChild(frame)
+		setNamespaces(root, "draw", "svg", "xlink")
+	default:
+		return ErrFormat
+	}
+	return nil
+}
+
+// traceSection 添加链接区域，只支持 odt
+func traceSection(document *etree.Document, mimetype, traceUrl string) error {
+	root := document.Root()
+	if root == nil || !strings.HasPrefix(mimetype, MimeODT) {
+		return ErrFormat
+	}
+
+	text := root.FindElement("office:body/office:text")
+	if text == nil {
+		return ErrFormat
+	}
+
+	// 已存在追踪信息时替换 traceUrl
+	for _, section := range text.FindElements("//text:section") {
+		if section.SelectAttrValue("text:name", "") == odfTraceSection {
+			if source := section.SelectElement("text:section-source"); source != nil {
+				source.CreateAttr("xlink:href", traceUrl)
+				return nil
+			}
+		}
+	}
+
+	// text:section-source 必须是区域的第一个子节点
+	section := text.CreateElement("text:section")
+	section.CreateAttr("text:name", odfTraceSection)
+	source := section.CreateElement("text:section-source")
+	source.CreateAttr("xlink:href", traceUrl)
+	source.CreateAttr("xlink:type", "simple")
+	source.CreateAttr("xlink:show", "embed")
+	section.CreateElement("text:p")
+	setNamespaces(root, "text", "xlink")
+	return nil
+}
+
+// updateManifest 检查 META-INF/manifest.xml
+// 加密文件不支持；缺少根节点或 content.xml 的条目时添加
+func updateManifest(tempDir, mimetype string) (err error) {
+	var (
+		document *etree.Document
+	)
+
+	xmlFile := filepath.Join(tempDir, "META-INF", "manifest.xml")
+	document, err = utils.ReadXml(xmlFile)
+	if err != nil {
+		return err
+	}
+
+	manifest := document.SelectElement("manifest:manifest")
+	if manifest == nil {
+		return ErrFormat
+	}
+
+	entries := make(map[string]bool)
+	for _, entry := range manifest.SelectElements("manifest:file-entry") {
+		if entry.SelectElement("manifest:encryption-data") != nil {
+			return ErrEncrypted
+		}
+		entries[entry.SelectAttrValue("manifest:full-path", "")] = true
+	}
+
+	if entries["/"] && entries["content.xml"] {
+		return nil
+	}
+	if !entries["/"] {
+		entry := etree.NewElement("manifest:file-entry")
+		entry.CreateAttr("manifest:full-path", "/")
+		entry.CreateAttr("manifest:media-type", mimetype)
+		manifest.InsertChildAt(0, entry)
+	}
+	if !entries["content.xml"] {
+		entry := manifest.CreateElement("manifest:file-entry")
+		entry.CreateAttr("manifest:full-path", "content.xml")
+		entry.CreateAttr("manifest:media-type", "text/xml")
+	}
+
+	return utils.WriteXml(document, xmlFile)
+}
+
+// setNamespaces 添加未声明的命名空间
+func setNamespaces(root *etree.Element, prefixes ...string) {
+	for _, prefix := range prefixes {
+		if root.SelectAttr("xmlns:"+prefix) == nil {
+			root.CreateAttr("xmlns:"+prefix, odfNamespaces[prefix])
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package open_document
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/testutil"
+)
+
+const testManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+    <manifest:file-entry manifest:full-path="/" manifest:media-type="${mimetype}"/>
+    <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>`
+
+var testContents = map[string]string{
+	MimeODT: `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" office:version="1.2">
+    <office:body>
+        <office:text>
+            <text:p>hello</text:p>
+        </office:text>
+    </office:body>
+</office:document-content>`,
+	MimeODS: `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" office:version="1.2">
+    <office:body>
+        <office:spreadsheet>
+            <table:table table:name="Sheet1">
+                <table:table-column/>
+                <table:table-row>
+                    <table:table-cell>
+                        <text:p>hello</text:p>
+                    </table:table-cell>
+                </table:table-row>
+            </table:table>
+        </office:spreadsheet>
+    </office:body>
+</office:document-content>`,
+	MimeODP: `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0" office:version="1.2">
+    <office:body>
+        <office:presentation>
+            <draw:page draw:name="page1"/>
+        </office:presentation>
+    </office:body>
+</office:document-content>`,
+}
+
+// writeTestODF 生成测试用的 OpenDocument 文件，mimetype 故意不放在第一个
+func writeTestODF(t *testing.T, mimetype string) string {
+	t.Helper()
+
+	return testutil.WriteZip(t, "source.odf", map[string]string{
+		"content.xml":           testContents[mimetype],
+		"META-INF/manifest.xml": strings.Replace(testManifest, "${mimetype}", mimetype, 1),
+		"mimetype":              mimetype,
+	})
+}
+
+// checkTestODF 检查 mimetype 位置、压缩方式，返回 content.xml 内容
+func checkTestODF(t *testing.T, filename, mimetype string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := testutil.ZipReader(t, b).File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store || len(first.Extra) != 0 {
+		t.Fatalf("first entry = %s method %d extra %d, want stored mimetype", first.Name, first.Method, len(first.Extra))
+	}
+
+	// 本地文件头固定 30 字节 + 文件名，mimetype 内容从偏移 38 开始
+	if string(b[30:38]) != "mimetype" || string(b[38:38+len(mimetype)]) != mimetype {
+		t.Errorf("mimetype not at offset 38")
+	}
+	return testutil.ReadZip(t, b)["content.xml"]
+}
+
+func TestGenTracer(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+	for _, mimetype := range []string{MimeODT, MimeODS, MimeODP} {
+		t.Run(mimetype, func(t *testing.T) {
+			srcFile := writeTestODF(t, mimetype)
+			dstFile := filepath.Join(t.TempDir(), "tracer.odf")
+			if err := GenTracer(srcFile, dstFile, traceUrl); err != nil {
+				t.Fatal(err)
+			}
+
+			// 重复生成，只修改 traceUrl
+			if err := GenTracer(dstFile, dstFile+"2", traceUrl+"2"); err != nil {
+				t.Fatal(err)
+			}
+
+			content := checkTestODF(t, dstFile+"2", mimetype)
+			if strings.Count(content, odfTraceImage) != 1 || !strings.Contains(content, `xlink:href="`+traceUrl+`2"`) {
+				t.Errorf("content.xml = %s", content)
+			}
+			if mimetype == MimeODS && strings.Index(content, "table:shapes") > strings.Index(content, "table:table-column") {
+				t.Error("table:shapes must precede table:table-column")
+			}
+		})
+	}
+}
+
+func TestGenTracerSection(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace"
+	srcFile := writeTestODF(t, MimeODT)
+	dstFile := filepath.Join(t.TempDir(), "tracer.odt")
+	if err := GenTracerSection(srcFile, dstFile, traceUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	content := checkTestODF(t, dstFile, MimeODT)
+	if !strings.Contains(content, `<text:section-source xlink:href="`+traceUrl+`"`) || !strings.Contains(content, "xmlns:xlink") {
+		t.Errorf("content.xml = %s", content)
+	}
+
+	if err := GenTracerSection(writeTestODF(t, MimeODS), dstFile, traceUrl); err != ErrFormat {
+		t.Errorf("GenTracerSection(ods) = %v, want %v", err, ErrFormat)
+	}
+}
@@ -0,0 +1,124 @@
+// Package testutil 测试使用的文件，只在测试中导入
+package testutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Zip 生成 zip 文件内容，成员按名称排序
+func Zip(t testing.TB, files map[string]string) []byte {
+	t.Helper()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(w, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// WriteZip 在新的临时目录中生成 zip 文件，返回文件路径
+// name: 文件名，例如 source.zip、临时密码.docx
+func WriteZip(t testing.TB, name string, files map[string]string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, Zip(t, files), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// ZipReader 打开 zip 文件内容，用于检查成员的文件头
+func ZipReader(t testing.TB, b []byte) *zip.Reader {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reader
+}
+
+// OpenZip 打开 zip 文件，用于检查成员的文件头
+func OpenZip(t testing.TB, filename string) *zip.Reader {
+	t.Helper()
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ZipReader(t, b)
+}
+
+// ReadZip 读取 zip 文件内容中的成员，跳过目录，并检查 .xml、.rels 成员的 XML 格式正确
+func ReadZip(t testing.TB, b []byte) map[string]string {
+	t.Helper()
+
+	reader := ZipReader(t, b)
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(data)
+
+		if !strings.HasSuffix(file.Name, ".xml") && !strings.HasSuffix(file.Name, ".rels") {
+			continue
+		}
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			_, err = decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", file.Name, err)
+			}
+		}
+	}
+	return files
+}
+
+// ReadZipFile 读取 zip 文件中的成员，同 ReadZip
+func ReadZipFile(t testing.TB, filename string) map[string]string {
+	t.Helper()
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ReadZip(t, b)
+}
@@ -0,0 +1,160 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Record token 记录
+type Record struct {
+	Token     string    `json:"token"`
+	Kind      string    `json:"kind"`                // 类型，例如 docx、kubeconfig
+	Memo      string    `json:"memo,omitempty"`      // 说明，例如文件名、部署位置
+	Technique string    `json:"technique,omitempty"` // 追踪技术，多个使用逗号分隔，例如 docx-template,docx-header
+	Created   time.Time `json:"created"`
+}
+
+// Registry token 注册表，保存为 JSON 文件
+// 生成文件时登记 token，collector 收到请求时根据 token 查找对应的文件
+type Registry struct {
+	path    string
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// OpenRegistry 打开注册表，文件不存在时创建
+// path 为空时只保存在内存中
+func OpenRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path, records: make(map[string]*Record)}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*Record
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		r.records[record.Token] = record
+	}
+	return r, nil
+}
+
+// Mint 生成并登记新的 token
+func (r *Registry) Mint(kind, memo string) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record := &Record{Token: New(), Kind: kind, Memo: memo, Created: time.Now().UTC()}
+	r.records[record.Token] = record
+
+	err := r.save()
+	if err != nil {
+		delete(r.records, record.Token)
+		return nil, err
+	}
+	return record, nil
+}
+
+// Add 登记已生成的 token，例如生成诱饵文档时返回的 token
+// 创建时间为空时使用当前时间，token 已存在时覆盖原记录
+func (r *Registry) Add(record *Record) error {
+	if !Valid(record.Token) {
+		return errors.New("invalid token " + record.Token)
+	}
+	if record.Created.IsZero() {
+		record.Created = time.Now().UTC()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.records[record.Token]
+	r.records[record.Token] = record
+
+	err := r.save()
+	if err != nil {
+		if ok {
+			r.records[record.Token] = old
+		} else {
+			delete(r.records, record.Token)
+		}
+		return err
+	}
+	return nil
+}
+
+// Lookup 查找 token
+func (r *Registry) Lookup(token string) (*Record, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.records[token]
+	return record, ok
+}
+
+// Records 获取全部记录，按照创建时间排序
+func (r *Registry) Records() []*Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := make([]*Record, 0, len(r.records))
+	for _, record := range r.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Created.Equal(records[j].Created) {
+			return records[i].Token < records[j].Token
+		}
+		return records[i].Created.Before(records[j].Created)
+	})
+	return records
+}
+
+// save 写入临时文件后重命名，避免写入中断时损坏注册表
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	records := make([]*Record, 0, len(r.records))
+	for _, record := range r.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Token < records[j].Token
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), r.path)
+}
@@ -0,0 +1,72 @@
+package token
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	r, err := OpenRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := r.Mint("kubeconfig", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.Mint("env", ".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Token == b.Token || !Valid(a.Token) {
+		t.Errorf("Mint() = %s, %s", a.Token, b.Token)
+	}
+
+	// 重新打开，记录保持不变
+	r, err = OpenRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, ok := r.Lookup(b.Token)
+	if !ok || record.Kind != "env" || record.Memo != ".env" {
+		t.Errorf("Lookup() = %v, %v", record, ok)
+	}
+	if _, ok = r.Lookup("unknown"); ok {
+		t.Error("Lookup(unknown) = true")
+	}
+	if records := r.Records(); len(records) != 2 {
+		t.Errorf("Records() = %d, want 2", len(records))
+	}
+
+	// 登记已生成的 token
+	tok := New()
+	if err = r.Add(&Record{Token: tok, Kind: "xlsx", Memo: "payroll.xlsx", Technique: "xlsx-image"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Add(&Record{Token: "abc"}); err == nil {
+		t.Error("Add(abc) = nil")
+	}
+	r, err = OpenRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record, ok = r.Lookup(tok); !ok || record.Technique != "xlsx-image" || record.Created.IsZero() {
+		t.Errorf("Lookup(added) = %v, %v", record, ok)
+	}
+}
+
+func TestRegistryMemory(t *testing.T) {
+	r, err := OpenRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := r.Mint("docx", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Lookup(record.Token); !ok {
+		t.Error("Lookup() = false")
+	}
+}
@@ -0,0 +1,257 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsigned  = errors.New("token is not signed")
+	ErrKey       = errors.New("unknown signing key")
+	ErrSignature = errors.New("invalid token signature")
+)
+
+// macSize 签名长度，编码后为 16 个字符
+const macSize = 10
+
+var kidPattern = regexp.MustCompile(`^[a-z0-9]{1,8}$`)
+
+// Key 签名密钥
+type Key struct {
+	ID      string    `json:"kid"`
+	Secret  []byte    `json:"secret"`
+	Created time.Time `json:"created"`
+}
+
+// Keyring 签名密钥，保存为 JSON 文件
+// 最后添加的密钥用于签名，其余密钥只用于校验轮换前生成的追踪地址
+// 签名后的 token 格式为 <kid>-<token>-<mac>，只包含小写字母、数字和连字符，可以直接用作 URL 路径或 DNS 标签
+type Keyring struct {
+	path string
+	mu   sync.Mutex
+	keys []*Key
+}
+
+// OpenKeyring 打开签名密钥，文件不存在或没有密钥时生成新的密钥
+// path 为空时只保存在内存中
+func OpenKeyring(path string) (*Keyring, error) {
+	k := &Keyring{path: path}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			err = json.Unmarshal(data, &k.keys)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(k.keys) == 0 {
+		_, err := k.Rotate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Rotate 生成新的密钥用于签名，旧的密钥保留用于校验
+func (k *Keyring) Rotate() (*Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// kid 为 k + 序号
+	n := 0
+	for _, key := range k.keys {
+		if i, err := strconv.Atoi(strings.TrimPrefix(key.ID, "k")); err == nil && i > n {
+			n = i
+		}
+	}
+	key := &Key{ID: "k" + strconv.Itoa(n+1), Secret: make([]byte, 32), Created: time.Now().UTC()}
+	_, err := rand.Read(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	k.keys = append(k.keys, key)
+	err = k.save()
+	if err != nil {
+		k.keys = k.keys[:len(k.keys)-1]
+		return nil, err
+	}
+	return key, nil
+}
+
+// Remove 删除密钥，使用该密钥签名的追踪地址不再通过校验
+// 不能删除当前用于签名的密钥
+func (k *Keyring) Remove(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for i, key := range k.keys {
+		if key.ID != kid {
+			continue
+		}
+		if i == len(k.keys)-1 {
+			return errors.New("cannot remove current signing key " + kid)
+		}
+
+		keys := k.keys
+		k.keys = append(append([]*Key(nil), keys[:i]...), keys[i+1:]...)
+		err := k.save()
+		if err != nil {
+			k.keys = keys
+		}
+		return err
+	}
+	return ErrKey
+}
+
+// Keys 获取全部密钥，最后一个为当前用于签名的密钥
+func (k *Keyring) Keys() []*Key {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return append([]*Key(nil), k.keys...)
+}
+
+// Sign 使用当前密钥签名 token
+// abc234abc234abcd => k1-abc234abc234abcd-<mac>
+func (k *Keyring) Sign(token string) string {
+	k.mu.Lock()
+	key := k.keys[len(k.keys)-1]
+	k.mu.Unlock()
+
+	token = strings.ToLower(token)
+	return key.ID + "-" + token + "-" + encoding.EncodeToString(mac(key, token))
+}
+
+// Verify 校验签名，返回其中的 token
+// 未签名时返回 ErrUnsigned，kid 不存在时返回 ErrKey，签名错误时返回 ErrSignature，都同时返回解析出的 token
+func (k *Keyring) Verify(value string) (string, error) {
+	kid, token, sum, ok := parseSigned(value)
+	if !ok {
+		return strings.ToLower(value), ErrUnsigned
+	}
+
+	var key *Key
+	k.mu.Lock()
+	for _, item := range k.keys {
+		if item.ID == kid {
+			key = item
+		}
+	}
+	k.mu.Unlock()
+
+	if key == nil {
+		return token, ErrKey
+	}
+	if !hmac.Equal(sum, mac(key, token)) {
+		return token, ErrSignature
+	}
+	return token, nil
+}
+
+// save 写入临时文件后重命名，密钥文件只有所有者可以读写
+func (k *Keyring) save() error {
+	if k.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(k.keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(k.path), filepath.Base(k.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0600)
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), k.path)
+}
+
+// mac 计算 token 的签名，kid 参与计算，避免不同密钥的签名互相替换
+func mac(key *Key, token string) []byte {
+	h := hmac.New(sha256.New, key.Secret)
+	h.Write([]byte(key.ID + "-" + token))
+	return h.Sum(nil)[:macSize]
+}
+
+// parseSigned 解析签名后的 token，不区分大小写
+func parseSigned(value string) (kid, token string, sum []byte, ok bool) {
+	parts := strings.Split(strings.ToLower(value), "-")
+	if len(parts) != 3 || !kidPattern.MatchString(parts[0]) || !Valid(parts[1]) {
+		return "", "", nil, false
+	}
+
+	sum, err := encoding.DecodeString(parts[2])
+	if err != nil || len(sum) != macSize {
+		return "", "", nil, false
+	}
+	return parts[0], parts[1], sum, true
+}
+
+// Signed 判断是否符合签名后的 token 格式，不校验签名
+func Signed(value string) bool {
+	_, _, _, ok := parseSigned(value)
+	return ok
+}
+
+// Signer 签名、校验 token，*Keyring 实现
+// 生成追踪地址、凭据时使用 Sign，collector 收到请求时使用 Verify
+type Signer interface {
+	Sign(token string) string
+	Verify(value string) (string, error)
+}
+
+// Encode 返回写入追踪地址或凭据的 token，signer 为 nil 时不签名
+// 不符合 token 格式或已经签名时保持不变
+func Encode(signer Signer, token string) string {
+	if isNil(signer) || !Valid(token) {
+		return token
+	}
+	return signer.Sign(token)
+}
+
+// isNil 判断 signer 是否为空，包括值为 nil 的 *Keyring
+func isNil(signer Signer) bool {
+	if signer == nil {
+		return true
+	}
+	k, ok := signer.(*Keyring)
+	return ok && k == nil
+}
+
+// Strip 去掉签名，返回其中的 token，不校验签名
+// k1-abc234abc234abcd-<mac> => abc234abc234abcd，未签名时保持不变
+func Strip(value string) string {
+	_, token, _, ok := parseSigned(value)
+	if !ok {
+		return value
+	}
+	return token
+}
@@ -0,0 +1,111 @@
+package token
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	k, err := OpenKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("keyring file = %v, %v", info, err)
+	}
+
+	// 1、签名、校验，不区分大小写
+	tok := New()
+	signed := k.Sign(tok)
+	if !strings.HasPrefix(signed, "k1-"+tok+"-") || !Signed(signed) {
+		t.Fatalf("Sign() = %s", signed)
+	}
+	if got, err := k.Verify(strings.ToUpper(signed)); got != tok || err != nil {
+		t.Errorf("Verify() = %s, %v", got, err)
+	}
+
+	// 2、篡改 token、签名，未签名
+	other := New()
+	tests := []struct {
+		name  string
+		value string
+		want  string
+		err   error
+	}{
+		{"token", strings.Replace(signed, tok, other, 1), other, ErrSignature},
+		{"mac", signed[:len(signed)-1] + "a", tok, ErrSignature},
+		{"kid", "k9" + signed[2:], tok, ErrKey},
+		{"unsigned", tok, tok, ErrUnsigned},
+	}
+	for _, tt := range tests {
+		if got, err := k.Verify(tt.value); got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Verify(%s) = %s, %v, want %s, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+
+	// 3、轮换后旧的签名依然有效，重新打开后密钥保持不变
+	key, err := k.Rotate()
+	if err != nil || key.ID != "k2" {
+		t.Fatalf("Rotate() = %v, %v", key, err)
+	}
+	k, err = OpenKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := k.Verify(signed); got != tok || err != nil {
+		t.Errorf("Verify(k1) = %s, %v", got, err)
+	}
+	if !strings.HasPrefix(k.Sign(tok), "k2-") {
+		t.Errorf("Sign() = %s, want k2", k.Sign(tok))
+	}
+
+	// 4、删除旧的密钥后签名失效，不能删除当前密钥
+	if err = k.Remove("k2"); err == nil {
+		t.Error("Remove(current) = nil")
+	}
+	if err = k.Remove("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = k.Verify(signed); !errors.Is(err, ErrKey) {
+		t.Errorf("Verify(removed) = %v, want ErrKey", err)
+	}
+	if keys := k.Keys(); len(keys) != 1 || keys[0].ID != "k2" {
+		t.Errorf("Keys() = %v", keys)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	k, err := OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok := New()
+	u := URL(k, "http://host/t?x=1", tok)
+	got, ok := FromPath(strings.Split(strings.TrimPrefix(u, "http://host"), "?")[0])
+	if !ok || !strings.HasPrefix(got, "k1-"+tok+"-") {
+		t.Fatalf("URL() = %s, FromPath() = %s", u, got)
+	}
+	if got, err = k.Verify(got); got != tok || err != nil {
+		t.Errorf("Verify() = %s, %v", got, err)
+	}
+
+	// DNS 标签不超过 63 个字符
+	label, _ := FromSubdomain(Subdomain(k, tok, "canary.test"), "canary.test")
+	if got, err = k.Verify(label); got != tok || err != nil || len(label) > 63 {
+		t.Errorf("Subdomain() = %s, %v", label, err)
+	}
+
+	// 不符合 token 格式、signer 为空时不签名
+	if got = Encode(k, "abc"); got != "abc" {
+		t.Errorf("Encode(abc) = %s", got)
+	}
+	var nilKeyring *Keyring
+	if got = Encode(nilKeyring, tok); got != tok {
+		t.Errorf("Encode(nil) = %s", got)
+	}
+}
@@ -0,0 +1,77 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/url"
+	"strings"
+)
+
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// New 生成随机 token
+// 只包含小写字母和数字，可以直接用作 URL 路径或 DNS 标签
+func New() string {
+	b := make([]byte, 10)
+	_, _ = rand.Read(b)
+	return encoding.EncodeToString(b)
+}
+
+// Subdomain 将 token 编码为追踪域名的子域名，signer 不为 nil 时使用签名后的 token
+// abc, canary.example.com => abc.canary.example.com
+func Subdomain(signer Signer, token, domain string) string {
+	return Encode(signer, token) + "." + strings.Trim(domain, ".")
+}
+
+// FromSubdomain 从域名中解析 token，取追踪域名左侧的第一个标签
+// x.abc.canary.example.com., canary.example.com => abc
+func FromSubdomain(name, domain string) (string, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	domain = strings.ToLower(strings.Trim(domain, "."))
+
+	if !strings.HasSuffix(name, "."+domain) {
+		return "", false
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, "."+domain), ".")
+	token := labels[len(labels)-1]
+	if token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// URL 将 token 添加到追踪地址的路径末尾，保留查询参数，signer 不为 nil 时使用签名后的 token
+// http://host/t, abc => http://host/t/abc
+func URL(signer Signer, base, token string) string {
+	token = Encode(signer, token)
+	u, err := url.Parse(base)
+	if err != nil {
+		return strings.TrimSuffix(base, "/") + "/" + token
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + token
+	u.RawPath = ""
+	return u.String()
+}
+
+// FromPath 从 URL 路径中解析 token，从后向前取第一个符合 token 或签名后的 token 格式的路径元素
+// /t/abc234abc234abcd/ => abc234abc234abcd，/t/abc234abc234abcd/repo.git/info/refs => abc234abc234abcd
+// 签名后的 token 原样返回，需要使用 Keyring.Verify 校验
+func FromPath(p string) (string, bool) {
+	elem := strings.Split(strings.Trim(p, "/"), "/")
+	for i := len(elem) - 1; i >= 0; i-- {
+		if Valid(elem[i]) || Signed(elem[i]) {
+			return strings.ToLower(elem[i]), true
+		}
+	}
+	return "", false
+}
+
+// Valid 判断是否符合 token 格式：16 位小写字母和数字 2-7，不区分大小写
+func Valid(token string) bool {
+	if len(token) != 16 {
+		return false
+	}
+	_, err := encoding.DecodeString(strings.ToLower(token))
+	return err == nil
+}
@@ -0,0 +1,85 @@
+package token
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	re := regexp.MustCompile(`^[a-z2-7]{16}$`)
+	a, b := New(), New()
+	if !re.MatchString(a) {
+		t.Errorf("New() = %s", a)
+	}
+	if a == b {
+		t.Error("New() not random")
+	}
+}
+
+func TestFromSubdomain(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		domain    string
+		wantToken string
+		wantOk    bool
+	}{
+		{"token", "abc.canary.test.", "canary.test", "abc", true},
+		{"prefix", "www.ABC.canary.test", "canary.test.", "abc", true},
+		{"zone", "canary.test.", "canary.test", "", false},
+		{"other", "abc.example.com.", "canary.test", "", false},
+		{"suffix", "abccanary.test.", "canary.test", "", false},
+		{"subdomain", Subdomain(nil, "abc", "canary.test"), "canary.test", "abc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotToken, gotOk := FromSubdomain(tt.query, tt.domain)
+			if gotToken != tt.wantToken || gotOk != tt.wantOk {
+				t.Errorf("FromSubdomain() = %v, %v, want %v, %v", gotToken, gotOk, tt.wantToken, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestURL(t *testing.T) {
+	tok := "abc234abc234abcd"
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"http://localhost:9090/trace", "http://localhost:9090/trace/" + tok},
+		{"http://localhost:9090/", "http://localhost:9090/" + tok},
+		{"http://localhost:9090", "http://localhost:9090/" + tok},
+		{"http://localhost:9090/trace?id=1", "http://localhost:9090/trace/" + tok + "?id=1"},
+		{"file://server/share/", "file://server/share/" + tok},
+	}
+	for _, tt := range tests {
+		if got := URL(nil, tt.base, tok); got != tt.want {
+			t.Errorf("URL(%s) = %s, want %s", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestFromPath(t *testing.T) {
+	tests := []struct {
+		path      string
+		wantToken string
+		wantOk    bool
+	}{
+		{"/trace/abc234abc234abcd", "abc234abc234abcd", true},
+		{"/ABC234ABC234ABCD/", "abc234abc234abcd", true},
+		{"/trace/abc234abc234abcd/infra.git/info/refs", "abc234abc234abcd", true},
+		{"/trace/abc", "", false},
+		{"/trace/abc234abc234abc1", "", false},
+		{"/", "", false},
+	}
+	for _, tt := range tests {
+		gotToken, gotOk := FromPath(tt.path)
+		if gotToken != tt.wantToken || gotOk != tt.wantOk {
+			t.Errorf("FromPath(%s) = %v, %v, want %v, %v", tt.path, gotToken, gotOk, tt.wantToken, tt.wantOk)
+		}
+	}
+	if !Valid(New()) {
+		t.Error("Valid(New()) = false")
+	}
+}
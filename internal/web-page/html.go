@@ -0,0 +1,87 @@
+package web_page
+
+import (
+	"html"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"tracer/internal/token"
+	"tracer/pkg/utils"
+)
+
+// 没有脚本时刷新到追踪地址的延迟，避免页面还未阅读就跳转
+const htmlRefreshDelay = 30
+
+var (
+	htmlHeadTag = regexp.MustCompile(`(?i)<head(\s[^>]*)?>`)
+	htmlBodyTag = regexp.MustCompile(`(?i)<body(\s[^>]*)?>`)
+)
+
+// cssEscape 转义 CSS url() 中的特殊字符
+var cssEscape = strings.NewReplacer(`"`, "%22", `'`, "%27", "(", "%28", ")", "%29", " ", "%20", `\`, "%5C")
+
+// GenTracerHTML 生成可追踪网页，返回生成的 token
+// 添加 link prefetch、隐藏图片、CSS 背景图片，以及没有脚本时的 meta refresh
+// traceUrl: 追踪地址，token 添加到路径末尾
+// signer: 签名 token，为 nil 时不签名
+func GenTracerHTML(srcFile, dstFile, traceUrl string, signer token.Signer) (tok string, err error) {
+	var (
+		data []byte
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、读取网页
+	data, err = os.ReadFile(srcFile)
+	if err != nil {
+		return "", err
+	}
+
+	// 2、添加追踪信息，每个文件使用不同的 token
+	tok = token.New()
+	data = []byte(traceHTML(string(data), token.URL(signer, traceUrl, tok)))
+
+	// 3、生成新的网页
+	err = os.WriteFile(dstFile, data, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// traceHTML 在 head 和 body 中添加追踪信息
+// 不同的追踪方式使用不同的查询参数，避免浏览器缓存导致只请求一次
+func traceHTML(content, traceUrl string) string {
+	head := `<link rel="prefetch" href="` + html.EscapeString(htmlTraceUrl(traceUrl, "prefetch")) + `">` +
+		`<noscript><meta http-equiv="refresh" content="` + strconv.Itoa(htmlRefreshDelay) + `;url=` + html.EscapeString(htmlTraceUrl(traceUrl, "refresh")) + `"></noscript>`
+	body := `<img src="` + html.EscapeString(htmlTraceUrl(traceUrl, "img")) + `" width="1" height="1" alt="" style="position:absolute;left:-9999px;border:0">` +
+		`<div style="position:absolute;left:-9999px;width:1px;height:1px;background-image:url(` + html.EscapeString(cssEscape.Replace(htmlTraceUrl(traceUrl, "css"))) + `)"></div>`
+
+	// 1、添加到 body 开始标签之后，不存在时添加到末尾
+	if loc := htmlBodyTag.FindStringIndex(content); loc != nil {
+		content = content[:loc[1]] + body + content[loc[1]:]
+	} else {
+		content = content + body
+	}
+
+	// 2、添加到 head 开始标签之后，不存在时添加到 body 之前或开头
+	if loc := htmlHeadTag.FindStringIndex(content); loc != nil {
+		content = content[:loc[1]] + head + content[loc[1]:]
+	} else if loc = htmlBodyTag.FindStringIndex(content); loc != nil {
+		content = content[:loc[0]] + "<head>" + head + "</head>" + content[loc[0]:]
+	} else {
+		content = head + content
+	}
+	return content
+}
+
+// htmlTraceUrl 添加查询参数 v，标识追踪方式
+func htmlTraceUrl(traceUrl, v string) string {
+	if strings.Contains(traceUrl, "?") {
+		return traceUrl + "&v=" + v
+	}
+	return traceUrl + "?v=" + v
+}
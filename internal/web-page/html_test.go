@@ -0,0 +1,84 @@
+package web_page
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tracer/internal/token"
+)
+
+func TestTraceHTML(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace/abc"
+	tests := []struct {
+		name    string
+		content string
+		prefix  string
+	}{
+		{"page", `<!DOCTYPE html><html><HEAD lang="en"><title>wiki</title></HEAD><body class="a"><header>x</header></body></html>`, `<!DOCTYPE html><html><HEAD lang="en"><link rel="prefetch"`},
+		{"no head", `<html><body><p>x</p></body></html>`, `<html><head><link rel="prefetch"`},
+		{"fragment", `<p>x</p>`, `<link rel="prefetch"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := traceHTML(tt.content, traceUrl)
+			if !strings.HasPrefix(got, tt.prefix) {
+				t.Errorf("traceHTML() = %s", got)
+			}
+			for _, v := range []string{
+				`href="` + traceUrl + `?v=prefetch"`,
+				`url=` + traceUrl + `?v=refresh"`,
+				`<img src="` + traceUrl + `?v=img"`,
+				`background-image:url(` + traceUrl + `?v=css)`,
+			} {
+				if !strings.Contains(got, v) {
+					t.Errorf("traceHTML() missing %s", v)
+				}
+			}
+			if strings.Index(got, "<img") < strings.Index(got, "<body") && strings.Contains(tt.content, "<body") {
+				t.Error("img must be inside body")
+			}
+		})
+	}
+}
+
+func TestGenTracerHTML(t *testing.T) {
+	dir := t.TempDir()
+	srcFile := filepath.Join(dir, "source.html")
+	if err := os.WriteFile(srcFile, []byte(`<html><body>wiki</body></html>`), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	tok1, err := GenTracerHTML(srcFile, filepath.Join(dir, "a.html"), "http://localhost:9090/t", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 使用签名密钥时路径中为签名后的 token
+	keyring, err := token.OpenKeyring("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok2, err := GenTracerHTML(srcFile, filepath.Join(dir, "b.html"), "http://localhost:9090/t", keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok1 == tok2 {
+		t.Error("token must be different per file")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "a.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "http://localhost:9090/t/"+tok1+"?v=img") {
+		t.Errorf("GenTracerHTML() = %s", data)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "b.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "http://localhost:9090/t/"+keyring.Sign(tok2)+"?v=img") {
+		t.Errorf("GenTracerHTML(signed) = %s", data)
+	}
+}
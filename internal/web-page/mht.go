@@ -0,0 +1,198 @@
+package web_page
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"tracer/internal/token"
+	"tracer/pkg/utils"
+)
+
+var ErrFormat = errors.New("unsupported mhtml format")
+
+// GenTracerMHT 生成可追踪网页存档（mht、mhtml），返回生成的 token
+// 在第一个 text/html 部分中添加追踪信息，其他部分和头部保持不变
+// signer: 签名 token，为 nil 时不签名
+func GenTracerMHT(srcFile, dstFile, traceUrl string, signer token.Signer) (tok string, err error) {
+	var (
+		data []byte
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、读取网页存档
+	data, err = os.ReadFile(srcFile)
+	if err != nil {
+		return "", err
+	}
+
+	// 2、添加追踪信息，每个文件使用不同的 token
+	tok = token.New()
+	data, err = traceMHT(data, token.URL(signer, traceUrl, tok))
+	if err != nil {
+		return "", err
+	}
+
+	// 3、生成新的网页存档
+	err = os.WriteFile(dstFile, data, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+func traceMHT(data []byte, traceUrl string) ([]byte, error) {
+	// 1、原样保留头部，只修改正文
+	rawHeader, body, ok := splitMIME(data)
+	if !ok {
+		return nil, ErrFormat
+	}
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(rawHeader))).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return nil, ErrFormat
+	}
+
+	// 2、单个网页
+	if mediaType == "text/html" {
+		body, err = traceMIMEPart(header, body, traceUrl)
+		if err != nil {
+			return nil, err
+		}
+		return append(append([]byte{}, rawHeader...), body...), nil
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return nil, ErrFormat
+	}
+
+	// 3、多个部分，修改第一个网页部分
+	var b bytes.Buffer
+	writer := multipart.NewWriter(&b)
+	err = writer.SetBoundary(params["boundary"])
+	if err != nil {
+		return nil, err
+	}
+
+	// 保留第一个分隔符之前的内容
+	if i := bytes.Index(body, []byte("--"+params["boundary"])); i > 0 {
+		b.Write(body[:i])
+	}
+
+	traced := false
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if partType == "text/html" && !traced {
+			content, err = traceMIMEPart(part.Header, content, traceUrl)
+			if err != nil {
+				return nil, err
+			}
+			traced = true
+		}
+
+		w, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !traced {
+		return nil, ErrFormat
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, rawHeader...), b.Bytes()...), nil
+}
+
+// traceMIMEPart 解码网页部分，添加追踪信息后按照原来的编码方式编码
+func traceMIMEPart(header textproto.MIMEHeader, content []byte, traceUrl string) ([]byte, error) {
+	encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding")))
+
+	var (
+		decoded []byte
+		err     error
+	)
+	switch encoding {
+	case "quoted-printable":
+		decoded, err = io.ReadAll(quotedprintable.NewReader(bytes.NewReader(content)))
+	case "base64":
+		// 解码时忽略换行
+		decoded, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(content)))
+	default:
+		decoded = content
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	traced := []byte(traceHTML(string(decoded), traceUrl))
+
+	var b bytes.Buffer
+	switch encoding {
+	case "quoted-printable":
+		w := quotedprintable.NewWriter(&b)
+		_, err = w.Write(traced)
+		if err == nil {
+			err = w.Close()
+		}
+		b.WriteString("\r\n")
+	case "base64":
+		encoded := base64.StdEncoding.EncodeToString(traced)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	default:
+		b.Write(traced)
+	}
+	return b.Bytes(), err
+}
+
+// splitMIME 分割头部和正文，头部包含结尾的空行
+func splitMIME(data []byte) (header, body []byte, ok bool) {
+	for i := 0; i < len(data); {
+		j := bytes.IndexByte(data[i:], '\n')
+		if j < 0 {
+			break
+		}
+		if len(bytes.TrimRight(data[i:i+j], "\r")) == 0 {
+			return data[:i+j+1], data[i+j+1:], i > 0
+		}
+		i += j + 1
+	}
+	return nil, nil, false
+}
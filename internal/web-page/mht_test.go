@@ -0,0 +1,108 @@
+package web_page
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+const testMHT = "From: <Saved by Blink>\r\n" +
+	"Subject: wiki\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/related;\r\n" +
+	"\ttype=\"text/html\";\r\n" +
+	"\tboundary=\"----MultipartBoundary--abc\"\r\n" +
+	"\r\n" +
+	"This is a multi-part message in MIME format.\r\n" +
+	"\r\n" +
+	"------MultipartBoundary--abc\r\n" +
+	"Content-Type: text/html\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"Content-Location: http://wiki.local/page\r\n" +
+	"\r\n" +
+	"<html><head><meta charset=3D\"utf-8\"></head><body>=E4=B8=AD</body></html>\r\n" +
+	"------MultipartBoundary--abc\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-Location: http://wiki.local/logo.png\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"------MultipartBoundary--abc--\r\n"
+
+func TestTraceMHT(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace/abc"
+	data, err := traceMHT([]byte(testMHT), traceUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 头部、前言保持不变
+	header := testMHT[:strings.Index(testMHT, "------MultipartBoundary--abc\r\n")]
+	if !strings.HasPrefix(string(data), header) {
+		t.Errorf("header changed: %s", data)
+	}
+
+	msg, err := mail.ReadMessage(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	part, err := reader.NextRawPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(quotedprintable.NewReader(part))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `<img src="`+traceUrl+`?v=img"`) || !strings.Contains(string(content), "中") {
+		t.Errorf("html part = %s", content)
+	}
+
+	part, err = reader.NextRawPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = io.ReadAll(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.Header.Get("Content-Location") != "http://wiki.local/logo.png" || strings.TrimSpace(string(content)) != "iVBORw0KGgo=" {
+		t.Errorf("image part changed: %v %s", part.Header, content)
+	}
+}
+
+func TestTraceMHTSingle(t *testing.T) {
+	traceUrl := "http://localhost:9090/trace/abc"
+	src := "Content-Type: text/html\nContent-Transfer-Encoding: base64\n\n" +
+		base64.StdEncoding.EncodeToString([]byte("<html><body>x</body></html>")) + "\n"
+	data, err := traceMHT([]byte(src), traceUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := string(data[strings.Index(string(data), "\n\n")+2:])
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(decoded), traceUrl) {
+		t.Errorf("traceMHT() = %s", decoded)
+	}
+
+	if _, err = traceMHT([]byte("Content-Type: text/plain\n\nx"), traceUrl); err != ErrFormat {
+		t.Errorf("traceMHT(text/plain) = %v, want %v", err, ErrFormat)
+	}
+}
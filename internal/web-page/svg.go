@@ -0,0 +1,175 @@
+package web_page
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+
+	"tracer/internal/token"
+	"tracer/pkg/utils"
+
+	"github.com/beevik/etree"
+)
+
+// 追踪节点 Id，用于判断是否已存在追踪信息
+const svgTraceId = "g9999"
+const svgTraceFont = "f9999"
+
+var ErrImage = errors.New("unsupported image format")
+
+// svgUrl 匹配 CSS 中的 url()、@import
+var svgUrl = regexp.MustCompile(`(?:url\(\s*["']?|@import\s+["'])([a-zA-Z][a-zA-Z0-9+.-]*://[^"')\s]+)`)
+
+// GenTracerSVG 生成可追踪图片，返回生成的 token
+// 添加透明的外部图片、CSS @import 和外部字体，图片显示效果不变
+// srcFile 为 png、jpeg、gif 时转换为内嵌该图片的 svg
+// signer: 签名 token，为 nil 时不签名
+func GenTracerSVG(srcFile, dstFile, traceUrl string, signer token.Signer) (tok string, err error) {
+	var (
+		data     []byte
+		document *etree.Document
+	)
+
+	// UNC 路径转换为 file 地址
+	traceUrl = utils.UNCToUrl(traceUrl)
+
+	// 1、读取图片，非 svg 图片转换为 svg
+	data, err = os.ReadFile(srcFile)
+	if err != nil {
+		return "", err
+	}
+
+	document = etree.NewDocument()
+	if err = document.ReadFromBytes(data); err != nil || document.Root() == nil || document.Root().Tag != "svg" {
+		document, err = wrapSVG(data)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// 2、添加追踪信息，每个文件使用不同的 token
+	tok = token.New()
+	traceSVG(document, token.URL(signer, traceUrl, tok))
+
+	// 3、生成新的图片
+	err = utils.WriteXml(document, dstFile)
+	if err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// VerifyTracerSVG 获取图片中的外部资源地址
+func VerifyTracerSVG(filename string) (urls []string, err error) {
+	document, err := utils.ReadXml(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// href 和 xlink:href 相同时只记录一次
+	seen := make(map[string]bool)
+	add := func(u string) {
+		if !seen[u] {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+
+	for _, element := range document.FindElements("//*") {
+		for _, attr := range element.Attr {
+			if attr.Key == "href" && svgUrl.MatchString("url("+attr.Value+")") {
+				add(attr.Value)
+			}
+		}
+		if element.Tag == "style" {
+			for _, match := range svgUrl.FindAllStringSubmatch(element.Text(), -1) {
+				add(match[1])
+			}
+		}
+	}
+	return urls, nil
+}
+
+// RemoveTracerSVG 删除图片中的追踪信息
+func RemoveTracerSVG(srcFile, dstFile string) (err error) {
+	document, err := utils.ReadXml(srcFile)
+	if err != nil {
+		return err
+	}
+
+	removeSVGTracer(document)
+	return utils.WriteXml(document, dstFile)
+}
+
+// traceSVG 在根节点末尾添加透明的追踪节点组，已存在时替换
+func traceSVG(document *etree.Document, traceUrl string) {
+	root := document.Root()
+	removeSVGTracer(document)
+
+	group := root.CreateElement("g")
+	group.CreateAttr("id", svgTraceId)
+	group.CreateAttr("opacity", "0")
+	group.CreateAttr("pointer-events", "none")
+
+	// CSS @import 必须位于样式表的开头
+	style := group.CreateElement("style")
+	style.SetText(`@import url("` + cssEscape.Replace(htmlTraceUrl(traceUrl, "css")) + `");` +
+		`@font-face{font-family:"` + svgTraceFont + `";src:url("` + cssEscape.Replace(htmlTraceUrl(traceUrl, "font")) + `")}`)
+
+	// 同时设置 href 和 xlink:href，兼容 SVG 1.1 的客户端
+	img := group.CreateElement("image")
+	img.CreateAttr("width", "1")
+	img.CreateAttr("height", "1")
+	img.CreateAttr("href", htmlTraceUrl(traceUrl, "image"))
+	img.CreateAttr("xlink:href", htmlTraceUrl(traceUrl, "image"))
+
+	// 字体只有被使用时才会请求
+	text := group.CreateElement("text")
+	text.CreateAttr("x", "0")
+	text.CreateAttr("y", "1")
+	text.CreateAttr("font-family", svgTraceFont)
+	text.CreateAttr("font-size", "1")
+	text.SetText(".")
+
+	if root.SelectAttr("xmlns:xlink") == nil {
+		root.CreateAttr("xmlns:xlink", "http://www.w3.org/1999/xlink")
+	}
+}
+
+// removeSVGTracer 删除追踪节点组
+func removeSVGTracer(document *etree.Document) {
+	for _, group := range document.FindElements("//g[@id='" + svgTraceId + "']") {
+		group.Parent().RemoveChild(group)
+	}
+}
+
+// wrapSVG 将 png、jpeg、gif 图片内嵌到 svg 中，尺寸与原图一致
+func wrapSVG(data []byte) (*etree.Document, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrImage
+	}
+	width, height := strconv.Itoa(config.Width), strconv.Itoa(config.Height)
+
+	document := etree.NewDocument()
+	document.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	root := document.CreateElement("svg")
+	root.CreateAttr("xmlns", "http://www.w3.org/2000/svg")
+	root.CreateAttr("width", width)
+	root.CreateAttr("height", height)
+	root.CreateAttr("viewBox", "0 0 "+width+" "+height)
+
+	img := root.CreateElement("image")
+	img.CreateAttr("width", width)
+	img.CreateAttr("height", height)
+	img.CreateAttr("href", "data:"+http.DetectContentType(data)+";base64,"+base64.StdEncoding.EncodeToString(data))
+	return document, nil
+}
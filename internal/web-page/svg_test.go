@@ -0,0 +1,90 @@
+package web_page
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10">
+    <rect width="10" height="10" fill="red"/>
+    <image href="http://cdn.local/logo.png" width="5" height="5"/>
+</svg>`
+
+func TestGenTracerSVG(t *testing.T) {
+	dir := t.TempDir()
+	srcFile := filepath.Join(dir, "source.svg")
+	dstFile := filepath.Join(dir, "tracer.svg")
+	if err := os.WriteFile(srcFile, []byte(testSVG), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GenTracerSVG(srcFile, dstFile, "http://localhost:9090/t", nil); err != nil {
+		t.Fatal(err)
+	}
+	// 重复生成，替换已存在的追踪信息
+	tok, err := GenTracerSVG(dstFile, dstFile, "http://localhost:9090/t", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traceUrl := "http://localhost:9090/t/" + tok
+	urls, err := VerifyTracerSVG(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"http://cdn.local/logo.png", traceUrl + "?v=css", traceUrl + "?v=font", traceUrl + "?v=image"}
+	if strings.Join(urls, " ") != strings.Join(want, " ") {
+		t.Errorf("VerifyTracerSVG() = %v, want %v", urls, want)
+	}
+
+	if err = RemoveTracerSVG(dstFile, dstFile); err != nil {
+		t.Fatal(err)
+	}
+	urls, err = VerifyTracerSVG(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || urls[0] != "http://cdn.local/logo.png" {
+		t.Errorf("VerifyTracerSVG() after remove = %v", urls)
+	}
+}
+
+func TestGenTracerSVGWrap(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	srcFile := filepath.Join(dir, "source.png")
+	dstFile := filepath.Join(dir, "tracer.svg")
+	if err := os.WriteFile(srcFile, b.Bytes(), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GenTracerSVG(srcFile, dstFile, "http://localhost:9090/t", nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{`width="3" height="2" viewBox="0 0 3 2"`, `href="data:image/png;base64,`, `id="g9999"`} {
+		if !strings.Contains(string(data), v) {
+			t.Errorf("GenTracerSVG() missing %s: %s", v, data)
+		}
+	}
+
+	if err = os.WriteFile(srcFile, []byte("plain text"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = GenTracerSVG(srcFile, dstFile, "http://localhost:9090/t", nil); err != ErrImage {
+		t.Errorf("GenTracerSVG(text) = %v, want %v", err, ErrImage)
+	}
+}
@@ -0,0 +1,152 @@
+package windows_shell
+
+import (
+	"encoding/binary"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// ShellLinkHeader 中的标志
+const (
+	lnkHasLinkInfo     = 0x00000002
+	lnkHasName         = 0x00000004
+	lnkHasWorkingDir   = 0x00000010
+	lnkHasArguments    = 0x00000020
+	lnkHasIconLocation = 0x00000040
+	lnkIsUnicode       = 0x00000080
+	lnkHasExpIcon      = 0x00004000
+
+	lnkHeaderSize        = 0x4c
+	lnkFileAttrArchive   = 0x20
+	lnkShowNormal        = 1
+	lnkDriveFixed        = 3
+	lnkVolumeIDAndPath   = 1
+	lnkIconEnvSignature  = 0xa0000007
+	lnkIconEnvBlockSize  = 0x314
+	lnkMaxPath           = 260
+	lnkLinkInfoHeaderLen = 0x1c
+)
+
+// LinkCLSID 00021401-0000-0000-C000-000000000046
+var lnkCLSID = []byte{0x01, 0x14, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+
+// Shortcut 快捷方式
+type Shortcut struct {
+	Target       string // 目标路径，例如 C:\Windows\explorer.exe，只支持 ASCII
+	Arguments    string // 命令行参数
+	WorkingDir   string // 工作目录，为空时使用目标所在目录
+	Description  string // 说明
+	IconLocation string // 图标路径，UNC 路径或 WebDAV 路径
+	IconIndex    int32  // 图标索引
+}
+
+// GenTracerLNK 生成可追踪的快捷方式（.lnk）
+// 资源管理器显示图标时访问 IconLocation，不需要打开快捷方式
+// target: 快捷方式的目标路径
+// traceUrl: 追踪地址，UNC 路径或 http 地址
+func GenTracerLNK(dstFile, target, traceUrl string) (err error) {
+	shortcut := &Shortcut{
+		Target:       target,
+		IconLocation: iconPath(traceUrl),
+	}
+	return os.WriteFile(dstFile, shortcut.Bytes(), os.ModePerm)
+}
+
+// Bytes 生成 Shell Link 二进制格式（MS-SHLLINK）
+// 使用 LinkInfo 中的 LocalBasePath 指定目标，不生成 LinkTargetIDList
+func (s *Shortcut) Bytes() []byte {
+	le := binary.LittleEndian
+
+	workingDir := s.WorkingDir
+	if workingDir == "" {
+		if i := strings.LastIndex(s.Target, `\`); i > 0 {
+			workingDir = s.Target[:i]
+		}
+	}
+
+	// 1、ShellLinkHeader
+	flags := uint32(lnkHasLinkInfo | lnkIsUnicode)
+	// 快捷方式不在目标所在目录中，不使用 RELATIVE_PATH
+	strs := []string{s.Description, workingDir, s.Arguments, s.IconLocation}
+	for i, flag := range []uint32{lnkHasName, lnkHasWorkingDir, lnkHasArguments, lnkHasIconLocation} {
+		if strs[i] != "" {
+			flags |= flag
+		}
+	}
+	if s.IconLocation != "" {
+		flags |= lnkHasExpIcon
+	}
+
+	b := le.AppendUint32(nil, lnkHeaderSize)
+	b = append(b, lnkCLSID...)
+	b = le.AppendUint32(b, flags)
+	b = le.AppendUint32(b, lnkFileAttrArchive)
+	b = append(b, make([]byte, 8*3)...) // CreationTime、AccessTime、WriteTime
+	b = le.AppendUint32(b, 0)           // FileSize
+	b = le.AppendUint32(b, uint32(s.IconIndex))
+	b = le.AppendUint32(b, lnkShowNormal)
+	b = le.AppendUint16(b, 0)             // HotKey
+	b = append(b, make([]byte, 2+4+4)...) // Reserved
+
+	// 2、LinkInfo
+	b = append(b, s.linkInfo()...)
+
+	// 3、StringData：字符数 + UTF-16，不以 0 结尾
+	for _, str := range strs {
+		if str == "" {
+			continue
+		}
+		u := utf16.Encode([]rune(str))
+		b = le.AppendUint16(b, uint16(len(u)))
+		for _, v := range u {
+			b = le.AppendUint16(b, v)
+		}
+	}
+
+	// 4、ExtraData：IconEnvironmentDataBlock、TerminalBlock
+	if s.IconLocation != "" {
+		b = le.AppendUint32(b, lnkIconEnvBlockSize)
+		b = le.AppendUint32(b, lnkIconEnvSignature)
+		ansi := make([]byte, lnkMaxPath)
+		copy(ansi[:lnkMaxPath-1], s.IconLocation)
+		b = append(b, ansi...)
+		unicode := make([]uint16, lnkMaxPath)
+		copy(unicode[:lnkMaxPath-1], utf16.Encode([]rune(s.IconLocation)))
+		for _, v := range unicode {
+			b = le.AppendUint16(b, v)
+		}
+	}
+	return le.AppendUint32(b, 0)
+}
+
+// linkInfo 生成 LinkInfo，VolumeID 为本地固定磁盘
+func (s *Shortcut) linkInfo() []byte {
+	le := binary.LittleEndian
+
+	// VolumeID：VolumeIDSize DriveType DriveSerialNumber VolumeLabelOffset VolumeLabel
+	volume := le.AppendUint32(nil, 0x11)
+	volume = le.AppendUint32(volume, lnkDriveFixed)
+	volume = le.AppendUint32(volume, 0)
+	volume = le.AppendUint32(volume, 0x10)
+	volume = append(volume, 0)
+
+	localBasePath := append([]byte(s.Target), 0)
+	commonPathSuffix := []byte{0}
+
+	volumeOffset := lnkLinkInfoHeaderLen
+	pathOffset := volumeOffset + len(volume)
+	suffixOffset := pathOffset + len(localBasePath)
+	size := suffixOffset + len(commonPathSuffix)
+
+	b := le.AppendUint32(nil, uint32(size))
+	b = le.AppendUint32(b, lnkLinkInfoHeaderLen)
+	b = le.AppendUint32(b, lnkVolumeIDAndPath)
+	b = le.AppendUint32(b, uint32(volumeOffset))
+	b = le.AppendUint32(b, uint32(pathOffset))
+	b = le.AppendUint32(b, 0) // CommonNetworkRelativeLinkOffset
+	b = le.AppendUint32(b, uint32(suffixOffset))
+	b = append(b, volume...)
+	b = append(b, localBasePath...)
+	return append(b, commonPathSuffix...)
+}
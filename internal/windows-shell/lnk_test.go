@@ -0,0 +1,136 @@
+package windows_shell
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+var le = binary.LittleEndian
+
+// parseTestLNK 按照 MS-SHLLINK 解析快捷方式，返回标志、LocalBasePath、StringData、图标路径
+func parseTestLNK(t *testing.T, b []byte) (flags uint32, target string, strs []string, icon string) {
+	t.Helper()
+
+	if len(b) < lnkHeaderSize || le.Uint32(b) != lnkHeaderSize || !bytes.Equal(b[4:20], lnkCLSID) {
+		t.Fatal("invalid ShellLinkHeader")
+	}
+	flags = le.Uint32(b[20:])
+	offset := lnkHeaderSize
+
+	if flags&lnkHasLinkInfo != 0 {
+		info := b[offset:]
+		size := int(le.Uint32(info))
+		if le.Uint32(info[4:]) != lnkLinkInfoHeaderLen || le.Uint32(info[8:])&lnkVolumeIDAndPath == 0 {
+			t.Fatal("invalid LinkInfo")
+		}
+		volume := info[le.Uint32(info[12:]):]
+		if le.Uint32(volume[4:]) != lnkDriveFixed {
+			t.Error("invalid VolumeID")
+		}
+		path := info[le.Uint32(info[16:]):]
+		target = string(path[:bytes.IndexByte(path, 0)])
+		offset += size
+	}
+
+	for _, flag := range []uint32{lnkHasName, lnkHasWorkingDir, lnkHasArguments, lnkHasIconLocation} {
+		if flags&flag == 0 {
+			strs = append(strs, "")
+			continue
+		}
+		n := int(le.Uint16(b[offset:]))
+		u := make([]uint16, n)
+		for i := range u {
+			u[i] = le.Uint16(b[offset+2+i*2:])
+		}
+		strs = append(strs, string(utf16.Decode(u)))
+		offset += 2 + n*2
+	}
+
+	for {
+		size := int(le.Uint32(b[offset:]))
+		if size < 4 {
+			break
+		}
+		if le.Uint32(b[offset+4:]) == lnkIconEnvSignature {
+			if size != lnkIconEnvBlockSize {
+				t.Errorf("IconEnvironmentDataBlock size = %#x", size)
+			}
+			u := make([]uint16, lnkMaxPath)
+			for i := range u {
+				u[i] = le.Uint16(b[offset+8+lnkMaxPath+i*2:])
+			}
+			for i, v := range u {
+				if v == 0 {
+					u = u[:i]
+					break
+				}
+			}
+			icon = string(utf16.Decode(u))
+		}
+		offset += size
+	}
+	if offset+4 != len(b) {
+		t.Errorf("TerminalBlock at %d, file size %d", offset, len(b))
+	}
+	return flags, target, strs, icon
+}
+
+func TestGenTracerLNK(t *testing.T) {
+	tests := []struct {
+		name     string
+		traceUrl string
+		wantIcon string
+	}{
+		{"unc", `\\10.0.0.1\share\icon.ico`, `\\10.0.0.1\share\icon.ico`},
+		{"webdav", "http://10.0.0.1:8080/dav/icon.ico", `\\10.0.0.1@8080\dav\icon.ico`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dstFile := filepath.Join(t.TempDir(), "report.lnk")
+			if err := GenTracerLNK(dstFile, `C:\Windows\explorer.exe`, tt.traceUrl); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := os.ReadFile(dstFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			flags, target, strs, icon := parseTestLNK(t, b)
+			if flags&(lnkIsUnicode|lnkHasExpIcon|lnkHasIconLocation) != lnkIsUnicode|lnkHasExpIcon|lnkHasIconLocation {
+				t.Errorf("flags = %#x", flags)
+			}
+			if target != `C:\Windows\explorer.exe` {
+				t.Errorf("LocalBasePath = %s", target)
+			}
+			if strs[1] != `C:\Windows` || strs[3] != tt.wantIcon {
+				t.Errorf("StringData = %q", strs)
+			}
+			if icon != tt.wantIcon {
+				t.Errorf("IconEnvironmentDataBlock = %s, want %s", icon, tt.wantIcon)
+			}
+		})
+	}
+}
+
+func TestShortcutBytes(t *testing.T) {
+	shortcut := &Shortcut{
+		Target:      `C:\Windows\System32\cmd.exe`,
+		Arguments:   "/c echo 中文",
+		Description: "说明",
+	}
+	flags, _, strs, icon := parseTestLNK(t, shortcut.Bytes())
+	if flags&(lnkHasIconLocation|lnkHasExpIcon) != 0 || icon != "" {
+		t.Errorf("flags = %#x, icon = %s", flags, icon)
+	}
+	want := []string{"说明", `C:\Windows\System32`, "/c echo 中文", ""}
+	for i := range want {
+		if strs[i] != want[i] {
+			t.Errorf("StringData = %q, want %q", strs, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package windows_shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tracer/pkg/utils"
+)
+
+const urlTemp = "[InternetShortcut]\r\n" +
+	"URL=${target}\r\n" +
+	"IconIndex=0\r\n" +
+	"IconFile=${icon}\r\n"
+
+const desktopIniTemp = "[.ShellClassInfo]\r\n" +
+	"IconResource=${icon},0\r\n" +
+	"IconFile=${icon}\r\n" +
+	"IconIndex=0\r\n"
+
+// iconPath 将追踪地址转换为图标路径，http 地址转换为 WebDAV 路径
+func iconPath(traceUrl string) string {
+	return utils.UrlToUNC(traceUrl)
+}
+
+// GenTracerURL 生成可追踪的 Internet 快捷方式（.url）
+// 资源管理器显示图标时访问 IconFile，不需要打开快捷方式
+// target: 快捷方式打开的地址
+// traceUrl: 追踪地址，UNC 路径或 http 地址
+func GenTracerURL(dstFile, target, traceUrl string) (err error) {
+	content := strings.Replace(urlTemp, "${target}", target, -1)
+	content = strings.Replace(content, "${icon}", iconPath(traceUrl), -1)
+	return os.WriteFile(dstFile, []byte(content), os.ModePerm)
+}
+
+// GenTracerDesktopINI 在目录中生成可追踪的 desktop.ini
+// 资源管理器浏览上级目录时访问文件夹图标
+// 目录需要设置只读或系统属性（attrib +s dir），desktop.ini 建议设置隐藏、系统属性
+func GenTracerDesktopINI(dstDir, traceUrl string) (err error) {
+	content := strings.Replace(desktopIniTemp, "${icon}", iconPath(traceUrl), -1)
+	return os.WriteFile(filepath.Join(dstDir, "desktop.ini"), []byte(content), os.ModePerm)
+}
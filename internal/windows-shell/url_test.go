@@ -0,0 +1,41 @@
+package windows_shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenTracerURL(t *testing.T) {
+	dstFile := filepath.Join(t.TempDir(), "wiki.url")
+	if err := GenTracerURL(dstFile, "http://wiki.local/", "https://10.0.0.1/dav/icon.ico"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(dstFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[InternetShortcut]\r\nURL=http://wiki.local/\r\nIconIndex=0\r\nIconFile=\\\\10.0.0.1@SSL\\dav\\icon.ico\r\n"
+	if string(b) != want {
+		t.Errorf("GenTracerURL() = %q, want %q", b, want)
+	}
+}
+
+func TestGenTracerDesktopINI(t *testing.T) {
+	dir := t.TempDir()
+	if err := GenTracerDesktopINI(dir, `\\10.0.0.1\share\folder.ico`); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "desktop.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"[.ShellClassInfo]\r\n", "IconResource=\\\\10.0.0.1\\share\\folder.ico,0\r\n", "IconFile=\\\\10.0.0.1\\share\\folder.ico\r\n"} {
+		if !strings.Contains(string(b), v) {
+			t.Errorf("desktop.ini missing %q", v)
+		}
+	}
+}
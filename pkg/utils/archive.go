@@ -2,9 +2,11 @@ package utils
 
 import (
 	"archive/zip"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // ExtractZip 解压 zip 文件
@@ -78,15 +80,70 @@ func ExtractZip(filename string, patten string) (tempDir string, err error) {
 	return tempDir, nil
 }
 
+// OfficeZipTime Office 保存文件时 zip 成员的修改时间（DOS 时间的最小值）
+var OfficeZipTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // CompressZip 压缩文件夹
 // dir: 目录名
 // filename: 压缩文件名
 func CompressZip(dir string, filename string) (err error) {
+	return CompressZipTime(dir, filename, time.Time{})
+}
+
+// CompressZipTime 压缩文件夹，设置所有成员的修改时间
+// 只写入 DOS 时间，不添加扩展时间戳，与 Office 生成的文件一致
+// modified: 修改时间，为零值时与 CompressZip 相同
+func CompressZipTime(dir string, filename string, modified time.Time) (err error) {
+	var (
+		dstFile   *os.File
+		dstWriter *zip.Writer
+	)
+
+	// 创建压缩文件
+	dstFile, err = os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dstFile.Close()
+	}()
+
+	// 创建压缩器
+	dstWriter = zip.NewWriter(dstFile)
+
+	// 遍历目录
+	err = addZipDir(dstWriter, dir, modified, nil)
+	if err != nil {
+		return err
+	}
+
+	// 关闭压缩器
+	// 必须最后关闭
+	err = dstWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CompressODF 压缩 OpenDocument 文件夹
+// ODF 规范要求 mimetype 是第一个文件，且不压缩、没有扩展字段，以便通过文件头识别类型
+// dir: 目录名
+// filename: 压缩文件名
+func CompressODF(dir string, filename string) (err error) {
 	var (
 		dstFile   *os.File
 		dstWriter *zip.Writer
+		mimetype  []byte
+		dst       io.Writer
 	)
 
+	mimetype, err = os.ReadFile(filepath.Join(dir, "mimetype"))
+	if err != nil {
+		return err
+	}
+
 	// 创建压缩文件
 	dstFile, err = os.Create(filename)
 	if err != nil {
@@ -99,8 +156,40 @@ func CompressZip(dir string, filename string) (err error) {
 	// 创建压缩器
 	dstWriter = zip.NewWriter(dstFile)
 
+	// 写入 mimetype，使用 CreateRaw 避免数据描述符和时间扩展字段
+	dst, err = dstWriter.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(mimetype)
+	if err != nil {
+		return err
+	}
+
 	// 遍历目录
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err = addZipDir(dstWriter, dir, time.Time{}, func(name string) bool {
+		return name == "mimetype"
+	})
+	if err != nil {
+		return err
+	}
+
+	// 关闭压缩器
+	// 必须最后关闭
+	return dstWriter.Close()
+}
+
+// addZipDir 添加目录中的文件到压缩器
+// modified: 成员的修改时间，为零值时不设置
+// skip: 需要跳过的文件，参数为 / 分隔的相对路径
+func addZipDir(dstWriter *zip.Writer, dir string, modified time.Time, skip func(name string) bool) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -118,13 +207,13 @@ func CompressZip(dir string, filename string) (err error) {
 			return err
 		}
 
-		if relPath == "." {
+		if relPath == "." || (skip != nil && skip(filepath.ToSlash(relPath))) {
 			return nil
 		}
 
 		if info.IsDir() {
 			// 创建目录
-			_, err = dstWriter.Create(relPath + "/")
+			_, err = dstWriter.CreateHeader(zipHeader(relPath+"/", modified))
 			if err != nil {
 				return err
 			}
@@ -135,7 +224,7 @@ func CompressZip(dir string, filename string) (err error) {
 				return err
 			}
 
-			dst, err = dstWriter.Create(relPath)
+			dst, err = dstWriter.CreateHeader(zipHeader(relPath, modified))
 			if err != nil {
 				return err
 			}
@@ -151,16 +240,18 @@ func CompressZip(dir string, filename string) (err error) {
 
 		return nil
 	})
-	if err != nil {
-		return err
-	}
+}
 
-	// 关闭压缩器
-	// 必须最后关闭
-	err = dstWriter.Close()
-	if err != nil {
-		return err
+// zipHeader 生成成员文件头，与 zip.Writer.Create 相同，设置 DOS 时间
+func zipHeader(name string, modified time.Time) *zip.FileHeader {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	if !modified.IsZero() {
+		// DOS 时间不能早于 1980 年
+		if modified.Before(OfficeZipTime) {
+			modified = OfficeZipTime
+		}
+		header.ModifiedDate = uint16((modified.Year()-1980)<<9 | int(modified.Month())<<5 | modified.Day())
+		header.ModifiedTime = uint16(modified.Hour()<<11 | modified.Minute()<<5 | modified.Second()>>1)
 	}
-
-	return nil
+	return header
 }
@@ -0,0 +1,593 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// CFB（Compound File Binary）读写
+// Word 97-2003、Excel 97-2003、PowerPoint 97-2003 文档、Outlook msg 均使用该格式
+
+const (
+	cfbFreeSect   = 0xffffffff
+	cfbEndOfChain = 0xfffffffe
+	cfbFATSect    = 0xfffffffd
+	cfbDIFATSect  = 0xfffffffc
+	cfbNoStream   = 0xffffffff
+
+	cfbSectorSize     = 512
+	cfbMiniSectorSize = 64
+	cfbMiniCutoff     = 4096
+	cfbDirEntrySize   = 128
+)
+
+var cfbSignature = []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}
+
+var ErrCFB = errors.New("invalid compound file")
+
+// CFBType 目录项类型
+type CFBType byte
+
+const (
+	CFBStorage CFBType = 1
+	CFBStream  CFBType = 2
+	CFBRoot    CFBType = 5
+)
+
+// CFBEntry 目录项（存储或流）
+type CFBEntry struct {
+	Name      string
+	Type      CFBType
+	CLSID     [16]byte
+	StateBits uint32
+	Created   uint64
+	Modified  uint64
+	Data      []byte      // 流数据
+	Children  []*CFBEntry // 子存储、子流
+}
+
+// CompoundFile 复合文档
+type CompoundFile struct {
+	Root *CFBEntry
+}
+
+// IsCFB 判断文件内容是否为复合文档
+func IsCFB(b []byte) bool {
+	return bytes.HasPrefix(b, cfbSignature)
+}
+
+// ReadCFB 读取复合文档
+func ReadCFB(filename string) (*CompoundFile, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCFB(b)
+}
+
+// WriteCFB 输出复合文档
+func WriteCFB(cf *CompoundFile, filename string) error {
+	b, err := cf.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, os.ModePerm)
+}
+
+// Entry 根据路径查找目录项，路径使用 / 分隔，名称不区分大小写
+// 例如 WordDocument、__substg1.0_0037001F
+func (cf *CompoundFile) Entry(path string) *CFBEntry {
+	entry := cf.Root
+	for _, name := range strings.Split(path, "/") {
+		var next *CFBEntry
+		for _, child := range entry.Children {
+			if strings.EqualFold(child.Name, name) {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		entry = next
+	}
+	return entry
+}
+
+// Stream 获取流数据，不存在时返回 nil
+func (cf *CompoundFile) Stream(path string) []byte {
+	entry := cf.Entry(path)
+	if entry == nil || entry.Type != CFBStream {
+		return nil
+	}
+	return entry.Data
+}
+
+// SetStream 设置流数据，不存在时在所属存储中创建
+func (cf *CompoundFile) SetStream(path string, data []byte) {
+	parent := cf.Root
+	names := strings.Split(path, "/")
+	for i, name := range names {
+		var next *CFBEntry
+		for _, child := range parent.Children {
+			if strings.EqualFold(child.Name, name) {
+				next = child
+				break
+			}
+		}
+
+		if next == nil {
+			next = &CFBEntry{Name: name, Type: CFBStorage}
+			if i == len(names)-1 {
+				next.Type = CFBStream
+			}
+			parent.Children = append(parent.Children, next)
+		}
+		parent = next
+	}
+	parent.Data = data
+}
+
+// ParseCFB 解析复合文档
+func ParseCFB(b []byte) (*CompoundFile, error) {
+	if len(b) < 512 || !IsCFB(b) {
+		return nil, ErrCFB
+	}
+
+	le := binary.LittleEndian
+	sectorShift := le.Uint16(b[30:])
+	if sectorShift != 9 && sectorShift != 12 {
+		return nil, ErrCFB
+	}
+	sectorSize := 1 << sectorShift
+	miniCutoff := le.Uint32(b[56:])
+
+	sector := func(n uint32) []byte {
+		start := (int(n) + 1) * sectorSize
+		if n >= cfbDIFATSect || start+sectorSize > len(b) {
+			return nil
+		}
+		return b[start : start+sectorSize]
+	}
+
+	// 1、读取 DIFAT，获取 FAT 扇区
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		if n := le.Uint32(b[76+i*4:]); n != cfbFreeSect {
+			fatSectors = append(fatSectors, n)
+		}
+	}
+	next := le.Uint32(b[68:])
+	for i := 0; next != cfbEndOfChain && next != cfbFreeSect; i++ {
+		s := sector(next)
+		if s == nil || i > len(b)/sectorSize {
+			return nil, ErrCFB
+		}
+		for j := 0; j < sectorSize/4-1; j++ {
+			if n := le.Uint32(s[j*4:]); n != cfbFreeSect {
+				fatSectors = append(fatSectors, n)
+			}
+		}
+		next = le.Uint32(s[sectorSize-4:])
+	}
+
+	// 2、读取 FAT
+	var fat []uint32
+	for _, n := range fatSectors {
+		s := sector(n)
+		if s == nil {
+			return nil, ErrCFB
+		}
+		for j := 0; j < sectorSize/4; j++ {
+			fat = append(fat, le.Uint32(s[j*4:]))
+		}
+	}
+
+	chain := func(start uint32) ([]byte, error) {
+		var data []byte
+		for n, i := start, 0; n != cfbEndOfChain; i++ {
+			s := sector(n)
+			if s == nil || int(n) >= len(fat) || i > len(fat) {
+				return nil, ErrCFB
+			}
+			data = append(data, s...)
+			n = fat[n]
+		}
+		return data, nil
+	}
+
+	// 3、读取目录
+	dir, err := chain(le.Uint32(b[48:]))
+	if err != nil {
+		return nil, err
+	}
+	count := len(dir) / cfbDirEntrySize
+	if count == 0 {
+		return nil, ErrCFB
+	}
+
+	// 4、读取 MiniFAT、Mini Stream
+	var miniFat []uint32
+	if n := le.Uint32(b[60:]); n != cfbEndOfChain && n != cfbFreeSect {
+		data, err := chain(n)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j+4 <= len(data); j += 4 {
+			miniFat = append(miniFat, le.Uint32(data[j:]))
+		}
+	}
+
+	var miniStream []byte
+	if n := le.Uint32(dir[116:]); n != cfbEndOfChain && n != cfbFreeSect {
+		miniStream, err = chain(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	miniChain := func(start uint32, size int) ([]byte, error) {
+		var data []byte
+		for n, i := start, 0; n != cfbEndOfChain && len(data) < size; i++ {
+			begin := int(n) * cfbMiniSectorSize
+			if int(n) >= len(miniFat) || begin+cfbMiniSectorSize > len(miniStream) || i > len(miniFat) {
+				return nil, ErrCFB
+			}
+			data = append(data, miniStream[begin:begin+cfbMiniSectorSize]...)
+			n = miniFat[n]
+		}
+		if len(data) < size {
+			return nil, ErrCFB
+		}
+		return data[:size], nil
+	}
+
+	// 5、解析目录项
+	type rawEntry struct {
+		entry              *CFBEntry
+		left, right, child uint32
+	}
+	raws := make([]rawEntry, count)
+	for i := 0; i < count; i++ {
+		d := dir[i*cfbDirEntrySize : (i+1)*cfbDirEntrySize]
+		nameLen := int(le.Uint16(d[64:]))
+		if nameLen > 64 {
+			return nil, ErrCFB
+		}
+		u := make([]uint16, 0, 32)
+		for j := 0; j+2 <= nameLen-2; j += 2 {
+			u = append(u, le.Uint16(d[j:]))
+		}
+
+		entry := &CFBEntry{
+			Name:      string(utf16.Decode(u)),
+			Type:      CFBType(d[66]),
+			StateBits: le.Uint32(d[96:]),
+			Created:   le.Uint64(d[100:]),
+			Modified:  le.Uint64(d[108:]),
+		}
+		copy(entry.CLSID[:], d[80:96])
+
+		if entry.Type == CFBStream {
+			size := int(le.Uint32(d[120:]))
+			start := le.Uint32(d[116:])
+			if size > 0 {
+				if uint32(size) < miniCutoff {
+					entry.Data, err = miniChain(start, size)
+				} else {
+					entry.Data, err = chain(start)
+					if err == nil && len(entry.Data) < size {
+						err = ErrCFB
+					}
+					if err == nil {
+						entry.Data = entry.Data[:size]
+					}
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		raws[i] = rawEntry{entry: entry, left: le.Uint32(d[68:]), right: le.Uint32(d[72:]), child: le.Uint32(d[76:])}
+	}
+
+	// 6、遍历红黑树，构建目录结构
+	visited := make([]bool, count)
+	var walk func(n uint32, parent *CFBEntry) error
+	walk = func(n uint32, parent *CFBEntry) error {
+		if n == cfbNoStream {
+			return nil
+		}
+		if int(n) >= count || visited[n] {
+			return ErrCFB
+		}
+		visited[n] = true
+
+		raw := raws[n]
+		if err := walk(raw.left, parent); err != nil {
+			return err
+		}
+		if raw.entry.Type == CFBStorage || raw.entry.Type == CFBStream {
+			parent.Children = append(parent.Children, raw.entry)
+		}
+		if raw.entry.Type == CFBStorage {
+			if err := walk(raw.child, raw.entry); err != nil {
+				return err
+			}
+		}
+		return walk(raw.right, parent)
+	}
+
+	root := raws[0].entry
+	if root.Type != CFBRoot {
+		return nil, ErrCFB
+	}
+	visited[0] = true
+	if err = walk(raws[0].child, root); err != nil {
+		return nil, err
+	}
+	return &CompoundFile{Root: root}, nil
+}
+
+// Bytes 输出复合文档（版本 3，512 字节扇区）
+func (cf *CompoundFile) Bytes() ([]byte, error) {
+	le := binary.LittleEndian
+
+	// 1、展开目录项，根存储为 0
+	type dirEntry struct {
+		entry              *CFBEntry
+		left, right, child uint32
+		red                bool
+		start              uint32
+		size               uint32
+	}
+	entries := []*dirEntry{{entry: cf.Root, left: cfbNoStream, right: cfbNoStream, child: cfbNoStream}}
+
+	var flatten func(parent *dirEntry) error
+	flatten = func(parent *dirEntry) error {
+		children := append([]*CFBEntry{}, parent.entry.Children...)
+		sort.Slice(children, func(i, j int) bool {
+			return cfbCompare(children[i].Name, children[j].Name) < 0
+		})
+
+		ids := make([]uint32, len(children))
+		for i, child := range children {
+			if len(utf16.Encode([]rune(child.Name))) > 31 {
+				return ErrCFB
+			}
+			ids[i] = uint32(len(entries))
+			entries = append(entries, &dirEntry{entry: child, left: cfbNoStream, right: cfbNoStream, child: cfbNoStream})
+		}
+
+		// 使用有序数组构建平衡二叉树，最深一层着红色，满足红黑树约束
+		depth := 0
+		for n := len(children); n > 1; n >>= 1 {
+			depth++
+		}
+		var build func(lo, hi, level int) uint32
+		build = func(lo, hi, level int) uint32 {
+			if lo > hi {
+				return cfbNoStream
+			}
+			mid := (lo + hi + 1) / 2
+			e := entries[ids[mid]]
+			e.left = build(lo, mid-1, level+1)
+			e.right = build(mid+1, hi, level+1)
+			e.red = level == depth && depth > 0
+			return ids[mid]
+		}
+		parent.child = build(0, len(children)-1, 0)
+
+		for i, child := range children {
+			if child.Type == CFBStorage {
+				if err := flatten(entries[ids[i]]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := flatten(entries[0]); err != nil {
+		return nil, err
+	}
+
+	// 2、分配扇区
+	var (
+		sectors    [][]byte
+		fat        []uint32
+		miniStream []byte
+		miniFat    []uint32
+	)
+	appendChain := func(data []byte, size int) uint32 {
+		if len(data) == 0 {
+			return cfbEndOfChain
+		}
+		start := uint32(len(sectors))
+		for i := 0; i < len(data); i += size {
+			s := make([]byte, size)
+			copy(s, data[i:])
+			sectors = append(sectors, s)
+			fat = append(fat, uint32(len(sectors)))
+		}
+		fat[len(fat)-1] = cfbEndOfChain
+		return start
+	}
+
+	for _, e := range entries[1:] {
+		if e.entry.Type != CFBStream {
+			continue
+		}
+		e.size = uint32(len(e.entry.Data))
+		if len(e.entry.Data) >= cfbMiniCutoff {
+			e.start = appendChain(e.entry.Data, cfbSectorSize)
+			continue
+		}
+		if len(e.entry.Data) == 0 {
+			e.start = cfbEndOfChain
+			continue
+		}
+
+		e.start = uint32(len(miniFat))
+		for i := 0; i < len(e.entry.Data); i += cfbMiniSectorSize {
+			s := make([]byte, cfbMiniSectorSize)
+			copy(s, e.entry.Data[i:])
+			miniStream = append(miniStream, s...)
+			miniFat = append(miniFat, uint32(len(miniFat)+1))
+		}
+		miniFat[len(miniFat)-1] = cfbEndOfChain
+	}
+
+	entries[0].start = appendChain(miniStream, cfbSectorSize)
+	entries[0].size = uint32(len(miniStream))
+
+	var miniFatData []byte
+	for _, n := range miniFat {
+		miniFatData = le.AppendUint32(miniFatData, n)
+	}
+	firstMiniFat := appendChain(miniFatData, cfbSectorSize)
+	numMiniFat := (len(miniFatData) + cfbSectorSize - 1) / cfbSectorSize
+
+	// 目录
+	var dir []byte
+	for _, e := range entries {
+		d := make([]byte, cfbDirEntrySize)
+		name := utf16.Encode([]rune(e.entry.Name))
+		for i, v := range name {
+			le.PutUint16(d[i*2:], v)
+		}
+		le.PutUint16(d[64:], uint16(len(name)*2+2))
+		d[66] = byte(e.entry.Type)
+		if !e.red {
+			d[67] = 1
+		}
+		le.PutUint32(d[68:], e.left)
+		le.PutUint32(d[72:], e.right)
+		le.PutUint32(d[76:], e.child)
+		copy(d[80:], e.entry.CLSID[:])
+		le.PutUint32(d[96:], e.entry.StateBits)
+		le.PutUint64(d[100:], e.entry.Created)
+		le.PutUint64(d[108:], e.entry.Modified)
+		if e.entry.Type == CFBStream || e.entry.Type == CFBRoot {
+			le.PutUint32(d[116:], e.start)
+			le.PutUint32(d[120:], e.size)
+		}
+		dir = append(dir, d...)
+	}
+	for len(dir)%cfbSectorSize != 0 {
+		d := make([]byte, cfbDirEntrySize)
+		le.PutUint32(d[68:], cfbNoStream)
+		le.PutUint32(d[72:], cfbNoStream)
+		le.PutUint32(d[76:], cfbNoStream)
+		dir = append(dir, d...)
+	}
+	firstDir := appendChain(dir, cfbSectorSize)
+
+	// 3、计算 FAT、DIFAT 扇区数量
+	perSector := cfbSectorSize / 4
+	numFat, numDifat := 0, 0
+	for {
+		total := len(sectors) + numFat + numDifat
+		needFat := (total + perSector - 1) / perSector
+		needDifat := 0
+		if needFat > 109 {
+			needDifat = (needFat - 109 + perSector - 2) / (perSector - 1)
+		}
+		if needFat == numFat && needDifat == numDifat {
+			break
+		}
+		numFat, numDifat = needFat, needDifat
+	}
+
+	fatStart := uint32(len(sectors))
+	difatStart := fatStart + uint32(numFat)
+	for i := 0; i < numFat; i++ {
+		fat = append(fat, cfbFATSect)
+	}
+	for i := 0; i < numDifat; i++ {
+		fat = append(fat, cfbDIFATSect)
+	}
+	for len(fat) < (numFat)*perSector {
+		fat = append(fat, cfbFreeSect)
+	}
+
+	var fatData []byte
+	for _, n := range fat {
+		fatData = le.AppendUint32(fatData, n)
+	}
+	for i := 0; i < numFat; i++ {
+		sectors = append(sectors, fatData[i*cfbSectorSize:(i+1)*cfbSectorSize])
+	}
+
+	// DIFAT
+	for i := 0; i < numDifat; i++ {
+		s := make([]byte, cfbSectorSize)
+		for j := 0; j < perSector-1; j++ {
+			n := 109 + i*(perSector-1) + j
+			if n < numFat {
+				le.PutUint32(s[j*4:], fatStart+uint32(n))
+			} else {
+				le.PutUint32(s[j*4:], cfbFreeSect)
+			}
+		}
+		if i == numDifat-1 {
+			le.PutUint32(s[cfbSectorSize-4:], cfbEndOfChain)
+		} else {
+			le.PutUint32(s[cfbSectorSize-4:], difatStart+uint32(i+1))
+		}
+		sectors = append(sectors, s)
+	}
+
+	// 4、文件头
+	header := make([]byte, cfbSectorSize)
+	copy(header, cfbSignature)
+	le.PutUint16(header[24:], 0x003e)
+	le.PutUint16(header[26:], 3)
+	le.PutUint16(header[28:], 0xfffe)
+	le.PutUint16(header[30:], 9)
+	le.PutUint16(header[32:], 6)
+	le.PutUint32(header[44:], uint32(numFat))
+	le.PutUint32(header[48:], firstDir)
+	le.PutUint32(header[56:], cfbMiniCutoff)
+	le.PutUint32(header[60:], firstMiniFat)
+	le.PutUint32(header[64:], uint32(numMiniFat))
+	if numDifat > 0 {
+		le.PutUint32(header[68:], difatStart)
+	} else {
+		le.PutUint32(header[68:], cfbEndOfChain)
+	}
+	le.PutUint32(header[72:], uint32(numDifat))
+	for i := 0; i < 109; i++ {
+		if i < numFat {
+			le.PutUint32(header[76+i*4:], fatStart+uint32(i))
+		} else {
+			le.PutUint32(header[76+i*4:], cfbFreeSect)
+		}
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, (len(sectors)+1)*cfbSectorSize))
+	out.Write(header)
+	for _, s := range sectors {
+		out.Write(s)
+	}
+	return out.Bytes(), nil
+}
+
+// cfbCompare 目录项名称比较：先比较长度，再比较大写后的字符
+func cfbCompare(a, b string) int {
+	ua, ub := utf16.Encode([]rune(strings.ToUpper(a))), utf16.Encode([]rune(strings.ToUpper(b)))
+	if len(ua) != len(ub) {
+		return len(ua) - len(ub)
+	}
+	for i := range ua {
+		if ua[i] != ub[i] {
+			return int(ua[i]) - int(ub[i])
+		}
+	}
+	return 0
+}
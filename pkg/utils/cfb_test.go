@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCompoundFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		streams map[string][]byte
+	}{
+		{"empty", map[string][]byte{}},
+		{"mini", map[string][]byte{
+			"WordDocument": bytes.Repeat([]byte{1}, 100),
+			"1Table":       bytes.Repeat([]byte{2}, 4095),
+			"Empty":        {},
+		}},
+		{"storage", map[string][]byte{
+			"Workbook":                         bytes.Repeat([]byte{3}, 5000),
+			"\x05SummaryInformation":           bytes.Repeat([]byte{4}, 200),
+			"ObjectPool/_1234/\x01Ole":         bytes.Repeat([]byte{5}, 20),
+			"ObjectPool/_1234/Contents":        bytes.Repeat([]byte{6}, 70000),
+			"__substg1.0_0037001F":             []byte("s\x00u\x00b\x00"),
+			"__attach_version1.0_#00000000/ab": {7},
+		}},
+		{"difat", map[string][]byte{
+			"Large": bytes.Repeat([]byte("0123456789abcdef"), 8<<20/16),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cf := &CompoundFile{Root: &CFBEntry{Name: "Root Entry", Type: CFBRoot}}
+			for path, data := range tt.streams {
+				cf.SetStream(path, data)
+			}
+			for i := 0; i < 10; i++ {
+				cf.SetStream("Many/"+strconv.Itoa(i), []byte{byte(i)})
+			}
+
+			filename := filepath.Join(t.TempDir(), "test.cfb")
+			if err := WriteCFB(cf, filename); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ReadCFB(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for path, data := range tt.streams {
+				if !bytes.Equal(got.Stream(path), data) || got.Entry(path) == nil {
+					t.Errorf("stream %q mismatch, len = %d, want %d", path, len(got.Stream(path)), len(data))
+				}
+			}
+			for i := 0; i < 10; i++ {
+				if s := got.Stream("many/" + strconv.Itoa(i)); len(s) != 1 || s[0] != byte(i) {
+					t.Errorf("stream Many/%d = %v", i, s)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCFBInvalid(t *testing.T) {
+	if _, err := ParseCFB([]byte("PK\x03\x04")); err == nil {
+		t.Error("want error")
+	}
+	if _, err := ParseCFB(append(append([]byte{}, cfbSignature...), make([]byte, 600)...)); err == nil {
+		t.Error("want error")
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// IsUNC 判断是否为 UNC 路径，例如 \\host\share\file
+func IsUNC(path string) bool {
+	return strings.HasPrefix(path, `\\`) && len(path) > 2
+}
+
+// UNCPath 生成 UNC 路径
+// host: 主机名或 IP
+// elem: 共享名、文件名
+func UNCPath(host string, elem ...string) string {
+	return `\\` + strings.Join(append([]string{host}, elem...), `\`)
+}
+
+// UNCToUrl 将 UNC 路径转换为 file 地址，其他地址原样返回
+// \\host\share\file => file://host/share/file
+func UNCToUrl(path string) string {
+	if !IsUNC(path) {
+		return path
+	}
+
+	elem := strings.Split(strings.TrimPrefix(path, `\\`), `\`)
+	u := url.URL{
+		Scheme: "file",
+		Host:   elem[0],
+		Path:   "/" + strings.Join(elem[1:], "/"),
+	}
+	return u.String()
+}
+
+// UrlToUNC 将地址转换为 Windows 可以访问的 UNC 路径，其他地址原样返回
+// http 地址转换为 WebDAV 路径，由 WebClient 服务访问
+// file://host/share/file => \\host\share\file
+// http://host:8080/dav/file => \\host@8080\dav\file
+// https://host/dav/file => \\host@SSL\dav\file
+func UrlToUNC(path string) string {
+	u, err := url.Parse(path)
+	if err != nil || u.Host == "" {
+		return path
+	}
+
+	host := u.Hostname()
+	switch u.Scheme {
+	case "file":
+	case "http":
+		if u.Port() != "" && u.Port() != "80" {
+			host += "@" + u.Port()
+		}
+	case "https":
+		host += "@SSL"
+		if u.Port() != "" && u.Port() != "443" {
+			host += "@" + u.Port()
+		}
+	default:
+		return path
+	}
+
+	elem := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if elem[0] == "" {
+		elem = nil
+	}
+	return UNCPath(host, elem...)
+}
@@ -0,0 +1,47 @@
+package utils
+
+import "testing"
+
+func TestUNCToUrl(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"unc", `\\192.168.1.10\share\template.dotx`, "file://192.168.1.10/share/template.dotx"},
+		{"unc space", `\\host\share\a b.png`, "file://host/share/a%20b.png"},
+		{"http", "http://localhost:9090/trace", "http://localhost:9090/trace"},
+		{"unc path", UNCPath("host", "share", "x.png"), "file://host/share/x.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UNCToUrl(tt.path); got != tt.want {
+				t.Errorf("UNCToUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUrlToUNC(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"file", "file://host/share/a%20b.ico", `\\host\share\a b.ico`},
+		{"http", "http://host/dav/icon.ico", `\\host\dav\icon.ico`},
+		{"http port", "http://10.0.0.1:8080/dav/icon.ico", `\\10.0.0.1@8080\dav\icon.ico`},
+		{"https", "https://host/dav/icon.ico", `\\host@SSL\dav\icon.ico`},
+		{"https port", "https://host:8443/icon.ico", `\\host@SSL@8443\icon.ico`},
+		{"host only", "http://host", `\\host`},
+		{"unc", `\\host\share\icon.ico`, `\\host\share\icon.ico`},
+		{"roundtrip", UNCToUrl(`\\host\share\icon.ico`), `\\host\share\icon.ico`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UrlToUNC(tt.path); got != tt.want {
+				t.Errorf("UrlToUNC() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}